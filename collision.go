@@ -0,0 +1,57 @@
+package main
+
+import (
+	"log"
+	"sync/atomic"
+)
+
+// SetCollisions makes messages collide at their receiver, as on a shared wireless medium: when a node would receive
+// messages from two or more senders during the same tick, all of them are lost, and a collision is counted. A lost
+// DataMessage is resolved as dropped. Only a synchronous simulation, whose ticks are lock-step, models the medium
+// this way, so this has no effect otherwise. Disabled by default. Must be called before Start.
+func (c *Controller) SetCollisions(enabled bool) {
+	c.collisions = enabled
+}
+
+// Collisions is the number of times a node received messages from several senders in the same tick, losing them all.
+// See SetCollisions. Safe to call concurrently with Start.
+func (c *Controller) Collisions() int {
+	return int(atomic.LoadInt64(&c.collisionCount))
+}
+
+// inboxMessage is a message waiting to be processed by a node in a synchronous simulation, along with the neighbor
+// which transmitted it.
+type inboxMessage struct {
+	from NodeID
+	msg  interface{}
+}
+
+// collide returns the messages the node receives during the tick, which are none if collisions are enabled and they
+// were transmitted by more than one sender.
+func (c *Controller) collide(to NodeID, tick int, inbox []inboxMessage) []interface{} {
+	collided := false
+	if c.collisions {
+		for _, m := range inbox {
+			if m.from != inbox[0].from {
+				collided = true
+				break
+			}
+		}
+	}
+	msgs := make([]interface{}, 0, len(inbox))
+	if !collided {
+		for _, m := range inbox {
+			msgs = append(msgs, m.msg)
+		}
+		return msgs
+	}
+
+	atomic.AddInt64(&c.collisionCount, 1)
+	log.Printf("controller: node %s: %d messages collided at tick %d", to, len(inbox), tick)
+	for _, m := range inbox {
+		if dm, ok := m.msg.(*DataMessage); ok {
+			c.dataResolved(dm, dataDropped)
+		}
+	}
+	return msgs
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestController_SetCollisions(t *testing.T) {
+	tests := []struct {
+		name          string
+		collisions    bool
+		wantCollided  bool
+		wantNeighbors int
+	}{
+		{name: "disabled", collisions: false, wantNeighbors: 2},
+		{name: "enabled", collisions: true, wantCollided: true, wantNeighbors: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Nodes 1 and 2 can not hear each other, and always send their HELLOs to node 0 in the same tick.
+			topology, err := NewNetworkTypology(strings.NewReader("0 UP 0 1\n0 UP 1 0\n0 UP 0 2\n0 UP 2 0\n"))
+			if err != nil {
+				t.Fatalf("NewNetworkTypology() error = %v", err)
+			}
+			c := NewController(*topology, time.Hour)
+			c.SetLogDir(t.TempDir())
+			c.Initialize([]NodeConfig{
+				{ID: 0, Message: NodeMessage{Sent: true}},
+				{ID: 1, Message: NodeMessage{Sent: true}},
+				{ID: 2, Message: NodeMessage{Sent: true}},
+			})
+			c.SetSynchronous(true)
+			c.SetCollisions(tt.collisions)
+			c.Start(20)
+
+			if got := c.Collisions() > 0; got != tt.wantCollided {
+				t.Errorf("Collisions() = %d, want collisions %v", c.Collisions(), tt.wantCollided)
+			}
+			if got := c.Stats().Collisions; got != c.Collisions() {
+				t.Errorf("Stats().Collisions = %d, want %d", got, c.Collisions())
+			}
+			n, _ := c.node(0)
+			if got := len(n.Snapshot().OneHopNeighbors); got != tt.wantNeighbors {
+				t.Errorf("node 0 has %d one-hop neighbors, want %d", got, tt.wantNeighbors)
+			}
+		})
+	}
+}
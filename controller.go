@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bufio"
 	"context"
 	"errors"
 	"fmt"
@@ -11,6 +10,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -26,23 +26,223 @@ type Controller struct {
 	// nodeChannels is a mapping between each node and its input channel.
 	nodeChannels map[NodeID]chan interface{}
 
+	// nodeStarted is closed for each node once it has come online.
+	nodeStarted map[NodeID]chan struct{}
+
+	// nodeStopped is closed for each node once it has gone offline.
+	nodeStopped map[NodeID]chan struct{}
+
+	// configs holds the configuration each node was created from.
+	configs map[NodeID]NodeConfig
+
 	// nodes holds all running nodes which this controller is responsible for.
-	nodes []Node
+	nodes []*Node
 
 	// tickDuration controls how quickly the simulation runs.
 	tickDuration time.Duration
+
+	// logDir is the directory nodes write their log files to.
+	logDir string
+
+	// logFormat determines how nodes write their log files.
+	logFormat LogFormat
+
+	// deliveryOrder, if set, orders the messages each node receives within a tick.
+	deliveryOrder *deliveryOrder
+
+	// neighborTransitionLog, if set, is where nodes log changes in their neighbors' advertised relationships.
+	neighborTransitionLog *log.Logger
+
+	// staleTCLog, if set, is where nodes log the TCMessage(s) they discard as stale.
+	staleTCLog *log.Logger
+
+	// emissionJitter, if set, offsets each node's periodic emissions.
+	emissionJitter *emissionJitter
+
+	// gate enables the simulation clock to be paused. Shared with all nodes.
+	gate *pauseGate
+
+	// linkMu guards linkMessages, which is read by accessors concurrently with the router.
+	linkMu sync.Mutex
+
+	// linkMessages is the number of messages delivered across each directed link, keyed by [from, to].
+	linkMessages map[[2]NodeID]int
+
+	// linkQueuesMu guards linkQueues, and the queues themselves, which are shared by the router and the goroutines
+	// delivering each link's messages.
+	linkQueuesMu sync.Mutex
+
+	// linkQueues holds the messages waiting to cross each directed link, keyed by [from, to].
+	linkQueues map[[2]NodeID]*linkQueue
+
+	// epochMu guards epoch, which is read by accessors concurrently with Start.
+	epochMu sync.RWMutex
+
+	// epoch is when the simulation started, zero before Start is called.
+	epoch time.Time
+
+	// synchronous runs the simulation in a single goroutine, in lock-step ticks.
+	synchronous bool
+
+	// syncTick is the current tick of a synchronous simulation. Guarded by epochMu.
+	syncTick int
+
+	// scheduledData are DataMessage(s) to be originated by nodes during the simulation.
+	scheduledData []scheduledData
+
+	// scheduledChanges are changes to be made to nodes' parameters during the simulation.
+	scheduledChanges []scheduledChange
+
+	// outstandingData is the number of DataMessage(s) that have yet to be delivered or dropped.
+	outstandingData int64
+
+	// allDataResolved is closed once outstandingData reaches zero.
+	allDataResolved chan struct{}
+
+	// resolvedOnce ensures allDataResolved is only closed once.
+	resolvedOnce sync.Once
+
+	// groups is the membership of every multicast group, by group.
+	groups map[NodeID][]NodeID
+
+	// watchdogGrace is the number of ticks a running node may go without progressing before it is reported as stuck.
+	// Zero disables the watchdog.
+	watchdogGrace int
+
+	// stuckMu guards stuck, which is written by the watchdog concurrently with accessors.
+	stuckMu sync.Mutex
+
+	// stuck holds the tick at which each node was reported as stuck.
+	stuck map[NodeID]int
+
+	// stopWhenResolved makes the simulation end once all DataMessage(s) are resolved.
+	stopWhenResolved bool
+
+	// resolvedGrace is the number of ticks to keep running after all DataMessage(s) are resolved, allowing the
+	// control plane to settle.
+	resolvedGrace int
+
+	// recordConvergence makes the Controller sample the convergence of the nodes' routes every tick.
+	recordConvergence bool
+
+	// convergenceMu guards convergence, which is written by the sampler concurrently with accessors.
+	convergenceMu sync.Mutex
+
+	// convergence is the fraction of node pairs with a correct route at the end of each tick.
+	convergence []float64
+
+	// interfaces holds every interface address of each node with several interfaces, main address first.
+	interfaces map[NodeID][]NodeID
+
+	// interceptors observe, modify, or drop every message before it crosses a link.
+	interceptors []Interceptor
+
+	// collisions makes messages from several senders received by a node in the same tick collide.
+	collisions bool
+
+	// collisionCount is the number of collisions so far. Accessed atomically.
+	collisionCount int64
+
+	// linkCapacity is the number of DataMessage(s) a directed link carries per tick, or zero if unlimited.
+	linkCapacity int
+
+	// congestionDrops is the number of DataMessage(s) dropped by congested links so far. Accessed atomically.
+	congestionDrops int64
+
+	// strictTopology makes Initialize fail if the topology has links involving a node which is not configured.
+	strictTopology bool
+}
+
+// scheduledData is a DataMessage to be originated by a node at a specific tick.
+type scheduledData struct {
+	src      NodeID
+	dst      NodeID
+	data     string
+	priority int
+	atTick   int
+}
+
+// scheduledChange is a change to a node's parameters to be made at a specific tick.
+type scheduledChange struct {
+	id     NodeID
+	change func(n *Node)
+	atTick int
 }
 
-// Initialize creates new nodes based on the supplied configuration and establishes channels.
-func (c *Controller) Initialize(nodes []NodeConfig) {
+// Initialize creates new nodes based on the supplied configuration and establishes channels. A node which has links in
+// the topology but no configuration is created with no message to send, unless SetStrictTopology is enabled, in which
+// case an error is returned and no nodes are created.
+func (c *Controller) Initialize(nodes []NodeConfig) error {
+	if err := c.setInterfaces(nodes); err != nil {
+		return err
+	}
+	nodes, err := c.withTopologyNodes(nodes)
+	if err != nil {
+		return err
+	}
 	c.inputLink = make(chan interface{})
+	c.groups = multicastGroups(nodes)
 	for _, config := range nodes {
 		in := make(chan interface{})
 		c.nodeChannels[config.ID] = in
+		c.nodeStarted[config.ID] = make(chan struct{})
+		c.nodeStopped[config.ID] = make(chan struct{})
+		c.configs[config.ID] = config
+
+		node := NewNode(in, c.inputLink, config.ID, config.Message, c.tickDuration, c.logDir)
+		node.onDataResolved = c.dataResolved
+		node.gate = c.gate
+		node.logFormat = c.logFormat
+		node.deliveryOrder = c.deliveryOrder
+		node.neighborTransitionLog = c.neighborTransitionLog
+		node.staleTCLog = c.staleTCLog
+		if c.emissionJitter != nil {
+			node.SetEmissionJitter(c.emissionJitter.seed+int64(config.ID), c.emissionJitter.max)
+		}
+		node.groups = config.Groups
+		node.interfaces = config.Interfaces
+		node.groupMembers = c.groups
+		c.nodes = append(c.nodes, node)
+		if !config.Message.Sent {
+			atomic.AddInt64(&c.outstandingData, c.deliveries(config.ID, config.Message.Destination))
+		}
+	}
+	return nil
+}
+
+// SetStrictTopology makes Initialize return an error, rather than creating a default node, for each node which has
+// links in the topology but no configuration. Must be called before Initialize.
+func (c *Controller) SetStrictTopology(strict bool) {
+	c.strictTopology = strict
+}
+
+// withTopologyNodes adds a default configuration, with no message to send, for each node in the topology which is not
+// configured.
+func (c *Controller) withTopologyNodes(configs []NodeConfig) ([]NodeConfig, error) {
+	configured := make(map[NodeID]struct{}, len(configs))
+	for _, config := range configs {
+		configured[config.ID] = struct{}{}
+	}
+	missing := make([]NodeID, 0)
+	for _, id := range c.topology.NodeIDs() {
+		if _, in := configured[id]; !in && !c.isInterface(id) {
+			missing = append(missing, id)
+		}
+	}
+	if len(missing) == 0 {
+		return configs, nil
+	}
+	if c.strictTopology {
+		return nil, fmt.Errorf("topology has links involving nodes with no configuration: %s", separatedString(missing, ", "))
+	}
 
-		node := NewNode(in, c.inputLink, config.ID, config.Message, c.tickDuration)
-		c.nodes = append(c.nodes, *node)
+	all := make([]NodeConfig, len(configs), len(configs)+len(missing))
+	copy(all, configs)
+	for _, id := range missing {
+		log.Printf("controller: node %s has links in the topology but no configuration, auto-created with no message", id)
+		all = append(all, NodeConfig{ID: id, Message: NodeMessage{Sent: true}})
 	}
+	return all, nil
 }
 
 func (c *Controller) handleHelloMessage(hm *HelloMessage, epoch time.Time) {
@@ -54,28 +254,51 @@ func (c *Controller) handleHelloMessage(hm *HelloMessage, epoch time.Time) {
 		q := QueryMsg{
 			FromNode: hm.Source,
 			ToNode:   node.id,
-			AtTime:   int(time.Since(epoch) / c.tickDuration),
+			AtTime:   c.ticksSince(epoch),
+		}
+		if !c.linkUp(q) {
+			continue
 		}
-		if c.topology.Query(q) {
-			// Send the hello if a link is available.
-			c.nodeChannels[node.id] <- hm
+		// Send the hello if a link is available.
+		if msg, ok := c.intercept(q.FromNode, q.ToNode, hm, q.AtTime); ok {
+			c.sendAcross(q.FromNode, q.ToNode, msg, epoch, q.AtTime, func(delivered bool) {
+				if delivered {
+					c.countDelivery(q.FromNode, q.ToNode)
+				}
+			})
 		}
 	}
 }
 
 func (c *Controller) handleTCMessage(tcm *TCMessage, epoch time.Time) {
-	// Send the TC message along all neighbor links that are UP.
+	c.flood(tcm, tcm.Source, tcm.FromNeighbor, epoch)
+}
+
+func (c *Controller) handleMIDMessage(mid *MIDMessage, epoch time.Time) {
+	c.flood(mid, mid.Source, mid.FromNeighbor, epoch)
+}
+
+// flood sends a flooded control message from the neighbor forwarding it along all of its links that are UP, except
+// back to its originator.
+func (c *Controller) flood(fm interface{}, source, fromNeighbor NodeID, epoch time.Time) {
 	for _, node := range c.nodes {
-		if node.id == tcm.Source {
+		if node.id == source {
 			continue
 		}
 		q := QueryMsg{
-			FromNode: tcm.FromNeighbor,
+			FromNode: fromNeighbor,
 			ToNode:   node.id,
-			AtTime:   int(time.Since(epoch) / c.tickDuration),
+			AtTime:   c.ticksSince(epoch),
 		}
-		if c.topology.Query(q) {
-			c.nodeChannels[node.id] <- tcm
+		if !c.linkUp(q) {
+			continue
+		}
+		if msg, ok := c.intercept(q.FromNode, q.ToNode, fm, q.AtTime); ok {
+			c.sendAcross(q.FromNode, q.ToNode, msg, epoch, q.AtTime, func(delivered bool) {
+				if delivered {
+					c.countDelivery(q.FromNode, q.ToNode)
+				}
+			})
 		}
 	}
 }
@@ -85,31 +308,274 @@ func (c *Controller) handleDataMessage(dm *DataMessage, epoch time.Time) {
 	q := QueryMsg{
 		FromNode: dm.FromNeighbor,
 		ToNode:   dm.NextHop,
-		AtTime:   int(time.Since(epoch) / c.tickDuration),
+		AtTime:   c.ticksSince(epoch),
+	}
+	dropped := func() {
+		log.Printf("controller: link %s -> %s unavailable, dropped:\t%s", q.FromNode, q.ToNode, dm)
+		c.dataResolved(dm, dataDropped)
+	}
+	if !c.linkUp(q) {
+		dropped()
+		return
+	}
+	msg, ok := c.intercept(q.FromNode, q.ToNode, dm, q.AtTime)
+	if !ok {
+		c.dataResolved(dm, dataDropped)
+		return
 	}
-	if c.topology.Query(q) {
-		c.nodeChannels[dm.NextHop] <- dm
+	c.sendAcross(q.FromNode, q.ToNode, msg, epoch, q.AtTime, func(delivered bool) {
+		if !delivered {
+			dropped()
+			return
+		}
+		// The message now belongs to the next hop, which may already be forwarding it, so only the query is read.
+		c.countDelivery(q.FromNode, q.ToNode)
+	})
+}
+
+// deliveries is the number of deliveries, or drops, a DataMessage from the source to the destination resolves into.
+func (c *Controller) deliveries(src, dst NodeID) int64 {
+	if IsMulticast(dst) {
+		return int64(len(multicastReceivers(c.groups, src, dst)))
+	}
+	return 1
+}
+
+// dataResolved records a DataMessage which was delivered or dropped. A multicast DataMessage resolves the delivery to
+// each of its receivers.
+func (c *Controller) dataResolved(msg *DataMessage, _ dataOutcome) {
+	if atomic.AddInt64(&c.outstandingData, -int64(dataReceivers(msg))) == 0 {
+		c.resolvedOnce.Do(func() {
+			close(c.allDataResolved)
+		})
+	}
+}
+
+// SetLogDir sets the directory nodes write their log files to. Must be called before Initialize.
+func (c *Controller) SetLogDir(dir string) {
+	c.logDir = dir
+}
+
+// SetLogFormat sets how nodes write their log files. Must be called before Initialize.
+func (c *Controller) SetLogFormat(f LogFormat) {
+	c.logFormat = f
+}
+
+// SetNeighborTransitionLog sets where every node logs a debug message whenever a neighbor's advertised relationship to
+// it changes. See Node.SetNeighborTransitionLog. Must be called before Initialize.
+func (c *Controller) SetNeighborTransitionLog(l *log.Logger) {
+	c.neighborTransitionLog = l
+}
+
+// SetStaleTCLog sets where every node logs a debug message whenever it discards a TCMessage as stale. See
+// Node.SetStaleTCLog. Must be called before Initialize.
+func (c *Controller) SetStaleTCLog(l *log.Logger) {
+	c.staleTCLog = l
+}
+
+// StopWhenResolved makes the simulation end early, once every DataMessage has been delivered or dropped and a further
+// grace period, in ticks, has passed.
+func (c *Controller) StopWhenResolved(grace int) {
+	c.stopWhenResolved = true
+	c.resolvedGrace = grace
+}
+
+// node finds the node with the given ID.
+func (c *Controller) node(id NodeID) (*Node, bool) {
+	for _, n := range c.nodes {
+		if n.id == id {
+			return n, true
+		}
+	}
+	return nil, false
+}
+
+// ScheduleData makes the source node originate a DataMessage to the destination at the given tick, since the start
+// of the simulation. The message is routed like any other DataMessage. Must be called after Initialize and before
+// Start.
+func (c *Controller) ScheduleData(src, dst NodeID, data string, atTick int) error {
+	return c.ScheduleDataPriority(src, dst, data, 0, atTick)
+}
+
+// ScheduleDataPriority makes the source node originate a DataMessage of the given Priority, as ScheduleData does. See
+// SetLinkCapacity. Must be called after Initialize and before Start.
+func (c *Controller) ScheduleDataPriority(src, dst NodeID, data string, priority int, atTick int) error {
+	if _, in := c.node(src); !in {
+		return fmt.Errorf("schedule data: unknown source node: %s", src)
+	}
+	c.scheduledData = append(c.scheduledData, scheduledData{src: src, dst: dst, data: data, priority: priority, atTick: atTick})
+	atomic.AddInt64(&c.outstandingData, c.deliveries(src, dst))
+	return nil
+}
+
+// ScheduleNodeChange makes the Controller call change on the node at the given tick, since the start of the
+// simulation, such as to call SetHelloInterval or SetWillingness as the node enters a power-saving mode. Changes made
+// through the Node's setters take effect from its next tick: in a synchronous simulation, that is the given tick, while
+// otherwise the change races with the node's tick. Must be called after Initialize and before Start.
+func (c *Controller) ScheduleNodeChange(id NodeID, atTick int, change func(n *Node)) error {
+	if _, in := c.node(id); !in {
+		return fmt.Errorf("schedule node change: unknown node: %s", id)
+	}
+	c.scheduledChanges = append(c.scheduledChanges, scheduledChange{id: id, change: change, atTick: atTick})
+	return nil
+}
+
+// deliver sends a message to a node's input channel. Messages sent to a node that is not online are dropped.
+// Returns whether the message was delivered.
+func (c *Controller) deliver(to NodeID, msg interface{}) bool {
+	select {
+	case <-c.nodeStarted[to]:
+	default:
+		return false
+	}
+	select {
+	case c.nodeChannels[to] <- msg:
+		return true
+	case <-c.nodeStopped[to]:
+		return false
+	}
+}
+
+// deliverAt sends a message to a node's input channel once the given tick since the epoch is reached. Messages sent
+// to a node that goes offline in the meantime are dropped. Returns whether the message was delivered.
+func (c *Controller) deliverAt(to NodeID, msg interface{}, epoch time.Time, tick int) bool {
+	if tick > c.ticksSince(epoch) && !c.waitUntil(c.nodeStopped[to], epoch, tick) {
+		return false
 	}
+	return c.deliver(to, msg)
 }
 
-// Start runs all nodes and starts the controller.
-func (c *Controller) Start(ticks int) {
+// Pause halts the simulation clock without stopping any nodes, until Resume is called.
+func (c *Controller) Pause() {
+	c.gate.Pause()
+}
+
+// Resume restarts the simulation clock after Pause.
+func (c *Controller) Resume() {
+	c.gate.Resume()
+}
+
+// ticksSince is the number of ticks since the epoch, excluding any time spent paused.
+func (c *Controller) ticksSince(epoch time.Time) int {
+	return int(c.gate.elapsed(epoch) / c.tickDuration)
+}
+
+// CurrentTick is the simulation's global clock: the number of ticks since the simulation started, excluding any time
+// spent paused, or zero if it has not started. In a synchronous simulation it is the tick being run. Otherwise it is
+// the tick by which the Controller schedules topology changes, scheduled messages, and the end of the run, which each
+// node's own tick may briefly lag. Safe to call concurrently with Start.
+func (c *Controller) CurrentTick() int {
+	c.epochMu.RLock()
+	epoch := c.epoch
+	syncTick := c.syncTick
+	c.epochMu.RUnlock()
+
+	if c.synchronous {
+		return syncTick
+	}
+
+	if epoch.IsZero() {
+		return 0
+	}
+	return c.ticksSince(epoch)
+}
+
+// waitUntilTick blocks until the given tick since the epoch, excluding any time spent paused.
+// Returns false if the context is done first.
+func (c *Controller) waitUntilTick(ctx context.Context, epoch time.Time, tick int) bool {
+	return c.waitUntil(ctx.Done(), epoch, tick)
+}
+
+// waitUntil blocks until the given tick since the epoch, excluding any time spent paused.
+// Returns false if done is closed first.
+func (c *Controller) waitUntil(done <-chan struct{}, epoch time.Time, tick int) bool {
+	for {
+		remaining := c.tickDuration*time.Duration(tick) - c.gate.elapsed(epoch)
+		if remaining <= 0 {
+			return true
+		}
+		select {
+		case <-time.After(remaining):
+		case <-done:
+			return false
+		}
+	}
+}
+
+// runNode runs a node within its configured active window, measured in ticks since the epoch.
+func (c *Controller) runNode(ctx context.Context, epoch time.Time, n *Node) {
+	defer close(c.nodeStopped[n.id])
+
+	config := c.configs[n.id]
+	if !c.waitUntilTick(ctx, epoch, config.StartTick) {
+		return
+	}
+	if config.StopTick > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithCancel(ctx)
+		defer cancel()
+		go func() {
+			if c.waitUntilTick(ctx, epoch, config.StopTick) {
+				cancel()
+			}
+		}()
+	}
+
+	close(c.nodeStarted[n.id])
+	n.Run(ctx)
+}
+
+// Start runs all nodes and starts the controller, returning the tick at which the simulation ended.
+func (c *Controller) Start(ticks int) int {
+	if c.synchronous {
+		return c.startSynchronous(ticks)
+	}
+	if c.collisions {
+		log.Printf("controller: WARNING: collisions are only modelled by a synchronous simulation, and are ignored")
+	}
+	if c.linkCapacity > 0 {
+		log.Printf("controller: WARNING: link capacity is only modelled by a synchronous simulation, and is ignored")
+	}
+
 	// Define a context to enable sending a done message to all nodes.
 	ctx, cancel := context.WithCancel(context.Background())
 	nodeWg := sync.WaitGroup{}
 
 	// Establish an epoch, which will be used in conjunction with the NetworkTopology.
 	epoch := time.Now()
+	c.epochMu.Lock()
+	c.epoch = epoch
+	c.epochMu.Unlock()
 
 	// Start up all the nodes
 	for _, node := range c.nodes {
 		nodeWg.Add(1)
-		go func(n Node) {
+		go func(n *Node) {
 			defer nodeWg.Done()
-			n.Run(ctx)
+			c.runNode(ctx, epoch, n)
 		}(node)
 	}
 
+	// Originate scheduled Data messages once their tick is reached.
+	for _, sd := range c.scheduledData {
+		go func(sd scheduledData) {
+			if c.waitUntilTick(ctx, epoch, sd.atTick) {
+				n, _ := c.node(sd.src)
+				n.OriginatePriority(sd.dst, sd.data, sd.priority)
+			}
+		}(sd)
+	}
+
+	// Change nodes' parameters once their tick is reached.
+	for _, sc := range c.scheduledChanges {
+		go func(sc scheduledChange) {
+			if c.waitUntilTick(ctx, epoch, sc.atTick) {
+				n, _ := c.node(sc.id)
+				sc.change(n)
+			}
+		}(sc)
+	}
+
 	// Signal the router to shutdown when all nodes return.
 	doneRouting := make(chan struct{})
 	go func() {
@@ -128,12 +594,15 @@ func (c *Controller) Start(ticks int) {
 				return
 			case msg := <-c.inputLink:
 				switch t := msg.(type) {
+				// Messages are queued on each link in the order they are sent, so none are handled concurrently.
 				case *HelloMessage:
-					go c.handleHelloMessage(msg.(*HelloMessage), epoch)
+					c.handleHelloMessage(msg.(*HelloMessage), epoch)
 				case *DataMessage:
-					go c.handleDataMessage(msg.(*DataMessage), epoch)
+					c.handleDataMessage(msg.(*DataMessage), epoch)
 				case *TCMessage:
-					go c.handleTCMessage(msg.(*TCMessage), epoch)
+					c.handleTCMessage(msg.(*TCMessage), epoch)
+				case *MIDMessage:
+					c.handleMIDMessage(msg.(*MIDMessage), epoch)
 				default:
 					log.Panicf("controller: invalid message type: %s\n", t)
 				}
@@ -141,23 +610,64 @@ func (c *Controller) Start(ticks int) {
 		}
 	}()
 
+	if c.watchdogGrace > 0 {
+		go c.watchNodes(ctx, epoch)
+	}
+	if c.recordConvergence {
+		go c.sampleConvergenceEachTick(ctx, epoch)
+	}
+
+	// Launch a goroutine to end the simulation early, once all Data messages are resolved.
+	if c.stopWhenResolved {
+		if atomic.LoadInt64(&c.outstandingData) == 0 {
+			c.resolvedOnce.Do(func() {
+				close(c.allDataResolved)
+			})
+		}
+		go func() {
+			select {
+			case <-c.allDataResolved:
+				log.Printf("controller: all data messages resolved, stopping in %d ticks", c.resolvedGrace)
+			case <-ctx.Done():
+				return
+			}
+			if c.waitUntilTick(ctx, epoch, c.ticksSince(epoch)+c.resolvedGrace) {
+				cancel()
+			}
+		}()
+	}
+
 	// Launch a goroutine to send a done message to all nodes, via a cancelled context, after the timer expires.
 	go func() {
-		<-time.NewTimer(c.tickDuration * time.Duration(ticks)).C
-		cancel()
+		if c.waitUntilTick(ctx, epoch, ticks) {
+			cancel()
+		}
 	}()
 
 	// Wait for all nodes to return and router to return.
 	<-routerShutdown
-	log.Println("done.")
+	cancel()
+	finalTick := c.ticksSince(epoch)
+	log.Printf("done at tick %d.", finalTick)
+	return finalTick
 }
 
 // NewController creates a Controller based on the supplied network typology.
+// The tick duration only sets the wall-clock period of a tick; all protocol intervals are measured in ticks.
 func NewController(topology NetworkTypology, tickDuration time.Duration) *Controller {
 	c := &Controller{}
 	c.topology = topology
 	c.nodeChannels = make(map[NodeID]chan interface{})
+	c.nodeStarted = make(map[NodeID]chan struct{})
+	c.nodeStopped = make(map[NodeID]chan struct{})
+	c.configs = make(map[NodeID]NodeConfig)
+	c.allDataResolved = make(chan struct{})
 	c.tickDuration = tickDuration
+	c.logDir = "./log"
+	c.gate = newPauseGate()
+	c.linkMessages = make(map[[2]NodeID]int)
+	c.linkQueues = make(map[[2]NodeID]*linkQueue)
+	c.stuck = make(map[NodeID]int)
 	return c
 }
 
@@ -165,51 +675,225 @@ func NewController(topology NetworkTypology, tickDuration time.Duration) *Contro
 type NodeConfig struct {
 	ID      NodeID
 	Message NodeMessage
+
+	// StartTick is the tick, since the start of the simulation, at which the node comes online.
+	StartTick int
+
+	// StopTick is the tick, since the start of the simulation, at which the node goes offline.
+	// Zero represents a node that stays online until the simulation ends.
+	StopTick int
+
+	// Subnet groups nodes for reporting. It has no effect on the protocol, as OLSR is flat.
+	Subnet int
+
+	// Gateway marks the node as a gateway between its subnet and others, for reporting.
+	Gateway bool
+
+	// Groups are the multicast groups the node joins. See IsMulticast.
+	Groups []NodeID
+
+	// Interfaces are the addresses of the node's interfaces other than its main address, ID. Each interface has its own
+	// links in the topology, but the node's messages originate from, and are addressed to, its main address.
+	Interfaces []NodeID
+}
+
+// ValidateScenario checks the node configurations against each other, returning a warning for each suspect
+// configuration. The scenario can still be run despite any warnings.
+func ValidateScenario(configs []NodeConfig) []string {
+	warnings := make([]string, 0)
+	configured := make(map[NodeID]struct{})
+	for _, config := range configs {
+		configured[config.ID] = struct{}{}
+	}
+	for _, config := range configs {
+		if config.Message.Sent {
+			continue
+		}
+		dst := config.Message.Destination
+		if dst == config.ID {
+			warnings = append(warnings, fmt.Sprintf("node %s: message is addressed to itself and will be delivered locally", config.ID))
+			continue
+		}
+		if _, in := configured[dst]; !in {
+			warnings = append(warnings, fmt.Sprintf("node %s: message destination %s is not a configured node", config.ID, dst))
+		}
+	}
+	return warnings
+}
+
+// ErrBadNodeConfigLine is returned when a line of a node configuration can not be parsed.
+type ErrBadNodeConfigLine struct {
+	// LineNum is the 1-based number of the line.
+	LineNum int
+
+	// Raw is the line, without its trailing newline.
+	Raw string
+
+	// Reason describes why the line is invalid.
+	Reason string
+}
+
+func (e ErrBadNodeConfigLine) Error() string {
+	return fmt.Sprintf("invalid node config: line %d: %s: %s", e.LineNum, e.Reason, e.Raw)
+}
+
+// diagnoseNodeConfigLine determines why a line does not match the node configuration format.
+func diagnoseNodeConfigLine(line string) string {
+	if fields := strings.Fields(line); len(fields) > 1 && fields[1] == "JOIN" {
+		return "group membership must be of the form: {ID} JOIN {GROUP}"
+	}
+	quotes := strings.Count(line, "\"")
+	if quotes == 0 {
+		return "missing quoted message"
+	}
+	if quotes == 1 {
+		return "unterminated quote"
+	}
+	before, after, _ := strings.Cut(line, "\"")
+	ids := strings.Fields(before)
+	if len(ids) != 2 {
+		return "expected a source and destination before the message"
+	}
+	if _, err := parseNodeID(ids[0]); err != nil {
+		return "source is not an int or dotted-quad address"
+	}
+	if _, err := parseNodeID(ids[1]); err != nil {
+		return "destination is not an int or dotted-quad address"
+	}
+	_, after, _ = strings.Cut(after, "\"")
+	rest := strings.Fields(after)
+	if len(rest) == 0 {
+		return "missing delay"
+	}
+	delay, err := strconv.Atoi(rest[0])
+	if err != nil {
+		return "delay is not an int"
+	}
+	if delay < 0 {
+		return "delay must not be negative"
+	}
+	if len(rest) == 2 {
+		return "StartTick and StopTick must be given together"
+	}
+	if len(rest) > 3 {
+		return "unexpected fields after StopTick"
+	}
+	return "does not match the node config format"
+}
+
+// nodeConfigJoin is a line of a node configuration making a node join a multicast group.
+type nodeConfigJoin struct {
+	id      NodeID
+	group   NodeID
+	lineNum int
+	raw     string
 }
 
 // ReadNodeConfiguration parses newline separated node configurations from an io.ReadCloser.
-// Configurations should be in the form: {Source} {Destination} "{Message}" {Delay}
+// Configurations should be in the form: {Source} {Destination} "{Message}" {Delay} [{StartTick} {StopTick}]
+// where Source and Destination are either integers or dotted-quad addresses.
+// A configured node joins a multicast group with a line of the form: {ID} JOIN {Group}
+// Blank lines and lines starting with '#' are ignored.
 func ReadNodeConfiguration(in io.Reader) ([]NodeConfig, error) {
 	configs := make([]NodeConfig, 0)
+	joins := make([]nodeConfigJoin, 0)
 
-	re := regexp.MustCompile(`(?P<Source>\d{1,2}) (?P<Destination>\d{1,2}) (?P<Message>".*?") (?P<Delay>\d{1,2})`)
+	re := regexp.MustCompile(`^(?P<Source>` + nodeIDPattern + `) (?P<Destination>` + nodeIDPattern + `) (?P<Message>".*?") (?P<Delay>\d+)(?: (?P<StartTick>\d+) (?P<StopTick>\d+))?$`)
+	joinRe := regexp.MustCompile(`^(?P<ID>` + nodeIDPattern + `) JOIN (?P<Group>` + nodeIDPattern + `)$`)
 
-	r := bufio.NewReader(in)
+	r, err := newInputReader(in)
+	if err != nil {
+		return nil, err
+	}
+	lineNum := 0
 	for {
 		line, err := r.ReadString('\n')
-		if err != nil {
-			if errors.Is(err, io.EOF) {
-				break
-			}
+		if err != nil && !errors.Is(err, io.EOF) {
 			return nil, err
 		}
+		// A final line without a trailing newline is returned along with io.EOF.
+		if errors.Is(err, io.EOF) && line == "" {
+			break
+		}
+		lineNum++
 		line = strings.TrimSuffix(line, "\n")
+		// Blank and comment lines are skipped, though still counted for error messages.
+		if trimmed := strings.TrimSpace(line); trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		bad := func(reason string) error {
+			return ErrBadNodeConfigLine{LineNum: lineNum, Raw: line, Reason: reason}
+		}
+		if matches := joinRe.FindStringSubmatch(line); matches != nil {
+			id, err := parseNodeID(matches[1])
+			if err != nil {
+				return nil, bad("node is not an int or dotted-quad address")
+			}
+			group, err := parseNodeID(matches[2])
+			if err != nil || !IsMulticast(group) {
+				return nil, bad("group is not a multicast address")
+			}
+			joins = append(joins, nodeConfigJoin{id: id, group: group, lineNum: lineNum, raw: line})
+			continue
+		}
 		matches := re.FindStringSubmatch(line)
+		if matches == nil {
+			return nil, bad(diagnoseNodeConfigLine(line))
+		}
 
-		id, err := strconv.Atoi(matches[1])
+		id, err := parseNodeID(matches[1])
 		if err != nil {
-			return nil, fmt.Errorf("invalid node config: ID is not an int: %s", line)
+			return nil, bad("source is not an int or dotted-quad address")
 		}
-		dst, err := strconv.Atoi(matches[2])
+		dst, err := parseNodeID(matches[2])
 		if err != nil {
-			return nil, fmt.Errorf("invalid node config: Destination is not an int: %s", line)
+			return nil, bad("destination is not an int or dotted-quad address")
 		}
 		delay, err := strconv.Atoi(matches[4])
 		if err != nil {
-			return nil, fmt.Errorf("invalid node config: Delay is not an int: %s", line)
+			return nil, bad("delay is not an int")
+		}
+
+		var start, stop int
+		if matches[5] != "" {
+			start, err = strconv.Atoi(matches[5])
+			if err != nil {
+				return nil, bad("StartTick is not an int")
+			}
+			stop, err = strconv.Atoi(matches[6])
+			if err != nil {
+				return nil, bad("StopTick is not an int")
+			}
+			if stop != 0 && stop <= start {
+				return nil, bad("StopTick must be after StartTick")
+			}
 		}
 
 		c := NodeConfig{
-			ID: NodeID(id),
+			ID: id,
 			Message: NodeMessage{
 				Message:     matches[3][1 : len(matches[3])-1],
 				Delay:       delay,
-				Destination: NodeID(dst),
+				Destination: dst,
 				Sent:        false,
 			},
+			StartTick: start,
+			StopTick:  stop,
 		}
 
 		configs = append(configs, c)
 	}
+
+	// A node may join groups before or after its configuration line.
+	for _, join := range joins {
+		i := 0
+		for i < len(configs) && configs[i].ID != join.id {
+			i++
+		}
+		if i == len(configs) {
+			return nil, ErrBadNodeConfigLine{LineNum: join.lineNum, Raw: join.raw, Reason: "node is not configured"}
+		}
+		configs[i].Groups = append(configs[i].Groups, join.group)
+	}
 	return configs, nil
 }
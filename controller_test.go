@@ -1,10 +1,14 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"io"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestReadNodeConfiguration(t *testing.T) {
@@ -33,6 +37,129 @@ func TestReadNodeConfiguration(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "active window",
+			args: args{in: io.NopCloser(strings.NewReader("0 2 \"(0 -> 2)\" 30 10 50\n"))},
+			want: []NodeConfig{
+				{
+					ID: 0,
+					Message: NodeMessage{
+						Message:     "(0 -> 2)",
+						Delay:       30,
+						Destination: 2,
+						Sent:        false,
+					},
+					StartTick: 10,
+					StopTick:  50,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "dotted-quad IDs",
+			args: args{in: io.NopCloser(strings.NewReader("10.0.0.1 10.0.0.2 \"hi\" 30\n"))},
+			want: []NodeConfig{
+				{
+					ID: 0x0A000001,
+					Message: NodeMessage{
+						Message:     "hi",
+						Delay:       30,
+						Destination: 0x0A000002,
+						Sent:        false,
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "multi-digit IDs",
+			args: args{in: io.NopCloser(strings.NewReader("123 4 \"z\" 1\n"))},
+			want: []NodeConfig{
+				{
+					ID: 123,
+					Message: NodeMessage{
+						Message:     "z",
+						Delay:       1,
+						Destination: 4,
+						Sent:        false,
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "no trailing newline",
+			args: args{in: io.NopCloser(strings.NewReader("0 2 \"(0 -> 2)\" 30\n1 0 \"(1 -> 0)\" 40"))},
+			want: []NodeConfig{
+				{
+					ID: 0,
+					Message: NodeMessage{
+						Message:     "(0 -> 2)",
+						Delay:       30,
+						Destination: 2,
+						Sent:        false,
+					},
+				},
+				{
+					ID: 1,
+					Message: NodeMessage{
+						Message:     "(1 -> 0)",
+						Delay:       40,
+						Destination: 0,
+						Sent:        false,
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "group membership",
+			args: args{in: io.NopCloser(strings.NewReader("0 JOIN 224.0.0.1\n0 2 \"hi\" 30\n0 JOIN 224.0.0.2\n"))},
+			want: []NodeConfig{
+				{
+					ID: 0,
+					Message: NodeMessage{
+						Message:     "hi",
+						Delay:       30,
+						Destination: 2,
+						Sent:        false,
+					},
+					Groups: []NodeID{0xE0000001, 0xE0000002},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name: "comments and blank lines",
+			args: args{in: io.NopCloser(strings.NewReader("# scenario\n\n0 2 \"(0 -> 2)\" 30\n  # indented\n   \n1 0 \"# not a comment\" 40\n"))},
+			want: []NodeConfig{
+				{
+					ID: 0,
+					Message: NodeMessage{
+						Message:     "(0 -> 2)",
+						Delay:       30,
+						Destination: 2,
+						Sent:        false,
+					},
+				},
+				{
+					ID: 1,
+					Message: NodeMessage{
+						Message:     "# not a comment",
+						Delay:       40,
+						Destination: 0,
+						Sent:        false,
+					},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "stop before start",
+			args:    args{in: io.NopCloser(strings.NewReader("0 2 \"(0 -> 2)\" 30 10 5\n"))},
+			want:    nil,
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -47,3 +174,384 @@ func TestReadNodeConfiguration(t *testing.T) {
 		})
 	}
 }
+
+func TestReadNodeConfiguration_errors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want ErrBadNodeConfigLine
+	}{
+		{
+			name: "missing fields",
+			in:   "0 \"hi\" 30\n",
+			want: ErrBadNodeConfigLine{LineNum: 1, Raw: "0 \"hi\" 30", Reason: "expected a source and destination before the message"},
+		},
+		{
+			name: "bad source",
+			in:   "x 2 \"hi\" 30\n",
+			want: ErrBadNodeConfigLine{LineNum: 1, Raw: "x 2 \"hi\" 30", Reason: "source is not an int or dotted-quad address"},
+		},
+		{
+			name: "bad destination",
+			in:   "0 y \"hi\" 30\n",
+			want: ErrBadNodeConfigLine{LineNum: 1, Raw: "0 y \"hi\" 30", Reason: "destination is not an int or dotted-quad address"},
+		},
+		{
+			name: "unterminated quote",
+			in:   "0 2 \"hi 30\n",
+			want: ErrBadNodeConfigLine{LineNum: 1, Raw: "0 2 \"hi 30", Reason: "unterminated quote"},
+		},
+		{
+			name: "missing message",
+			in:   "0 2 30\n",
+			want: ErrBadNodeConfigLine{LineNum: 1, Raw: "0 2 30", Reason: "missing quoted message"},
+		},
+		{
+			name: "missing delay",
+			in:   "0 2 \"hi\"\n",
+			want: ErrBadNodeConfigLine{LineNum: 1, Raw: "0 2 \"hi\"", Reason: "missing delay"},
+		},
+		{
+			name: "bad delay",
+			in:   "0 2 \"hi\" soon\n",
+			want: ErrBadNodeConfigLine{LineNum: 1, Raw: "0 2 \"hi\" soon", Reason: "delay is not an int"},
+		},
+		{
+			name: "negative delay",
+			in:   "0 2 \"hi\" -5\n",
+			want: ErrBadNodeConfigLine{LineNum: 1, Raw: "0 2 \"hi\" -5", Reason: "delay must not be negative"},
+		},
+		{
+			name: "stop before start",
+			in:   "0 2 \"hi\" 30 10 5\n",
+			want: ErrBadNodeConfigLine{LineNum: 1, Raw: "0 2 \"hi\" 30 10 5", Reason: "StopTick must be after StartTick"},
+		},
+		{
+			name: "leading field",
+			in:   "x 0 2 \"hi\" 30\n",
+			want: ErrBadNodeConfigLine{LineNum: 1, Raw: "x 0 2 \"hi\" 30", Reason: "expected a source and destination before the message"},
+		},
+		{
+			name: "trailing field",
+			in:   "0 2 \"hi\" 30 10 50 7\n",
+			want: ErrBadNodeConfigLine{LineNum: 1, Raw: "0 2 \"hi\" 30 10 50 7", Reason: "unexpected fields after StopTick"},
+		},
+		{
+			name: "missing StopTick",
+			in:   "0 2 \"hi\" 30 10\n",
+			want: ErrBadNodeConfigLine{LineNum: 1, Raw: "0 2 \"hi\" 30 10", Reason: "StartTick and StopTick must be given together"},
+		},
+		{
+			name: "final line without newline",
+			in:   "0 2 \"hi\" 30\n1 2 \"hi\"",
+			want: ErrBadNodeConfigLine{LineNum: 2, Raw: "1 2 \"hi\"", Reason: "missing delay"},
+		},
+		{
+			name: "join of a unicast address",
+			in:   "0 2 \"hi\" 30\n0 JOIN 10.0.0.1\n",
+			want: ErrBadNodeConfigLine{LineNum: 2, Raw: "0 JOIN 10.0.0.1", Reason: "group is not a multicast address"},
+		},
+		{
+			name: "malformed join",
+			in:   "0 2 \"hi\" 30\n0 JOIN\n",
+			want: ErrBadNodeConfigLine{LineNum: 2, Raw: "0 JOIN", Reason: "group membership must be of the form: {ID} JOIN {GROUP}"},
+		},
+		{
+			name: "join of an unconfigured node",
+			in:   "0 2 \"hi\" 30\n1 JOIN 224.0.0.1\n",
+			want: ErrBadNodeConfigLine{LineNum: 2, Raw: "1 JOIN 224.0.0.1", Reason: "node is not configured"},
+		},
+		{
+			name: "later line",
+			in:   "0 2 \"hi\" 30\n1 2 \"hi\n",
+			want: ErrBadNodeConfigLine{LineNum: 2, Raw: "1 2 \"hi", Reason: "unterminated quote"},
+		},
+		{
+			name: "after comments and blank lines",
+			in:   "# scenario\n\n0 2 \"hi\" 30\n# next\n1 2 \"hi\"\n",
+			want: ErrBadNodeConfigLine{LineNum: 5, Raw: "1 2 \"hi\"", Reason: "missing delay"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReadNodeConfiguration(strings.NewReader(tt.in))
+			var got ErrBadNodeConfigLine
+			if !errors.As(err, &got) {
+				t.Fatalf("ReadNodeConfiguration() error = %v, want ErrBadNodeConfigLine", err)
+			}
+			if got != tt.want {
+				t.Errorf("ReadNodeConfiguration() error = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestController_deliver(t *testing.T) {
+	c := NewController(NetworkTypology{}, time.Millisecond)
+	in := make(chan interface{}, 1)
+	c.nodeChannels[1] = in
+	c.nodeStarted[1] = make(chan struct{})
+	c.nodeStopped[1] = make(chan struct{})
+
+	// Not yet online, so the message is dropped.
+	c.deliver(1, &HelloMessage{Source: 0})
+	if len(in) != 0 {
+		t.Fatalf("deliver() to offline node queued %d messages, want 0", len(in))
+	}
+
+	close(c.nodeStarted[1])
+	c.deliver(1, &HelloMessage{Source: 0})
+	if len(in) != 1 {
+		t.Fatalf("deliver() to online node queued %d messages, want 1", len(in))
+	}
+
+	// Once offline, delivery must not block even though the channel is full.
+	close(c.nodeStopped[1])
+	c.deliver(1, &HelloMessage{Source: 0})
+}
+
+func TestController_ScheduleData(t *testing.T) {
+	c := NewController(NetworkTypology{}, time.Millisecond)
+	c.nodes = append(c.nodes, newTestNode(0, &recordingTransmitter{}))
+
+	if err := c.ScheduleData(0, 1, "data", 10); err != nil {
+		t.Errorf("ScheduleData() error = %v, want nil", err)
+	}
+	if err := c.ScheduleData(5, 1, "data", 10); err == nil {
+		t.Errorf("ScheduleData() from unknown node error = nil, want error")
+	}
+	if want := []scheduledData{{src: 0, dst: 1, data: "data", atTick: 10}}; !reflect.DeepEqual(c.scheduledData, want) {
+		t.Errorf("scheduledData = %v, want %v", c.scheduledData, want)
+	}
+}
+
+func TestController_dataResolved(t *testing.T) {
+	c := NewController(NetworkTypology{}, time.Millisecond)
+	c.nodes = append(c.nodes, newTestNode(0, &recordingTransmitter{}))
+	_ = c.ScheduleData(0, 1, "first", 10)
+	_ = c.ScheduleData(0, 1, "second", 10)
+
+	c.dataResolved(&DataMessage{Data: "first"}, dataDelivered)
+	select {
+	case <-c.allDataResolved:
+		t.Fatalf("allDataResolved closed with 1 outstanding message")
+	default:
+	}
+
+	c.dataResolved(&DataMessage{Data: "second"}, dataDropped)
+	select {
+	case <-c.allDataResolved:
+	default:
+		t.Fatalf("allDataResolved not closed with 0 outstanding messages")
+	}
+}
+
+func TestController_StopWhenResolved_unroutable(t *testing.T) {
+	// Node 2 is never reachable, so node 0 retries its configured message until it gives up and drops it.
+	topology, err := NewNetworkTypology(strings.NewReader("0 UP 0 1\n0 UP 1 0\n"))
+	if err != nil {
+		t.Fatalf("NewNetworkTypology() error = %v", err)
+	}
+	c := NewController(*topology, time.Millisecond)
+	c.SetLogDir(t.TempDir())
+	c.SetSynchronous(true)
+	c.Initialize([]NodeConfig{
+		{ID: 0, Message: NodeMessage{Message: "unroutable", Delay: 10, Destination: 2}},
+		{ID: 1, Message: NodeMessage{Sent: true}},
+		{ID: 2, Message: NodeMessage{Sent: true}},
+	})
+	c.StopWhenResolved(0)
+
+	want := 10 + nodeMsgMaxRetries*nodeMsgRetryInterval
+	if got := c.Start(1000); got > want+1 {
+		t.Errorf("Start() = %d, want the simulation to stop once the message is dropped at tick %d", got, want)
+	}
+	n, _ := c.node(0)
+	if got := n.Counters().DataDropped; got != 1 {
+		t.Errorf("DataDropped = %d, want 1", got)
+	}
+}
+
+func TestController_unidirectionalLink(t *testing.T) {
+	// Node 1 can hear node 0, but node 0 can not hear node 1.
+	topology, err := NewNetworkTypology(strings.NewReader("0 UP 0 1\n"))
+	if err != nil {
+		t.Fatalf("NewNetworkTypology() error = %v", err)
+	}
+	c := NewController(*topology, time.Millisecond)
+	c.SetLogDir(t.TempDir())
+	c.Initialize([]NodeConfig{
+		{ID: 0, Message: NodeMessage{Sent: true}},
+		{ID: 1, Message: NodeMessage{Sent: true}},
+	})
+	c.SetSynchronous(true)
+	c.Start(30)
+
+	n0, _ := c.node(0)
+	if got := n0.Snapshot().OneHopNeighbors; len(got) != 0 {
+		t.Errorf("node 0 neighbors = %v, want none", got)
+	}
+	n1, _ := c.node(1)
+	if got, want := n1.Snapshot().OneHopNeighbors, map[NodeID]NeighborState{0: unidirectional}; !reflect.DeepEqual(got, want) {
+		t.Errorf("node 1 neighbors = %v, want %v", got, want)
+	}
+	if got := n1.Snapshot().RoutingTable; len(got) != 0 {
+		t.Errorf("node 1 routes = %v, want none over a unidirectional link", got)
+	}
+}
+
+func TestController_CurrentTick(t *testing.T) {
+	tests := []struct {
+		name        string
+		synchronous bool
+	}{
+		{name: "synchronous", synchronous: true},
+		{name: "goroutines", synchronous: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topology, err := NewNetworkTypology(strings.NewReader("0 UP 0 1\n0 UP 1 0\n"))
+			if err != nil {
+				t.Fatalf("NewNetworkTypology() error = %v", err)
+			}
+			c := NewController(*topology, 5*time.Millisecond)
+			c.SetLogDir(t.TempDir())
+			c.Initialize([]NodeConfig{{ID: 0, Message: NodeMessage{Sent: true}}, {ID: 1, Message: NodeMessage{Sent: true}}})
+			c.SetSynchronous(tt.synchronous)
+			if got := c.CurrentTick(); got != 0 {
+				t.Errorf("CurrentTick() before Start = %d, want 0", got)
+			}
+
+			var mu sync.Mutex
+			got := make(map[int]int)
+			ticks := []int{0, 3, 7, 12}
+			for _, tick := range ticks {
+				tick := tick
+				if err := c.ScheduleNodeChange(0, tick, func(*Node) {
+					mu.Lock()
+					defer mu.Unlock()
+					got[tick] = c.CurrentTick()
+				}); err != nil {
+					t.Fatalf("ScheduleNodeChange() error = %v", err)
+				}
+			}
+			c.Start(15)
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, tick := range ticks {
+				// Without lock-step ticks, the Controller may have moved on by the time the change is made.
+				if got[tick] < tick || (tt.synchronous && got[tick] != tick) {
+					t.Errorf("CurrentTick() at scheduled tick %d = %d", tick, got[tick])
+				}
+			}
+		})
+	}
+}
+
+func TestController_PauseResume(t *testing.T) {
+	c := NewController(NetworkTypology{}, time.Millisecond)
+	n := newTestNode(0, &recordingTransmitter{})
+	n.gate = c.gate
+	clock := make(chan time.Time)
+	n.clock = clock
+
+	done := make(chan struct{})
+	go func() {
+		n.Run(context.Background())
+		close(done)
+	}()
+
+	// Sending on the unbuffered clock only succeeds once the Node has finished the previous tick.
+	for i := 0; i < 5; i++ {
+		clock <- time.Time{}
+	}
+	c.Pause()
+	clock <- time.Time{}
+	// The Node has finished 5 ticks and taken a sixth, which is blocked on the paused clock.
+	paused := n.Snapshot().Tick
+	if paused != 5 {
+		t.Errorf("tick = %d while paused, want 5", paused)
+	}
+
+	// So it neither ticks nor takes another tick.
+	select {
+	case clock <- time.Time{}:
+		t.Fatalf("node took a tick while paused")
+	default:
+	}
+	if got := n.Snapshot().Tick; got != paused {
+		t.Errorf("tick advanced while paused: %d -> %d", paused, got)
+	}
+
+	c.Resume()
+	clock <- time.Time{}
+	close(clock)
+	<-done
+	if got := n.Snapshot().Tick; got != paused+2 {
+		t.Errorf("tick = %d after resuming, want %d", got, paused+2)
+	}
+}
+
+func TestValidateScenario(t *testing.T) {
+	configs, err := ReadNodeConfiguration(strings.NewReader(
+		"0 0 \"to self\" 10\n" +
+			"1 7 \"to nowhere\" 10\n" +
+			"2 0 \"to zero\" 10\n"))
+	if err != nil {
+		t.Fatalf("ReadNodeConfiguration() error = %v", err)
+	}
+	want := []string{
+		"node 0: message is addressed to itself and will be delivered locally",
+		"node 1: message destination 7 is not a configured node",
+	}
+	if got := ValidateScenario(configs); !reflect.DeepEqual(got, want) {
+		t.Errorf("ValidateScenario() = %v, want %v", got, want)
+	}
+}
+
+func TestController_topologyOnlyNodes(t *testing.T) {
+	newTopology := func() *NetworkTypology {
+		// Node 7 has links, but no configuration.
+		topology, err := NewNetworkTypology(strings.NewReader("0 UP 0 7\n0 UP 7 0\n0 UP 1 7\n0 UP 7 1\n"))
+		if err != nil {
+			t.Fatalf("NewNetworkTypology() error = %v", err)
+		}
+		return topology
+	}
+	configs := []NodeConfig{
+		{ID: 0, Message: NodeMessage{Sent: true}},
+		{ID: 1, Message: NodeMessage{Sent: true}},
+	}
+
+	strict := NewController(*newTopology(), time.Millisecond)
+	strict.SetLogDir(t.TempDir())
+	strict.SetStrictTopology(true)
+	if err := strict.Initialize(configs); err == nil || !strings.Contains(err.Error(), "7") {
+		t.Errorf("Initialize() error = %v, want an error naming node 7", err)
+	}
+	if len(strict.nodes) != 0 {
+		t.Errorf("Initialize() created %d nodes despite the error", len(strict.nodes))
+	}
+
+	c := NewController(*newTopology(), time.Hour)
+	c.SetLogDir(t.TempDir())
+	if err := c.Initialize(configs); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	c.SetSynchronous(true)
+	c.Start(20)
+
+	n7, ok := c.node(7)
+	if !ok {
+		t.Fatalf("node 7 was not created")
+	}
+	want := map[NodeID]NeighborState{0: bidirectional, 1: bidirectional}
+	if got := n7.Snapshot().OneHopNeighbors; !reflect.DeepEqual(got, want) {
+		t.Errorf("node 7 neighbors = %v, want %v", got, want)
+	}
+	n0, _ := c.node(0)
+	if got := n0.Snapshot().TwoHopNeighbors[7]; !reflect.DeepEqual(got, []NodeID{1}) {
+		t.Errorf("node 0 two-hop neighbors via 7 = %v, want [1]", got)
+	}
+}
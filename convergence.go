@@ -0,0 +1,228 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// RecordConvergence makes the Controller record, at the end of every tick, the fraction of ordered pairs of online
+// nodes which have a correct route to each other. See ConvergenceTimeline. Must be called before Start.
+func (c *Controller) RecordConvergence(enabled bool) {
+	c.recordConvergence = enabled
+}
+
+// ConvergenceTimeline returns the fraction of node pairs with a correct route at the end of each tick, indexed by
+// tick, if RecordConvergence was enabled. A route is correct if it is as short as the shortest path over the
+// bidirectional links between online nodes at that tick, and its next hop is on such a path. Only pairs with such a
+// path are counted, so a tick without any is fully converged.
+func (c *Controller) ConvergenceTimeline() []float64 {
+	c.convergenceMu.Lock()
+	defer c.convergenceMu.Unlock()
+
+	return append([]float64(nil), c.convergence...)
+}
+
+// sampleConvergence records the fraction of node pairs with a correct route at the end of the tick.
+func (c *Controller) sampleConvergence(tick int, online func(id NodeID) bool) {
+	accuracy := c.routeAccuracy(tick, online)
+
+	c.convergenceMu.Lock()
+	defer c.convergenceMu.Unlock()
+	// Ticks missed while sampling in real time take the value observed after them.
+	for len(c.convergence) <= tick {
+		c.convergence = append(c.convergence, accuracy)
+	}
+}
+
+// sampleConvergenceEachTick samples the convergence at the end of every tick, until the context is done.
+func (c *Controller) sampleConvergenceEachTick(ctx context.Context, epoch time.Time) {
+	for tick := 0; c.waitUntilTick(ctx, epoch, tick+1); tick++ {
+		c.sampleConvergence(tick, c.running)
+	}
+}
+
+// routeAccuracy determines the fraction of ordered pairs of online nodes, connected over bidirectional links at the
+// tick, whose routing table holds a correct route from one to the other.
+func (c *Controller) routeAccuracy(tick int, online func(id NodeID) bool) float64 {
+	states := make(map[NodeID]NodeState)
+	for _, n := range c.nodes {
+		if online(n.id) {
+			states[n.id] = n.Snapshot()
+		}
+	}
+	ids := sortedNodeIDs(states)
+
+	symmetric := func(a, b NodeID) bool {
+		return c.symmetricLink(a, b, tick)
+	}
+	distances := c.hopDistances(ids, tick)
+
+	pairs, correct := 0, 0
+	for _, src := range ids {
+		routes := make(map[NodeID]RoutingEntry)
+		for _, route := range states[src].RoutingTable {
+			if _, in := routes[route.Destination]; !in {
+				routes[route.Destination] = route
+			}
+		}
+		for _, dst := range ids {
+			want, connected := distances[dst][src]
+			if src == dst || !connected {
+				continue
+			}
+			pairs++
+			route, in := routes[dst]
+			if !in || route.Distance != want || !symmetric(src, route.NextHop) {
+				continue
+			}
+			if d, in := distances[dst][route.NextHop]; in && d == want-1 {
+				correct++
+			}
+		}
+	}
+	if pairs == 0 {
+		return 1
+	}
+	return float64(correct) / float64(pairs)
+}
+
+// symmetricLink determines whether the link between two nodes is up in both directions at the tick.
+func (c *Controller) symmetricLink(a, b NodeID, tick int) bool {
+	return c.linkUp(QueryMsg{FromNode: a, ToNode: b, AtTime: tick}) &&
+		c.linkUp(QueryMsg{FromNode: b, ToNode: a, AtTime: tick})
+}
+
+// hopDistances determines the hop count of the shortest path from each node to each destination among the nodes, over
+// the links which are up in both directions at the tick. Unreachable destinations are left out.
+func (c *Controller) hopDistances(ids []NodeID, tick int) map[NodeID]map[NodeID]int {
+	distances := make(map[NodeID]map[NodeID]int, len(ids))
+	for _, dst := range ids {
+		distances[dst] = map[NodeID]int{dst: 0}
+		frontier := []NodeID{dst}
+		for len(frontier) > 0 {
+			next := make([]NodeID, 0)
+			for _, a := range frontier {
+				for _, b := range ids {
+					if _, seen := distances[dst][b]; !seen && c.symmetricLink(a, b, tick) {
+						distances[dst][b] = distances[dst][a] + 1
+						next = append(next, b)
+					}
+				}
+			}
+			frontier = next
+		}
+	}
+	return distances
+}
+
+// EstimatedConvergenceBound estimates the most ticks the nodes need to converge on correct routes, from scratch, over
+// the topology as of the current tick (see CurrentTick), given their configured intervals. It is computed from the
+// ground-truth topology rather than observed, for comparison with the measured convergence (see ConvergenceTimeline).
+//
+// Each neighbor must hear three HELLO rounds: one to detect the link, one to confirm it is symmetric, and one to learn
+// the MPR selection and two-hop neighbors. The MPRs then advertise their selectors in a TC, within a TC interval,
+// which is flooded across the network's diameter. Every message crossing a link takes a tick, plus the longest link
+// delay. The slowest intervals of any online node are used, so the bound is conservative.
+func (c *Controller) EstimatedConvergenceBound() int {
+	tick := c.CurrentTick()
+	ids := make([]NodeID, 0, len(c.nodes))
+	helloInterval, tcInterval := 0, 0
+	for _, n := range c.nodes {
+		// Whether a node is online is determined by its configured window, so the bound can be computed before Start.
+		if !c.online(n.id, tick) {
+			continue
+		}
+		ids = append(ids, n.id)
+		n.mu.RLock()
+		if n.helloInterval > helloInterval {
+			helloInterval = n.helloInterval
+		}
+		if tc := n.willingness.scaleTCInterval(n.tcInterval); tc > tcInterval {
+			tcInterval = tc
+		}
+		n.mu.RUnlock()
+	}
+	sortNodeIDs(ids)
+
+	diameter, delay := 0, 0
+	distances := c.hopDistances(ids, tick)
+	for _, a := range ids {
+		for _, b := range ids {
+			if d, in := distances[b][a]; in && d > diameter {
+				diameter = d
+			}
+			if a != b && c.symmetricLink(a, b, tick) {
+				if d := c.linkDelay(QueryMsg{FromNode: a, ToNode: b, AtTime: tick}); d > delay {
+					delay = d
+				}
+			}
+		}
+	}
+	// Without any links, there are no routes to converge on.
+	if diameter == 0 {
+		return 0
+	}
+	hop := 1 + delay
+	return 3*(helloInterval+hop) + tcInterval + diameter*hop
+}
+
+// DiffConvergenceTimelines returns, for each tick, how much more converged the second timeline is than the first. The
+// shorter timeline is extended with its final value.
+func DiffConvergenceTimelines(a, b []float64) []float64 {
+	at := func(timeline []float64, tick int) float64 {
+		if len(timeline) == 0 {
+			return 0
+		}
+		if tick >= len(timeline) {
+			return timeline[len(timeline)-1]
+		}
+		return timeline[tick]
+	}
+	ticks := len(a)
+	if len(b) > ticks {
+		ticks = len(b)
+	}
+	diff := make([]float64, ticks)
+	for tick := range diff {
+		diff[tick] = at(b, tick) - at(a, tick)
+	}
+	return diff
+}
+
+// WriteConvergenceTimelinesCSV writes named convergence timelines as CSV for plotting, with a row for each tick and
+// a column for each timeline. Ticks beyond the end of a timeline are left empty.
+func WriteConvergenceTimelinesCSV(w io.Writer, names []string, timelines ...[]float64) error {
+	if len(names) != len(timelines) {
+		return fmt.Errorf("write convergence timelines: %d names for %d timelines", len(names), len(timelines))
+	}
+	ticks := 0
+	for _, timeline := range timelines {
+		if len(timeline) > ticks {
+			ticks = len(timeline)
+		}
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write(append([]string{"tick"}, names...)); err != nil {
+		return err
+	}
+	for tick := 0; tick < ticks; tick++ {
+		row := []string{strconv.Itoa(tick)}
+		for _, timeline := range timelines {
+			value := ""
+			if tick < len(timeline) {
+				value = strconv.FormatFloat(timeline[tick], 'f', -1, 64)
+			}
+			row = append(row, value)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestController_ConvergenceTimeline(t *testing.T) {
+	// A line of nodes 0 - 1 - 2 - 3, with 12 ordered pairs.
+	topology, err := NewNetworkTypology(strings.NewReader(
+		"0 UP 0 1\n0 UP 1 0\n0 UP 1 2\n0 UP 2 1\n0 UP 2 3\n0 UP 3 2\n"))
+	if err != nil {
+		t.Fatalf("NewNetworkTypology() error = %v", err)
+	}
+	c := NewController(*topology, time.Hour)
+	c.SetLogDir(t.TempDir())
+	configs := make([]NodeConfig, 0)
+	for id := NodeID(0); id < 4; id++ {
+		configs = append(configs, NodeConfig{ID: id, Message: NodeMessage{Sent: true}})
+	}
+	c.Initialize(configs)
+	c.SetSynchronous(true)
+	c.RecordConvergence(true)
+	c.Start(25)
+
+	// Neighbors become symmetric after the second HELLO round, the two-hop routes follow the third, and the three-hop
+	// routes need a TC.
+	want := make([]float64, 0, 25)
+	for tick := 0; tick < 25; tick++ {
+		switch {
+		case tick < 6:
+			want = append(want, 0)
+		case tick < 11:
+			want = append(want, 6.0/12)
+		case tick < 22:
+			want = append(want, 10.0/12)
+		default:
+			want = append(want, 1)
+		}
+	}
+	if got := c.ConvergenceTimeline(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ConvergenceTimeline() = %v, want %v", got, want)
+	}
+}
+
+func TestDiffConvergenceTimelines(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []float64
+		want []float64
+	}{
+		{name: "same length", a: []float64{0, 0.5, 1}, b: []float64{0, 1, 1}, want: []float64{0, 0.5, 0}},
+		{name: "shorter first", a: []float64{0, 0.5}, b: []float64{0, 0.25, 1, 1}, want: []float64{0, -0.25, 0.5, 0.5}},
+		{name: "empty", a: nil, b: []float64{1}, want: []float64{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffConvergenceTimelines(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffConvergenceTimelines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteConvergenceTimelinesCSV(t *testing.T) {
+	var buf bytes.Buffer
+	err := WriteConvergenceTimelinesCSV(&buf, []string{"tc10", "tc5"}, []float64{0, 0.5, 1}, []float64{0, 1})
+	if err != nil {
+		t.Fatalf("WriteConvergenceTimelinesCSV() error = %v", err)
+	}
+	want := "tick,tc10,tc5\n0,0,0\n1,0.5,1\n2,1,\n"
+	if got := buf.String(); got != want {
+		t.Errorf("WriteConvergenceTimelinesCSV() = %q, want %q", got, want)
+	}
+
+	if err := WriteConvergenceTimelinesCSV(&buf, []string{"a"}); err == nil {
+		t.Errorf("WriteConvergenceTimelinesCSV() with mismatched names error = nil, want an error")
+	}
+}
+
+func TestController_EstimatedConvergenceBound(t *testing.T) {
+	tests := []struct {
+		name     string
+		topology string
+		nodes    []NodeConfig
+		// change is made to every node before the simulation starts.
+		change func(n *Node)
+		want   int
+	}{
+		{
+			name:     "no links",
+			topology: "",
+			nodes:    []NodeConfig{{ID: 0}, {ID: 1}},
+			want:     0,
+		},
+		{
+			name:     "line",
+			topology: "0 UP 0 1\n0 UP 1 0\n0 UP 1 2\n0 UP 2 1\n0 UP 2 3\n0 UP 3 2\n",
+			nodes:    []NodeConfig{{ID: 0}, {ID: 1}, {ID: 2}, {ID: 3}},
+			// Three HELLO rounds of 5 + 1, a TC interval of 10, and a diameter of 3.
+			want: 31,
+		},
+		{
+			name:     "star with a delayed link",
+			topology: "0 UP 0 1\n0 UP 1 0 2\n0 UP 0 2\n0 UP 2 0\n0 UP 0 3\n0 UP 3 0\n",
+			nodes:    []NodeConfig{{ID: 0}, {ID: 1}, {ID: 2}, {ID: 3}},
+			want:     3*(5+3) + 10 + 2*3,
+		},
+		{
+			name:     "one-way link ignored",
+			topology: "0 UP 0 1\n0 UP 1 0\n0 UP 1 2\n",
+			nodes:    []NodeConfig{{ID: 0}, {ID: 1}, {ID: 2}},
+			want:     3*(5+1) + 10 + 1,
+		},
+		{
+			name:     "offline node ignored",
+			topology: "0 UP 0 1\n0 UP 1 0\n0 UP 1 2\n0 UP 2 1\n",
+			nodes:    []NodeConfig{{ID: 0}, {ID: 1}, {ID: 2, StartTick: 50}},
+			want:     3*(5+1) + 10 + 1,
+		},
+		{
+			name:     "slower intervals",
+			topology: "0 UP 0 1\n0 UP 1 0\n",
+			nodes:    []NodeConfig{{ID: 0}, {ID: 1}},
+			change: func(n *Node) {
+				n.SetHelloInterval(8)
+				n.SetWillingness(WillLow)
+			},
+			want: 3*(8+1) + 20 + 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topology, err := NewNetworkTypology(strings.NewReader(tt.topology))
+			if err != nil {
+				t.Fatalf("NewNetworkTypology() error = %v", err)
+			}
+			c := NewController(*topology, time.Hour)
+			c.SetLogDir(t.TempDir())
+			for i := range tt.nodes {
+				tt.nodes[i].Message = NodeMessage{Sent: true}
+			}
+			c.Initialize(tt.nodes)
+			if tt.change != nil {
+				for _, n := range c.nodes {
+					tt.change(n)
+				}
+			}
+			if got := c.EstimatedConvergenceBound(); got != tt.want {
+				t.Errorf("EstimatedConvergenceBound() = %d, want %d", got, tt.want)
+			}
+
+			// The measured convergence must be within the bound.
+			c.SetSynchronous(true)
+			c.RecordConvergence(true)
+			c.Start(tt.want + 10)
+			if got := c.Stats().ConvergenceTick; got < 0 || got > tt.want {
+				t.Errorf("measured convergence at tick %d, want within the bound of %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// Example runs a 4-node diamond, where node 0 can only reach node 3 via node 1 or node 2:
+//
+//	  1
+//	 / \
+//	0   3
+//	 \ /
+//	  2
+func Example() {
+	topology, err := NewNetworkTypology(strings.NewReader(
+		"0 UP 0 1\n0 UP 1 0\n" +
+			"0 UP 0 2\n0 UP 2 0\n" +
+			"0 UP 1 3\n0 UP 3 1\n" +
+			"0 UP 2 3\n0 UP 3 2\n"))
+	if err != nil {
+		panic(err)
+	}
+
+	logDir, err := os.MkdirTemp("", "olsrsim")
+	if err != nil {
+		panic(err)
+	}
+	defer os.RemoveAll(logDir)
+
+	c := NewController(*topology, time.Millisecond)
+	c.SetLogDir(logDir)
+	configs := make([]NodeConfig, 0)
+	for id := NodeID(0); id < 4; id++ {
+		configs = append(configs, NodeConfig{ID: id, Message: NodeMessage{Sent: true}})
+	}
+	c.Initialize(configs)
+	// Lock-step ticks make the run, and so the output, reproducible.
+	c.SetSynchronous(true)
+	c.Start(40)
+
+	n, _ := c.node(0)
+	state := n.Snapshot()
+	fmt.Println("mprs:", state.MPRs)
+	for _, route := range state.RoutingTable {
+		fmt.Printf("route to %s: next hop %s, %d hops\n", route.Destination, route.NextHop, route.Distance)
+	}
+	// Output:
+	// mprs: [1]
+	// route to 1: next hop 1, 1 hops
+	// route to 2: next hop 2, 1 hops
+	// route to 3: next hop 1, 2 hops
+}
@@ -0,0 +1,19 @@
+//go:build !windows && (!unix || solaris || aix)
+
+package main
+
+import (
+	"os"
+)
+
+// lockFile is a no-op on platforms whose standard library has no file locking, such as js/wasm, plan9, solaris, and
+// aix. Records are still written with a single append each, but concurrent simulations sharing a file are not
+// serialized.
+func lockFile(_ *os.File) error {
+	return nil
+}
+
+// unlockFile is a no-op where lockFile is.
+func unlockFile(_ *os.File) error {
+	return nil
+}
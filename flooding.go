@@ -0,0 +1,126 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+)
+
+// FloodingRelays determines the nodes which should relay a flood, such as a TCMessage, originating at the given node.
+// A node relays the flood once it hears it from a neighbor which selected it as an mpr, so the relays are computed
+// from every node's current mpr selectors. Sorted by NodeID.
+func (c *Controller) FloodingRelays(origin NodeID) []NodeID {
+	selectors := make(map[NodeID][]NodeID)
+	for _, n := range c.nodes {
+		selectors[n.id] = n.Snapshot().MPRSelectors
+	}
+
+	relays := make(map[NodeID]struct{})
+	transmitters := []NodeID{origin}
+	transmitted := map[NodeID]struct{}{origin: {}}
+	for len(transmitters) > 0 {
+		from := transmitters[0]
+		transmitters = transmitters[1:]
+		for _, id := range sortedNodeIDs(selectors) {
+			if _, in := transmitted[id]; in {
+				continue
+			}
+			for _, selector := range selectors[id] {
+				if selector == from {
+					relays[id] = struct{}{}
+					transmitted[id] = struct{}{}
+					transmitters = append(transmitters, id)
+					break
+				}
+			}
+		}
+	}
+	return sortedNodeIDs(relays)
+}
+
+// UnrelayedTCOrigins flags the online nodes whose TCMessage(s) would not be relayed by any neighbor, given every
+// node's current mpr selectors, although other nodes are more than one hop away over the current topology. Such a
+// node's link-state information dies at the first hop, so nodes further away can not learn routes through it, and may
+// not learn a route to it at all. This happens when a node selects no mpr, or no neighbor has yet learned of its
+// selection from its HelloMessage(s). Sorted by NodeID.
+func (c *Controller) UnrelayedTCOrigins() []NodeID {
+	tick := c.CurrentTick()
+	ids := make([]NodeID, 0, len(c.nodes))
+	for _, n := range c.nodes {
+		if c.isOnline(n.id, tick) {
+			ids = append(ids, n.id)
+		}
+	}
+	sortNodeIDs(ids)
+	distances := c.hopDistances(ids, tick)
+
+	unrelayed := make([]NodeID, 0)
+	for _, origin := range ids {
+		beyondNeighbors := false
+		for _, dst := range ids {
+			if d, in := distances[dst][origin]; in && d > 1 {
+				beyondNeighbors = true
+				break
+			}
+		}
+		if beyondNeighbors && len(c.FloodingRelays(origin)) == 0 {
+			unrelayed = append(unrelayed, origin)
+		}
+	}
+	return unrelayed
+}
+
+// ActualRelays determines the nodes which forwarded at least one TCMessage originating at the given node during the
+// simulation. Sorted by NodeID.
+func (c *Controller) ActualRelays(origin NodeID) []NodeID {
+	relays := make([]NodeID, 0)
+	for _, n := range c.nodes {
+		if len(n.forwardCounts(origin)) > 0 {
+			relays = append(relays, n.id)
+		}
+	}
+	sortNodeIDs(relays)
+	return relays
+}
+
+// DiffFloodingRelays describes the differences between the nodes which should relay a flood from the given node and
+// those which actually relayed its TCMessage(s), followed by each TCMessage a node relayed more than once. Any
+// difference indicates a forwarding bug, or a change in mpr selection during the simulation, while a repeated relay
+// indicates a failure of duplicate suppression.
+func (c *Controller) DiffFloodingRelays(origin NodeID) []string {
+	diffs := diffNodeIDSets("relay", c.FloodingRelays(origin), c.ActualRelays(origin))
+	ids := make([]NodeID, 0, len(c.nodes))
+	counts := make(map[NodeID]map[int]int)
+	for _, n := range c.nodes {
+		ids = append(ids, n.id)
+		counts[n.id] = n.forwardCounts(origin)
+	}
+	sortNodeIDs(ids)
+	for _, id := range ids {
+		seqs := make([]int, 0, len(counts[id]))
+		for seq := range counts[id] {
+			seqs = append(seqs, seq)
+		}
+		sort.Ints(seqs)
+		for _, seq := range seqs {
+			if forwarded := counts[id][seq]; forwarded > 1 {
+				diffs = append(diffs, fmt.Sprintf("relay %s: forwarded TC %d %d times", id, seq, forwarded))
+			}
+		}
+	}
+	return diffs
+}
+
+// forwardCounts determines the number of times the Node forwarded each TCMessage originating at the given node, by
+// message sequence number.
+func (n *Node) forwardCounts(origin NodeID) map[int]int {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	counts := make(map[int]int)
+	for k, forwarded := range n.tcForwardedFor {
+		if k.source == origin {
+			counts[k.seq] = forwarded
+		}
+	}
+	return counts
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestController_FloodingRelays(t *testing.T) {
+	// A chain: 0 - 1 - 2 - 3 - 4
+	network := newLockstepNetwork(map[NodeID][]NodeID{
+		0: {1},
+		1: {0, 2},
+		2: {1, 3},
+		3: {2, 4},
+		4: {3},
+	})
+	network.run(60)
+
+	c := NewController(NetworkTypology{}, time.Millisecond)
+	for _, id := range sortedNodeIDs(network.nodes) {
+		c.nodes = append(c.nodes, network.nodes[id])
+	}
+
+	tests := []struct {
+		origin NodeID
+		want   []NodeID
+	}{
+		{origin: 1, want: []NodeID{2, 3}},
+		{origin: 2, want: []NodeID{1, 3}},
+		// Leaf nodes never relay, but their floods are relayed across the chain.
+		{origin: 4, want: []NodeID{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.origin.String(), func(t *testing.T) {
+			if got := c.FloodingRelays(tt.origin); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FloodingRelays() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	// Leaf nodes are selected as an mpr by no one, so only the others originate TCs.
+	for _, origin := range []NodeID{1, 2, 3} {
+		if got := c.DiffFloodingRelays(origin); len(got) != 0 {
+			t.Errorf("DiffFloodingRelays(%d) = %v, want none", origin, got)
+		}
+	}
+
+	// A node forwarding a flood it should not is reported.
+	network.nodes[0].tcForwardedFor[tcMessageID{source: 1, seq: 0}]++
+	if got, want := c.DiffFloodingRelays(1), []string{"relay 0: added"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffFloodingRelays() = %v, want %v", got, want)
+	}
+
+	// So is a relay forwarding the same TC more than once.
+	network.nodes[2].tcForwardedFor[tcMessageID{source: 3, seq: 0}]++
+	if got, want := c.DiffFloodingRelays(3), []string{"relay 2: forwarded TC 0 2 times"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffFloodingRelays() = %v, want %v", got, want)
+	}
+}
+
+func TestController_UnrelayedTCOrigins(t *testing.T) {
+	tests := []struct {
+		name  string
+		ticks int
+		// stripMPRs makes node 0's HelloMessage(s) advertise its mprs as plain bidirectional neighbors.
+		stripMPRs bool
+		want      []NodeID
+	}{
+		// Links are symmetric after the second HELLO round, but no mpr selection has been advertised yet.
+		{name: "before mpr selection", ticks: 8, want: []NodeID{0, 1, 2, 3}},
+		{name: "converged", ticks: 40, want: []NodeID{}},
+		{name: "mpr selection never advertised", ticks: 40, stripMPRs: true, want: []NodeID{0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// A chain: 0 - 1 - 2 - 3
+			topology, err := NewNetworkTypology(strings.NewReader(
+				"0 UP 0 1\n0 UP 1 0\n0 UP 1 2\n0 UP 2 1\n0 UP 2 3\n0 UP 3 2\n"))
+			if err != nil {
+				t.Fatalf("NewNetworkTypology() error = %v", err)
+			}
+			c := NewController(*topology, time.Hour)
+			c.SetLogDir(t.TempDir())
+			configs := make([]NodeConfig, 0)
+			for id := NodeID(0); id < 4; id++ {
+				configs = append(configs, NodeConfig{ID: id, Message: NodeMessage{Sent: true}})
+			}
+			c.Initialize(configs)
+			if tt.stripMPRs {
+				c.AddInterceptor(func(from, _ NodeID, msg interface{}, _ int) (interface{}, bool) {
+					hello, ok := msg.(*HelloMessage)
+					if !ok || from != 0 {
+						return msg, true
+					}
+					stripped := *hello
+					stripped.Bidirectional = append(append([]NodeID(nil), hello.Bidirectional...), hello.MultipointRelay...)
+					stripped.MultipointRelay = nil
+					return &stripped, true
+				})
+			}
+			c.SetSynchronous(true)
+			c.Start(tt.ticks)
+
+			if got := c.UnrelayedTCOrigins(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UnrelayedTCOrigins() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"sort"
+	"strings"
+)
+
+// GenerateScenario generates a random, valid scenario for stress testing, returning the contents of a topology file
+// and a node configuration file. The same seed always generates the same scenario.
+//
+// The nodes are connected by a random tree at tick 0, so that the network starts connected, followed by random link
+// up and down events throughout the run, most of them symmetric and some with link delays. Each node sends a single
+// message to another node, and some nodes are only online for part of the run. Nodes are identified by single digits
+// when there are at most 10, and by dotted-quad addresses otherwise.
+func GenerateScenario(seed int64, nodes int, ticks int) (topology string, configs string) {
+	r := rand.New(rand.NewSource(seed))
+	if ticks < 1 {
+		ticks = 1
+	}
+	ids := generatedNodeIDs(nodes)
+
+	// up tracks the state of each directed link, so that every event changes a link's state.
+	up := make(map[[2]int]bool)
+	var t strings.Builder
+	link := func(tick, from, to int, state bool, delay int) {
+		up[[2]int{from, to}] = state
+		writeLinkEvent(&t, tick, ids[from], ids[to], state, delay)
+	}
+	for _, edge := range generateTree(r, nodes) {
+		link(0, edge[0], edge[1], true, 0)
+		link(0, edge[1], edge[0], true, 0)
+	}
+
+	if nodes > 1 && ticks > 1 {
+		times := make([]int, 2*nodes)
+		for i := range times {
+			times[i] = 1 + r.Intn(ticks-1)
+		}
+		sort.Ints(times)
+		for _, tick := range times {
+			from := r.Intn(nodes)
+			to := (from + 1 + r.Intn(nodes-1)) % nodes
+			state := !up[[2]int{from, to}]
+			delay := 0
+			if state && r.Intn(5) == 0 {
+				delay = 1 + r.Intn(3)
+			}
+			link(tick, from, to, state, delay)
+			// Most links change state in both directions at once.
+			if r.Intn(5) != 0 && up[[2]int{to, from}] != state {
+				link(tick, to, from, state, delay)
+			}
+		}
+	}
+
+	return t.String(), generateNodeConfigs(r, ids, ticks)
+}
+
+// FlappingProfile describes how the links of a scenario generated by GenerateFlappingScenario flap.
+type FlappingProfile struct {
+	// MeanUp is the mean number of ticks a link stays up before going down. Less than 1 is treated as 1.
+	MeanUp float64
+
+	// MeanDown is the mean number of ticks a link stays down before coming back up. Less than 1 is treated as 1.
+	MeanDown float64
+}
+
+// GenerateFlappingScenario generates a random, valid scenario in which links flap, for testing how the network copes
+// with instability, returning the contents of a topology file and a node configuration file. The same seed always
+// generates the same scenario.
+//
+// The nodes are connected by a random tree, along with as many extra random links again, all of which are up at tick
+// 0. Each link then alternates between down and up in both directions at once, staying up and down for exponentially
+// distributed durations with the profile's means, so most periods are short and a few are long. The node
+// configurations are generated as by GenerateScenario.
+func GenerateFlappingScenario(seed int64, nodes int, ticks int, profile FlappingProfile) (topology string, configs string) {
+	r := rand.New(rand.NewSource(seed))
+	if ticks < 1 {
+		ticks = 1
+	}
+	ids := generatedNodeIDs(nodes)
+
+	edges := generateTree(r, nodes)
+	linked := make(map[[2]int]bool)
+	for _, edge := range edges {
+		linked[edge] = true
+	}
+	// Extra links give the network alternate paths to converge on while others are down.
+	for i := 0; nodes > 2 && i < nodes-1; i++ {
+		a, b := r.Intn(nodes), r.Intn(nodes)
+		if a < b {
+			a, b = b, a
+		}
+		if a != b && !linked[[2]int{a, b}] {
+			linked[[2]int{a, b}] = true
+			edges = append(edges, [2]int{a, b})
+		}
+	}
+
+	// duration draws an exponentially distributed period, of at least a tick, with the mean.
+	duration := func(mean float64) int {
+		if mean < 1 {
+			mean = 1
+		}
+		return int(math.Ceil(r.ExpFloat64() * mean))
+	}
+	type event struct {
+		tick int
+		from int
+		to   int
+		up   bool
+	}
+	events := make([]event, 0)
+	for _, edge := range edges {
+		for tick, state := 0, true; tick < ticks; state = !state {
+			events = append(events, event{tick: tick, from: edge[0], to: edge[1], up: state})
+			events = append(events, event{tick: tick, from: edge[1], to: edge[0], up: state})
+			if state {
+				tick += duration(profile.MeanUp)
+			} else {
+				tick += duration(profile.MeanDown)
+			}
+		}
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].tick < events[j].tick })
+
+	var t strings.Builder
+	for _, e := range events {
+		writeLinkEvent(&t, e.tick, ids[e.from], ids[e.to], e.up, 0)
+	}
+	return t.String(), generateNodeConfigs(r, ids, ticks)
+}
+
+// generatedNodeIDs labels the nodes of a generated scenario by single digits when there are at most 10, and by
+// dotted-quad addresses otherwise.
+func generatedNodeIDs(nodes int) []string {
+	ids := make([]string, nodes)
+	for i := range ids {
+		if nodes <= 10 {
+			ids[i] = fmt.Sprint(i)
+			continue
+		}
+		ids[i] = fmt.Sprintf("10.0.%d.%d", (i+1)>>8, (i+1)&0xff)
+	}
+	return ids
+}
+
+// generateTree connects the nodes by a random tree, returning its edges, each from a node to a lower node.
+func generateTree(r *rand.Rand, nodes int) [][2]int {
+	edges := make([][2]int, 0, nodes)
+	for i := 1; i < nodes; i++ {
+		edges = append(edges, [2]int{i, r.Intn(i)})
+	}
+	return edges
+}
+
+// writeLinkEvent writes a line of a topology file.
+func writeLinkEvent(t *strings.Builder, tick int, from, to string, state bool, delay int) {
+	var status LinkStatus = DOWN
+	if state {
+		status = UP
+	}
+	fmt.Fprintf(t, "%d %s %s %s", tick, status, from, to)
+	if delay > 0 {
+		fmt.Fprintf(t, " %d", delay)
+	}
+	t.WriteString("\n")
+}
+
+// generateNodeConfigs generates a node configuration file in which each node sends a single message to another node,
+// and some nodes are only online for part of the run.
+func generateNodeConfigs(r *rand.Rand, ids []string, ticks int) string {
+	nodes := len(ids)
+	var c strings.Builder
+	for i := 0; i < nodes; i++ {
+		dst := i
+		if nodes > 1 {
+			dst = (i + 1 + r.Intn(nodes-1)) % nodes
+		}
+		fmt.Fprintf(&c, "%s %s \"(%s -> %s)\" %d", ids[i], ids[dst], ids[i], ids[dst], r.Intn(ticks))
+		if r.Intn(5) == 0 {
+			start := r.Intn(ticks/2 + 1)
+			stop := 0
+			if r.Intn(2) == 0 {
+				stop = start + 1 + r.Intn(ticks-start)
+			}
+			fmt.Fprintf(&c, " %d %d", start, stop)
+		}
+		c.WriteString("\n")
+	}
+	return c.String()
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateScenario(t *testing.T) {
+	tests := []struct {
+		name  string
+		seed  int64
+		nodes int
+		ticks int
+	}{
+		{name: "single node", seed: 1, nodes: 1, ticks: 20},
+		{name: "small", seed: 2, nodes: 4, ticks: 60},
+		{name: "digit labels", seed: 3, nodes: 10, ticks: 80},
+		{name: "dotted-quad labels", seed: 4, nodes: 16, ticks: 80},
+		{name: "short run", seed: 5, nodes: 6, ticks: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topologyText, configsText := GenerateScenario(tt.seed, tt.nodes, tt.ticks)
+			if again, _ := GenerateScenario(tt.seed, tt.nodes, tt.ticks); again != topologyText {
+				t.Fatalf("GenerateScenario() is not deterministic for seed %d", tt.seed)
+			}
+
+			topology, err := NewNetworkTypology(strings.NewReader(topologyText))
+			if err != nil {
+				t.Fatalf("NewNetworkTypology() error = %v\n%s", err, topologyText)
+			}
+			configs, err := ReadNodeConfiguration(strings.NewReader(configsText))
+			if err != nil {
+				t.Fatalf("ReadNodeConfiguration() error = %v\n%s", err, configsText)
+			}
+			if len(configs) != tt.nodes {
+				t.Fatalf("ReadNodeConfiguration() returned %d configs, want %d", len(configs), tt.nodes)
+			}
+			// A lone node can only address its message to itself.
+			for _, warning := range ValidateScenario(configs) {
+				if !strings.Contains(warning, "addressed to itself") || tt.nodes > 1 {
+					t.Errorf("ValidateScenario() warning: %s", warning)
+				}
+			}
+
+			logDir, err := os.MkdirTemp("", "olsrsim")
+			if err != nil {
+				t.Fatalf("MkdirTemp() error = %v", err)
+			}
+			defer os.RemoveAll(logDir)
+
+			c := NewController(*topology, time.Hour)
+			c.SetLogDir(logDir)
+			c.Initialize(configs)
+			for _, n := range c.nodes {
+				n.SetStrict(true)
+			}
+			c.SetSynchronous(true)
+			if got := c.Start(tt.ticks); got != tt.ticks {
+				t.Errorf("Start() = %d, want %d", got, tt.ticks)
+			}
+		})
+	}
+}
+
+func TestGenerateFlappingScenario(t *testing.T) {
+	tests := []struct {
+		name    string
+		seed    int64
+		nodes   int
+		ticks   int
+		profile FlappingProfile
+	}{
+		{name: "single node", seed: 1, nodes: 1, ticks: 20, profile: FlappingProfile{MeanUp: 10, MeanDown: 3}},
+		{name: "stable", seed: 2, nodes: 6, ticks: 80, profile: FlappingProfile{MeanUp: 40, MeanDown: 2}},
+		{name: "unstable", seed: 3, nodes: 8, ticks: 80, profile: FlappingProfile{MeanUp: 5, MeanDown: 5}},
+		{name: "dotted-quad labels", seed: 4, nodes: 12, ticks: 60, profile: FlappingProfile{MeanUp: 15, MeanDown: 4}},
+		{name: "means below a tick", seed: 5, nodes: 4, ticks: 30, profile: FlappingProfile{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topologyText, configsText := GenerateFlappingScenario(tt.seed, tt.nodes, tt.ticks, tt.profile)
+			if again, _ := GenerateFlappingScenario(tt.seed, tt.nodes, tt.ticks, tt.profile); again != topologyText {
+				t.Fatalf("GenerateFlappingScenario() is not deterministic for seed %d", tt.seed)
+			}
+
+			// Events are sorted by tick, and each link alternates between up and down, starting up at tick 0.
+			last := -1
+			up := make(map[string]bool)
+			for _, line := range strings.Split(strings.TrimSpace(topologyText), "\n") {
+				if line == "" {
+					continue
+				}
+				fields := strings.Fields(line)
+				tick, err := strconv.Atoi(fields[0])
+				if err != nil {
+					t.Fatalf("bad tick in %q", line)
+				}
+				if tick < last {
+					t.Fatalf("event %q is out of order", line)
+				}
+				last = tick
+				link := fields[2] + " " + fields[3]
+				state, seen := up[link]
+				if !seen && (tick != 0 || fields[1] != "UP") {
+					t.Fatalf("link %s first appears as %q, want it up at tick 0", link, line)
+				}
+				if seen && state == (fields[1] == "UP") {
+					t.Fatalf("event %q does not change the link's state", line)
+				}
+				up[link] = fields[1] == "UP"
+			}
+
+			topology, err := NewNetworkTypology(strings.NewReader(topologyText))
+			if err != nil {
+				t.Fatalf("NewNetworkTypology() error = %v\n%s", err, topologyText)
+			}
+			configs, err := ReadNodeConfiguration(strings.NewReader(configsText))
+			if err != nil {
+				t.Fatalf("ReadNodeConfiguration() error = %v\n%s", err, configsText)
+			}
+
+			c := NewController(*topology, time.Hour)
+			c.SetLogDir(t.TempDir())
+			c.Initialize(configs)
+			for _, n := range c.nodes {
+				n.SetStrict(true)
+			}
+			c.SetSynchronous(true)
+			c.RecordConvergence(true)
+			if got := c.Start(tt.ticks); got != tt.ticks {
+				t.Errorf("Start() = %d, want %d", got, tt.ticks)
+			}
+			if got := c.Stats().NonConvergedTicks; got < 0 || got > tt.ticks {
+				t.Errorf("Stats().NonConvergedTicks = %d, want within [0, %d]", got, tt.ticks)
+			}
+		})
+	}
+}
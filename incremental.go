@@ -0,0 +1,122 @@
+package main
+
+// neighborChanged determines whether a HelloMessage from the neighbor changed anything its routes are calculated from:
+// whether the link to it is symmetric, or which two-hop neighbors are reached through it. Table caps may evict the
+// entries of other neighbors, so the routes are always recalculated with them.
+func (n *Node) neighborChanged(neighbor NodeID, symmetricBefore bool, twoHopsBefore map[NodeID]bool) bool {
+	if n.tableCaps.OneHopNeighbors > 0 || n.tableCaps.TwoHopNeighbors > 0 {
+		return true
+	}
+	if n.isSymmetricNeighbor(neighbor) != symmetricBefore {
+		return true
+	}
+	twoHops := n.twoHopNeighbors[neighbor]
+	if len(twoHops) != len(twoHopsBefore) {
+		return true
+	}
+	for dst := range twoHops {
+		if !twoHopsBefore[dst] {
+			return true
+		}
+	}
+	return false
+}
+
+// noteTopologyChange records how a TCMessage changed the entries of its originator, which previously advertised the
+// destinations in before. Entries which were only refreshed leave the routes as they are, and added entries are kept
+// for extendRoutingTable. Removed entries, or entries of other originators removed by compaction or the table cap,
+// mark the routes to be recalculated.
+func (n *Node) noteTopologyChange(originator NodeID, before map[NodeID]bool) {
+	if n.compactTopology || n.tableCaps.TopologyEntries > 0 {
+		n.routesChanged = true
+		return
+	}
+	entries := n.topologyTable[originator]
+	for dst := range before {
+		if _, in := entries[dst]; !in {
+			n.routesChanged = true
+			return
+		}
+	}
+	for _, dst := range sortedNodeIDs(entries) {
+		if !before[dst] {
+			n.addedTopology = append(n.addedTopology, topologyEntry{dst: dst, originator: originator})
+		}
+	}
+}
+
+// extendRoutingTable updates the routingTable with topology entries added since it was last calculated, rather than
+// recalculating it from scratch. Added entries can only shorten routes or add equal-cost next hops, so only the
+// destinations they reach, and the destinations routed through those, are revisited. The result is the same as that
+// of calculateRoutingTable. Returns false, leaving the routingTable untouched, if it must be recalculated instead.
+func (n *Node) extendRoutingTable(added []topologyEntry) bool {
+	// Interface routes follow the routes to main addresses, so they are left to a full recalculation.
+	if len(n.interfaceAssociations) > 0 {
+		return false
+	}
+	routes := make(map[NodeID]routingEntry, len(n.routingTable))
+	for dst, entry := range n.routingTable {
+		routes[dst] = entry
+	}
+
+	updated := make([]NodeID, 0)
+	relax := func(originator, dst NodeID) {
+		via, in := routes[originator]
+		// As in calculateRoutingTable, only originators beyond the one-hop neighbors extend routes, up to 256 hops.
+		if !in || via.distance < 2 || via.distance >= 256 || dst == n.id {
+			return
+		}
+		distance := via.distance + 1
+		entry, in := routes[dst]
+		switch {
+		case !in || distance < entry.distance:
+			entry = routingEntry{dst: dst, nextHops: append([]NodeID(nil), via.nextHops...), distance: distance}
+		case distance == entry.distance:
+			merged := mergeNodeIDs(entry.nextHops, via.nextHops)
+			if len(merged) == len(entry.nextHops) {
+				return
+			}
+			entry.nextHops = merged
+		default:
+			return
+		}
+		entry.nextHop = entry.nextHops[0]
+		routes[dst] = entry
+		updated = append(updated, dst)
+	}
+
+	for _, entry := range added {
+		relax(entry.originator, entry.dst)
+	}
+	// Every updated destination may in turn improve the routes to the destinations it advertises.
+	for len(updated) > 0 {
+		originator := updated[0]
+		updated = updated[1:]
+		for dst := range n.topologyTable[originator] {
+			relax(originator, dst)
+		}
+	}
+	n.routingTable = routes
+	return true
+}
+
+// mergeNodeIDs returns the sorted union of two sorted lists of NodeIDs, without modifying either.
+func mergeNodeIDs(a, b []NodeID) []NodeID {
+	merged := make([]NodeID, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case j == len(b) || (i < len(a) && a[i] < b[j]):
+			merged = append(merged, a[i])
+			i++
+		case i == len(a) || b[j] < a[i]:
+			merged = append(merged, b[j])
+			j++
+		default:
+			merged = append(merged, a[i])
+			i++
+			j++
+		}
+	}
+	return merged
+}
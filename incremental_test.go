@@ -0,0 +1,190 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+func Test_mergeNodeIDs(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b []NodeID
+		want []NodeID
+	}{
+		{name: "empty", a: nil, b: nil, want: []NodeID{}},
+		{name: "one empty", a: []NodeID{1, 3}, b: nil, want: []NodeID{1, 3}},
+		{name: "disjoint", a: []NodeID{1, 5}, b: []NodeID{2, 3, 7}, want: []NodeID{1, 2, 3, 5, 7}},
+		{name: "overlapping", a: []NodeID{1, 2, 4}, b: []NodeID{2, 4, 6}, want: []NodeID{1, 2, 4, 6}},
+		{name: "equal", a: []NodeID{3, 4}, b: []NodeID{3, 4}, want: []NodeID{3, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeNodeIDs(tt.a, tt.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeNodeIDs() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNode_noteTopologyChange(t *testing.T) {
+	tests := []struct {
+		name            string
+		compactTopology bool
+		mprSet          []NodeID
+		wantChanged     bool
+		wantAdded       []topologyEntry
+	}{
+		{name: "refreshed", mprSet: []NodeID{3, 4}},
+		{name: "added", mprSet: []NodeID{3, 4, 5, 6}, wantAdded: []topologyEntry{{dst: 5, originator: 2}, {dst: 6, originator: 2}}},
+		{name: "removed", mprSet: []NodeID{3}, wantChanged: true},
+		{name: "replaced", mprSet: []NodeID{3, 5}, wantChanged: true},
+		{name: "compact", compactTopology: true, mprSet: []NodeID{3, 4}, wantChanged: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := newTestNode(0, &recordingTransmitter{})
+			n.SetCompactTopology(tt.compactTopology)
+			n.oneHopNeighbors[1] = oneHopNeighborEntry{neighborID: 1, state: bidirectional, holdUntil: 15}
+			n.handleTC(&TCMessage{Source: 2, FromNeighbor: 1, Sequence: 1, MultipointRelaySet: []NodeID{3, 4}})
+			n.refreshRoutes()
+
+			n.handleTC(&TCMessage{Source: 2, FromNeighbor: 1, Sequence: 2, MultipointRelaySet: tt.mprSet, MessageSequence: 1})
+			if n.routesChanged != tt.wantChanged {
+				t.Errorf("routesChanged = %v, want %v", n.routesChanged, tt.wantChanged)
+			}
+			if !tt.wantChanged && !reflect.DeepEqual(n.addedTopology, tt.wantAdded) {
+				t.Errorf("addedTopology = %v, want %v", n.addedTopology, tt.wantAdded)
+			}
+		})
+	}
+}
+
+func TestNode_handleHello_routesChanged(t *testing.T) {
+	hello := func(seq int, bidirectional ...NodeID) *HelloMessage {
+		return &HelloMessage{Source: 1, Sequence: seq, Bidirectional: bidirectional}
+	}
+	tests := []struct {
+		name string
+		next *HelloMessage
+		want bool
+	}{
+		{name: "unchanged", next: hello(3, 0, 2), want: false},
+		{name: "new two-hop neighbor", next: hello(3, 0, 2, 3), want: true},
+		{name: "lost two-hop neighbor", next: hello(3, 0), want: true},
+		{name: "no longer symmetric", next: hello(3, 2), want: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := newTestNode(0, &recordingTransmitter{})
+			n.handleHello(hello(1))
+			n.handleHello(hello(2, 0, 2))
+			n.refreshRoutes()
+
+			n.handleHello(tt.next)
+			if n.routesChanged != tt.want {
+				t.Errorf("routesChanged = %v, want %v", n.routesChanged, tt.want)
+			}
+		})
+	}
+}
+
+// TestNode_extendRoutingTable checks that the routes updated incrementally, through random sequences of TCs which
+// mostly extend the topology, always match those recalculated from scratch.
+func TestNode_extendRoutingTable(t *testing.T) {
+	const nodes = 40
+	for seed := int64(1); seed <= 5; seed++ {
+		t.Run(fmt.Sprintf("seed=%d", seed), func(t *testing.T) {
+			r := rand.New(rand.NewSource(seed))
+			n := newTestNode(0, &recordingTransmitter{})
+			for id := NodeID(1); id <= 3; id++ {
+				n.oneHopNeighbors[id] = oneHopNeighborEntry{neighborID: id, state: bidirectional, holdUntil: 1000}
+				n.twoHopNeighbors[id] = map[NodeID]NodeID{id + 3: id + 3}
+			}
+			n.topologyHoldTime = 1000
+
+			advertised := make(map[NodeID][]NodeID)
+			sequences := make(map[NodeID]int)
+			extended := 0
+			for step := 0; step < 200; step++ {
+				src := NodeID(4 + r.Intn(nodes-4))
+				if r.Intn(5) == 0 {
+					// Occasionally replace the advertised set, which requires a full recalculation.
+					advertised[src] = nil
+				}
+				advertised[src] = append(advertised[src], NodeID(1+r.Intn(nodes-1)))
+				sequences[src]++
+				n.handleTC(&TCMessage{
+					Source:             src,
+					FromNeighbor:       NodeID(1 + r.Intn(3)),
+					Sequence:           sequences[src],
+					MultipointRelaySet: advertised[src],
+				})
+				if !n.routesChanged && len(n.addedTopology) > 0 {
+					extended++
+				}
+				n.refreshRoutes()
+
+				got := n.routingTable
+				n.calculateRoutingTable()
+				if !reflect.DeepEqual(got, n.routingTable) {
+					t.Fatalf("step %d: incremental routing table = %v, want %v", step, got, n.routingTable)
+				}
+			}
+			if extended == 0 {
+				t.Errorf("no routes were updated incrementally")
+			}
+		})
+	}
+}
+
+// BenchmarkNode_refreshRoutes measures updating the routes of a node in a corner of a 100-node grid mesh, after a
+// single link is added far away, incrementally and by recalculating the routing table from scratch.
+func BenchmarkNode_refreshRoutes(b *testing.B) {
+	const side = 10
+	n := newTestNode(0, &recordingTransmitter{})
+	n.topologyHoldTime = 1000
+	neighbors := func(id NodeID) []NodeID {
+		x, y := int(id)%side, int(id)/side
+		ids := make([]NodeID, 0)
+		for _, d := range [][2]int{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+			if nx, ny := x+d[0], y+d[1]; nx >= 0 && nx < side && ny >= 0 && ny < side {
+				ids = append(ids, NodeID(ny*side+nx))
+			}
+		}
+		return ids
+	}
+	for _, id := range neighbors(0) {
+		n.oneHopNeighbors[id] = oneHopNeighborEntry{neighborID: id, state: bidirectional, holdUntil: 1000}
+		n.twoHopNeighbors[id] = make(map[NodeID]NodeID)
+		for _, twoHop := range neighbors(id) {
+			if twoHop != 0 {
+				n.twoHopNeighbors[id][twoHop] = twoHop
+			}
+		}
+	}
+	for id := NodeID(1); id < side*side; id++ {
+		n.handleTC(&TCMessage{Source: id, FromNeighbor: 1, Sequence: 1, MultipointRelaySet: neighbors(id)})
+	}
+	n.calculateRoutingTable()
+	base := n.routingTable
+
+	// A diagonal link between two distant nodes, advertised by one of them.
+	n.handleTC(&TCMessage{Source: 55, FromNeighbor: 1, Sequence: 2, MultipointRelaySet: append(neighbors(55), 66)})
+	added := n.addedTopology
+
+	b.Run("incremental", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			n.routingTable = base
+			n.extendRoutingTable(added)
+		}
+	})
+	b.Run("full", func(b *testing.B) {
+		b.ReportAllocs()
+		for i := 0; i < b.N; i++ {
+			n.calculateRoutingTable()
+		}
+	})
+}
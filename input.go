@@ -0,0 +1,25 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"io"
+)
+
+// gzipMagic are the leading bytes of gzip-compressed data.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// newInputReader buffers a scenario input, transparently decompressing it if it starts with the gzip magic bytes.
+func newInputReader(in io.Reader) (*bufio.Reader, error) {
+	r := bufio.NewReader(in)
+	magic, err := r.Peek(len(gzipMagic))
+	if err != nil || magic[0] != gzipMagic[0] || magic[1] != gzipMagic[1] {
+		// Inputs too short to be compressed are read as plain text. Read errors resurface on the next read.
+		return r, nil
+	}
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	return bufio.NewReader(gz), nil
+}
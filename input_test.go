@@ -0,0 +1,87 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// gzipped compresses the contents of the reader.
+func gzipped(t *testing.T, in io.Reader) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	if _, err := io.Copy(gz, in); err != nil {
+		t.Fatal(err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return &buf
+}
+
+func TestNewNetworkTypology_gzip(t *testing.T) {
+	got, err := NewNetworkTypology(gzipped(t, goodTopologyReadyCloser()))
+	if err != nil {
+		t.Fatalf("NewNetworkTypology() error = %v", err)
+	}
+	if want := goodTopology(); !reflect.DeepEqual(got, want) {
+		t.Errorf("NewNetworkTypology() got = %v, want %v", got, want)
+	}
+}
+
+func TestReadNodeConfiguration_gzip(t *testing.T) {
+	plain, err := ReadNodeConfiguration(getTestData("./testdata/test_node_config.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		in      func() io.Reader
+		want    []NodeConfig
+		wantErr bool
+	}{
+		{
+			name: "gzipped",
+			in: func() io.Reader {
+				return gzipped(t, getTestData("./testdata/test_node_config.txt"))
+			},
+			want: plain,
+		},
+		{
+			name: "plain",
+			in: func() io.Reader {
+				return getTestData("./testdata/test_node_config.txt")
+			},
+			want: plain,
+		},
+		{
+			name: "corrupt gzip",
+			in: func() io.Reader {
+				return bytes.NewReader(append([]byte{0x1f, 0x8b}, "not gzip"...))
+			},
+			wantErr: true,
+		},
+		{
+			name: "shorter than the magic bytes",
+			in: func() io.Reader {
+				return strings.NewReader("\n")
+			},
+			want: []NodeConfig{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadNodeConfiguration(tt.in())
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ReadNodeConfiguration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadNodeConfiguration() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package main
+
+import (
+	"log"
+)
+
+// Interceptor observes a message about to cross the directed link at the tick it was sent, returning the message to
+// deliver in its place, or false to drop it. Broadcast messages are shared by every receiver, so an Interceptor which
+// modifies a message must return a modified copy rather than changing it in place.
+type Interceptor func(from, to NodeID, msg interface{}, tick int) (interface{}, bool)
+
+// AddInterceptor registers an Interceptor for every message the Controller delivers across a link, for fault
+// injection and protocol experiments. Interceptors run in the order they were added, each receiving the message
+// returned by the previous one, and only for links which are up. A dropped DataMessage is resolved as dropped. Must be
+// called before Start.
+func (c *Controller) AddInterceptor(interceptor Interceptor) {
+	c.interceptors = append(c.interceptors, interceptor)
+}
+
+// intercept runs the registered interceptors over a message about to cross the directed link, returning the message
+// to deliver, or false if it was dropped.
+func (c *Controller) intercept(from, to NodeID, msg interface{}, tick int) (interface{}, bool) {
+	for _, interceptor := range c.interceptors {
+		out, ok := interceptor(from, to, msg, tick)
+		if !ok {
+			log.Printf("controller: link %s -> %s intercepted, dropped:\t%s", from, to, msg)
+			return nil, false
+		}
+		msg = out
+	}
+	return msg, true
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestController_AddInterceptor(t *testing.T) {
+	tests := []struct {
+		name          string
+		interceptor   Interceptor
+		wantNeighbors map[NodeID]NeighborState
+		wantDelivered int
+		wantBytes     int
+	}{
+		{
+			name: "observe",
+			interceptor: func(_, _ NodeID, msg interface{}, _ int) (interface{}, bool) {
+				return msg, true
+			},
+			wantNeighbors: map[NodeID]NeighborState{0: bidirectional},
+			wantDelivered: 1,
+			wantBytes:     len("hi"),
+		},
+		{
+			// Node 1 never hears node 0, so the link never becomes bidirectional and node 0 has no route for its message.
+			name: "drop HELLOs from 0 to 1",
+			interceptor: func(from, to NodeID, msg interface{}, _ int) (interface{}, bool) {
+				_, hello := msg.(*HelloMessage)
+				return msg, !hello || from != 0 || to != 1
+			},
+			wantNeighbors: map[NodeID]NeighborState{},
+			wantDelivered: 0,
+		},
+		{
+			name: "mutate data",
+			interceptor: func(_, _ NodeID, msg interface{}, _ int) (interface{}, bool) {
+				if dm, ok := msg.(*DataMessage); ok {
+					tampered := *dm
+					tampered.Data = "tampered"
+					return &tampered, true
+				}
+				return msg, true
+			},
+			wantNeighbors: map[NodeID]NeighborState{0: bidirectional},
+			wantDelivered: 1,
+			wantBytes:     len("tampered"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topology, err := NewNetworkTypology(strings.NewReader("0 UP 0 1\n0 UP 1 0\n"))
+			if err != nil {
+				t.Fatalf("NewNetworkTypology() error = %v", err)
+			}
+			c := NewController(*topology, time.Hour)
+			c.SetLogDir(t.TempDir())
+			c.Initialize([]NodeConfig{
+				{ID: 0, Message: NodeMessage{Message: "hi", Delay: 15, Destination: 1}},
+				{ID: 1, Message: NodeMessage{Sent: true}},
+			})
+			c.SetSynchronous(true)
+			c.AddInterceptor(tt.interceptor)
+			// Interceptors only see links which are up.
+			c.AddInterceptor(func(from, to NodeID, msg interface{}, _ int) (interface{}, bool) {
+				if from == to || from > 1 || to > 1 {
+					t.Errorf("interceptor called for link %d -> %d", from, to)
+				}
+				return msg, true
+			})
+			c.Start(30)
+
+			n1, _ := c.node(1)
+			if got := n1.Snapshot().OneHopNeighbors; !reflect.DeepEqual(got, tt.wantNeighbors) {
+				t.Errorf("node 1 neighbors = %v, want %v", got, tt.wantNeighbors)
+			}
+			counters := n1.Counters()
+			if counters.DataDelivered != tt.wantDelivered || counters.DataDeliveredBytes != tt.wantBytes {
+				t.Errorf("node 1 delivered %d messages of %d bytes, want %d of %d", counters.DataDelivered,
+					counters.DataDeliveredBytes, tt.wantDelivered, tt.wantBytes)
+			}
+		})
+	}
+}
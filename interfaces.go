@@ -0,0 +1,80 @@
+package main
+
+import (
+	"fmt"
+)
+
+// interfacesOf lists every interface address of the node, starting with its main address.
+func (c *Controller) interfacesOf(id NodeID) []NodeID {
+	if interfaces, in := c.interfaces[id]; in {
+		return interfaces
+	}
+	return []NodeID{id}
+}
+
+// setInterfaces records the interface addresses of each configured node, checking that no address is used twice.
+func (c *Controller) setInterfaces(configs []NodeConfig) error {
+	owners := make(map[NodeID]NodeID)
+	for _, config := range configs {
+		owners[config.ID] = config.ID
+	}
+	interfaces := make(map[NodeID][]NodeID)
+	for _, config := range configs {
+		if len(config.Interfaces) == 0 {
+			continue
+		}
+		interfaces[config.ID] = []NodeID{config.ID}
+		for _, addr := range config.Interfaces {
+			if owner, in := owners[addr]; in {
+				return fmt.Errorf("node %s: interface address %s is already used by node %s", config.ID, addr, owner)
+			}
+			owners[addr] = config.ID
+			interfaces[config.ID] = append(interfaces[config.ID], addr)
+		}
+	}
+	c.interfaces = interfaces
+	return nil
+}
+
+// isInterface determines whether the address is an additional interface of a configured node, rather than a main
+// address.
+func (c *Controller) isInterface(addr NodeID) bool {
+	for _, interfaces := range c.interfaces {
+		for _, iface := range interfaces[1:] {
+			if iface == addr {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// linkUp determines whether a message can cross from one node to another at the queried time, over a link between
+// any interface of the first and any interface of the second.
+func (c *Controller) linkUp(q QueryMsg) bool {
+	_, up := c.interfaceLink(q)
+	return up
+}
+
+// linkDelay determines the number of ticks a message sent from one node to another at the queried time takes to
+// arrive, over the link with the shortest delay between their interfaces. Zero if no link is up.
+func (c *Controller) linkDelay(q QueryMsg) int {
+	delay, _ := c.interfaceLink(q)
+	return delay
+}
+
+// interfaceLink finds the shortest delay of the links which are up between the interfaces of two nodes.
+func (c *Controller) interfaceLink(q QueryMsg) (delay int, up bool) {
+	for _, from := range c.interfacesOf(q.FromNode) {
+		for _, to := range c.interfacesOf(q.ToNode) {
+			iq := QueryMsg{FromNode: from, ToNode: to, AtTime: q.AtTime}
+			if !c.topology.Query(iq) {
+				continue
+			}
+			if d := c.topology.Delay(iq); !up || d < delay {
+				delay, up = d, true
+			}
+		}
+	}
+	return delay, up
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestController_multipleInterfaces(t *testing.T) {
+	// Node 5 bridges two segments: its main address links to node 0, and its second interface, 6, links to node 2.
+	topology, err := NewNetworkTypology(strings.NewReader("0 UP 0 5\n0 UP 5 0\n0 UP 6 2\n0 UP 2 6\n"))
+	if err != nil {
+		t.Fatalf("NewNetworkTypology() error = %v", err)
+	}
+	c := NewController(*topology, time.Hour)
+	c.SetLogDir(t.TempDir())
+	c.SetStrictTopology(true)
+	err = c.Initialize([]NodeConfig{
+		{ID: 0, Message: NodeMessage{Message: "across", Delay: 30, Destination: 2}},
+		{ID: 2, Message: NodeMessage{Sent: true}},
+		{ID: 5, Message: NodeMessage{Sent: true}, Interfaces: []NodeID{6}},
+	})
+	if err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	c.SetSynchronous(true)
+	c.Start(40)
+
+	if _, ok := c.node(6); ok {
+		t.Errorf("interface 6 was created as a node")
+	}
+	n0, _ := c.node(0)
+	routes := n0.Snapshot().RoutingTable
+	// Node 5's MIDMessage(s) add a route to its interface 6, through its main address.
+	want := []RoutingEntry{{Destination: 2, NextHop: 5, Distance: 2}, {Destination: 5, NextHop: 5, Distance: 1}, {Destination: 6, NextHop: 5, Distance: 1}}
+	if !reflect.DeepEqual(routes, want) {
+		t.Errorf("node 0 routes = %v, want %v", routes, want)
+	}
+	n2, _ := c.node(2)
+	if got := n2.Counters().DataDelivered; got != 1 {
+		t.Errorf("node 2 DataDelivered = %d, want 1", got)
+	}
+	// Both segments see the bridge by its main address only.
+	if _, in := n2.Snapshot().OneHopNeighbors[5]; !in {
+		t.Errorf("node 2 neighbors = %v, want 5", n2.Snapshot().OneHopNeighbors)
+	}
+}
+
+func TestController_multipleInterfacesConflict(t *testing.T) {
+	c := NewController(*NewEmptyTopology(), time.Hour)
+	c.SetLogDir(t.TempDir())
+	err := c.Initialize([]NodeConfig{
+		{ID: 0, Message: NodeMessage{Sent: true}, Interfaces: []NodeID{1}},
+		{ID: 1, Message: NodeMessage{Sent: true}},
+	})
+	if err == nil {
+		t.Errorf("Initialize() with an interface address used as a main address error = nil, want an error")
+	}
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"math/rand"
+)
+
+// emissionJitter seeds the jitter of every node's periodic emissions. See Controller.SetEmissionJitter.
+type emissionJitter struct {
+	seed int64
+	max  Ticks
+}
+
+// SetEmissionJitter offsets each node's periodic emissions by up to max ticks, determined by the seed and the node's
+// NodeID, so that nodes started together do not all emit in the same ticks. See Node.SetEmissionJitter. Must be
+// called before Initialize.
+func (c *Controller) SetEmissionJitter(seed int64, max Ticks) {
+	c.emissionJitter = &emissionJitter{seed: seed, max: max}
+}
+
+// SetEmissionJitter delays the Node's first periodic HelloMessage, TCMessage, and MIDMessage by independent
+// pseudo-random numbers of ticks, from zero up to max, determined by the seed. The following emissions remain spaced by
+// their intervals, so the same seed always gives the same schedule; see EmissionSchedule. A negative max is treated as
+// zero, leaving the emissions unjittered. Must be called before the Node's first tick.
+func (n *Node) SetEmissionJitter(seed int64, max Ticks) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if max < 0 {
+		max = 0
+	}
+	r := rand.New(rand.NewSource(seed))
+	n.helloPhase = n.currentTick + r.Intn(int(max)+1)
+	n.tcPhase = n.currentTick + r.Intn(int(max)+1)
+	n.midPhase = n.currentTick + r.Intn(int(max)+1)
+}
+
+// EmissionSchedule computes the ticks, from the current tick up to but excluding horizon ticks later, at which the
+// Node's periodic HelloMessage(s) and TCMessage(s) are due under its current phases and intervals. A due TCMessage is
+// only sent if the Node has something to advertise, and triggered emissions are not included.
+func (n *Node) EmissionSchedule(horizon int) (hello []int, tc []int) {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	hello = emissionTicks(n.helloPhase, n.helloInterval, n.currentTick, horizon)
+	tc = emissionTicks(n.tcPhase, n.willingness.scaleTCInterval(n.tcInterval), n.currentTick, horizon)
+	return hello, tc
+}
+
+// emissionTicks lists the ticks, from the tick up to but excluding horizon ticks later, at which a periodic emission
+// sent at the phase tick and every interval after it is due.
+func emissionTicks(phase, interval, tick, horizon int) []int {
+	ticks := make([]int, 0)
+	for t := tick; t < tick+horizon; t++ {
+		if t >= phase && (t-phase)%interval == 0 {
+			ticks = append(ticks, t)
+		}
+	}
+	return ticks
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestNode_EmissionSchedule(t *testing.T) {
+	tests := []struct {
+		name      string
+		jitter    bool
+		seed      int64
+		max       Ticks
+		wantHello []int
+		wantTC    []int
+	}{
+		{name: "no jitter", wantHello: []int{0, 5, 10, 15, 20, 25}, wantTC: []int{0, 10, 20}},
+		{name: "no jitter allowed", jitter: true, seed: 7, max: 0, wantHello: []int{0, 5, 10, 15, 20, 25}, wantTC: []int{0, 10, 20}},
+		{name: "negative max", jitter: true, seed: 7, max: -3, wantHello: []int{0, 5, 10, 15, 20, 25}, wantTC: []int{0, 10, 20}},
+		{name: "jittered", jitter: true, seed: 7, max: 4, wantHello: []int{1, 6, 11, 16, 21, 26}, wantTC: []int{0, 10, 20}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &recordingTransmitter{}
+			n := newTestNode(0, out)
+			n.msSet[1] = 1
+			if tt.jitter {
+				n.SetEmissionJitter(tt.seed, tt.max)
+			}
+			hello, tc := n.EmissionSchedule(30)
+			if tt.wantHello != nil && !reflect.DeepEqual(hello, tt.wantHello) {
+				t.Errorf("EmissionSchedule() hello = %v, want %v", hello, tt.wantHello)
+			}
+			if tt.wantTC != nil && !reflect.DeepEqual(tc, tt.wantTC) {
+				t.Errorf("EmissionSchedule() tc = %v, want %v", tc, tt.wantTC)
+			}
+			within := int(tt.max)
+			if within < 0 {
+				within = 0
+			}
+			if len(hello) == 0 || hello[0] > within || len(tc) == 0 || tc[0] > within {
+				t.Fatalf("EmissionSchedule() = %v, %v, want the first emissions within %d ticks", hello, tc, within)
+			}
+
+			// The same seed gives the same schedule.
+			if tt.jitter {
+				other := newTestNode(0, &recordingTransmitter{})
+				other.SetEmissionJitter(tt.seed, tt.max)
+				if otherHello, otherTC := other.EmissionSchedule(30); !reflect.DeepEqual(otherHello, hello) || !reflect.DeepEqual(otherTC, tc) {
+					t.Errorf("EmissionSchedule() with the same seed = %v, %v, want %v, %v", otherHello, otherTC, hello, tc)
+				}
+			}
+
+			// The Node emits on the scheduled ticks.
+			var sentHello, sentTC []int
+			for tick := 0; tick < 30; tick++ {
+				out.sent = nil
+				n.tick(nil)
+				for _, msg := range out.sent {
+					switch msg.(type) {
+					case *HelloMessage:
+						sentHello = append(sentHello, tick)
+					case *TCMessage:
+						sentTC = append(sentTC, tick)
+					}
+				}
+			}
+			if !reflect.DeepEqual(sentHello, hello) {
+				t.Errorf("sent HELLOs at %v, want %v", sentHello, hello)
+			}
+			if !reflect.DeepEqual(sentTC, tc) {
+				t.Errorf("sent TCs at %v, want %v", sentTC, tc)
+			}
+		})
+	}
+}
+
+func TestController_SetEmissionJitter(t *testing.T) {
+	c := NewController(NetworkTypology{}, time.Millisecond)
+	c.SetLogDir(t.TempDir())
+	c.SetEmissionJitter(1, 4)
+	c.Initialize([]NodeConfig{
+		{ID: 0, Message: NodeMessage{Sent: true}},
+		{ID: 1, Message: NodeMessage{Sent: true}},
+		{ID: 2, Message: NodeMessage{Sent: true}},
+	})
+
+	first := make(map[int]bool)
+	for _, id := range []NodeID{0, 1, 2} {
+		n, _ := c.node(id)
+		hello, _ := n.EmissionSchedule(10)
+		first[hello[0]] = true
+	}
+	if len(first) == 1 {
+		t.Errorf("every node sends its first HELLO at the same tick")
+	}
+}
@@ -31,10 +31,16 @@ type LinkState struct {
 
 	// toNode is the destination Node ID.
 	toNode NodeID
+
+	// delay is the number of ticks a message takes to cross the link. Zero delivers messages immediately.
+	delay int
 }
 
 func (l *LinkState) String() string {
-	return fmt.Sprintf("%d %s %d %d", l.time, l.status, l.fromNode, l.toNode)
+	if l.delay > 0 {
+		return fmt.Sprintf("%d %s %s %s %d", l.time, l.status, l.fromNode, l.toNode, l.delay)
+	}
+	return fmt.Sprintf("%d %s %s %s", l.time, l.status, l.fromNode, l.toNode)
 }
 
 func parseLinkState(state string) (*LinkState, error) {
@@ -42,8 +48,8 @@ func parseLinkState(state string) (*LinkState, error) {
 
 	// Basic validation
 	splitState := strings.Split(state, " ")
-	if len(splitState) != 4 {
-		return nil, ErrParseLinkState{msg: "must be of the form: '{TIME} {UP | DOWN} {LABEL} {LABEL}'"}
+	if len(splitState) != 4 && len(splitState) != 5 {
+		return nil, ErrParseLinkState{msg: "must be of the form: '{TIME} {UP | DOWN} {LABEL} {LABEL} [{DELAY}]'"}
 	}
 
 	// Parse time
@@ -67,21 +73,33 @@ func parseLinkState(state string) (*LinkState, error) {
 	}
 
 	// Parse labels
-	lre := regexp.MustCompile(`^\d$`)
-	if !lre.Match([]byte(splitState[2])) {
-		return nil, ErrParseLinkState{msg: fmt.Sprintf("invalid ID: '%s': must be '^[0-9]$'", splitState[2])}
+	lre := regexp.MustCompile(`^(?:` + nodeIDPattern + `)$`)
+	labels := make([]NodeID, 0, 2)
+	for _, label := range splitState[2:4] {
+		if !lre.MatchString(label) {
+			return nil, ErrParseLinkState{msg: fmt.Sprintf("invalid ID: '%s': must be an integer or a dotted-quad address", label)}
+		}
+		id, err := parseNodeID(label)
+		if err != nil {
+			return nil, ErrParseLinkState{msg: fmt.Sprintf("invalid ID: '%s': %s", label, err)}
+		}
+		labels = append(labels, id)
 	}
-	if !lre.Match([]byte(splitState[3])) {
-		return nil, ErrParseLinkState{msg: fmt.Sprintf("invalid ID: '%s': must be '^[0-9]$'", splitState[3])}
+	ls.fromNode = labels[0]
+	ls.toNode = labels[1]
+
+	// Parse the optional delay
+	if len(splitState) == 5 {
+		delay, err := strconv.Atoi(splitState[4])
+		if err != nil {
+			return nil, ErrParseLinkState{msg: fmt.Sprintf("delay is not an integer: '%s'", splitState[4])}
+		}
+		if delay < 0 {
+			return nil, ErrParseLinkState{msg: fmt.Sprintf("delay must not be negative: '%s'", splitState[4])}
+		}
+		ls.delay = delay
 	}
 
-	// Already ensured the string represents an integer from the regex.
-	rawLabel, _ := strconv.Atoi(splitState[2])
-	ls.fromNode = NodeID(rawLabel)
-
-	rawLabel, _ = strconv.Atoi(splitState[3])
-	ls.toNode = NodeID(rawLabel)
-
 	return ls, nil
 }
 
@@ -95,6 +113,20 @@ type Link struct {
 	states []LinkState
 }
 
+// delayAt determines the delay, in ticks, of the link at the given time. Zero if the link is down.
+func (l *Link) delayAt(time int) int {
+	delay := 0
+	for _, state := range l.states {
+		if time >= state.time {
+			delay = state.delay
+		}
+	}
+	if !l.isUp(time) {
+		return 0
+	}
+	return delay
+}
+
 // isUp determines whether the link is available at the given time.
 func (l *Link) isUp(time int) bool {
 	up := false
@@ -220,6 +220,70 @@ func Test_parseLinkState(t *testing.T) {
 			want:    nil,
 			wantErr: true,
 		},
+		{
+			name: "dotted-quad IDs",
+			args: args{state: "10 UP 10.0.0.1 3"},
+			want: &LinkState{
+				time:     10,
+				status:   UP,
+				fromNode: 0x0A000001,
+				toNode:   3,
+			},
+			wantErr: false,
+		},
+		{
+			name: "multi-digit IDs",
+			args: args{state: "10 UP 12 345"},
+			want: &LinkState{
+				time:     10,
+				status:   UP,
+				fromNode: 12,
+				toNode:   345,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "ID out of range",
+			args:    args{state: "1 UP 4294967296 1"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "invalid dotted-quad ID",
+			args:    args{state: "1 UP 10.0.0.256 1"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name: "delay",
+			args: args{state: "10 UP 0 1 3"},
+			want: &LinkState{
+				time:     10,
+				status:   UP,
+				fromNode: 0,
+				toNode:   1,
+				delay:    3,
+			},
+			wantErr: false,
+		},
+		{
+			name:    "invalid delay",
+			args:    args{state: "10 UP 0 1 x"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "no negative delay",
+			args:    args{state: "10 UP 0 1 -1"},
+			want:    nil,
+			wantErr: true,
+		},
+		{
+			name:    "too many fields",
+			args:    args{state: "10 UP 0 1 3 4"},
+			want:    nil,
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
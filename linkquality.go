@@ -0,0 +1,69 @@
+package main
+
+// linkQualityWindow is the number of a neighbor's most recent HELLO sequence numbers over which the quality of the link
+// from it is measured.
+const linkQualityWindow = 10
+
+// helloHistory records which of a neighbor's recent HelloMessage(s) were received, to measure link quality.
+type helloHistory struct {
+	// first is the sequence number of the first HelloMessage received from the neighbor.
+	first int
+
+	// recent are the sequence numbers received within the window ending at the newest one, oldest first.
+	recent []int
+}
+
+// SetAdvertiseLinkQuality enables or disables advertising, in each HelloMessage, the Node's perceived quality of the
+// link from each neighbor: the fraction of the neighbor's last linkQualityWindow HelloMessage(s) which were received.
+// Disabled by default, in which case the legacy HELLO format without link quality is sent.
+func (n *Node) SetAdvertiseLinkQuality(enabled bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.advertiseLinkQuality = enabled
+}
+
+// recordHello records the receipt of a HelloMessage, with a sequence number newer than any before it, from the
+// neighbor.
+func (n *Node) recordHello(neighbor NodeID, seq int) {
+	history, in := n.helloHistories[neighbor]
+	if !in {
+		history = helloHistory{first: seq}
+	}
+	recent := history.recent[:0]
+	for _, s := range history.recent {
+		if s > seq-linkQualityWindow {
+			recent = append(recent, s)
+		}
+	}
+	history.recent = append(recent, seq)
+	n.helloHistories[neighbor] = history
+}
+
+// linkQuality is the fraction of the HelloMessage(s) the neighbor sent within the window which were received, as
+// determined by the gaps in their sequence numbers. Zero if none were received.
+func (n *Node) linkQuality(neighbor NodeID) float64 {
+	history, in := n.helloHistories[neighbor]
+	if !in || len(history.recent) == 0 {
+		return 0
+	}
+	// A neighbor heard for less than the window is judged only by the HelloMessage(s) sent since it was first heard.
+	expected := history.recent[len(history.recent)-1] - history.first + 1
+	if expected > linkQualityWindow {
+		expected = linkQualityWindow
+	}
+	return float64(len(history.recent)) / float64(expected)
+}
+
+// advertisedLinkQuality is the link quality to advertise for each listed neighbor in a HelloMessage, or nil if the
+// Node does not advertise link quality.
+func (n *Node) advertisedLinkQuality() map[NodeID]float64 {
+	if !n.advertiseLinkQuality {
+		return nil
+	}
+	quality := make(map[NodeID]float64, len(n.oneHopNeighbors))
+	for id := range n.oneHopNeighbors {
+		quality[id] = n.linkQuality(id)
+	}
+	return quality
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestNode_linkQuality(t *testing.T) {
+	tests := []struct {
+		name     string
+		received []int
+		want     float64
+	}{
+		{name: "none", received: nil, want: 0},
+		{name: "all", received: []int{0, 1, 2, 3}, want: 1},
+		{name: "every other", received: []int{0, 2, 4, 6, 8}, want: 5.0 / 9},
+		{name: "beyond the window", received: []int{0, 2, 4, 6, 8, 10, 12, 14, 16, 18}, want: 0.5},
+		{name: "first heard late", received: []int{5, 6}, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := newTestNode(0, &recordingTransmitter{})
+			for _, seq := range tt.received {
+				n.handleHello(&HelloMessage{Source: 1, Sequence: seq})
+			}
+			if got := n.linkQuality(1); got != tt.want {
+				t.Errorf("linkQuality() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNode_SetAdvertiseLinkQuality(t *testing.T) {
+	for _, enabled := range []bool{false, true} {
+		out := &recordingTransmitter{}
+		sender := newTestNode(0, out)
+		sender.SetAdvertiseLinkQuality(enabled)
+		for _, seq := range []int{0, 1, 3} {
+			sender.handleHello(&HelloMessage{Source: 1, Sequence: seq})
+		}
+		sender.sendHello()
+		hello := out.sent[0].(*HelloMessage)
+		if !enabled {
+			if hello.LinkQuality != nil {
+				t.Errorf("HELLO advertises link quality %v while disabled", hello.LinkQuality)
+			}
+			continue
+		}
+		if got, want := hello.LinkQuality[1], 0.75; got != want {
+			t.Errorf("advertised link quality from 1 = %v, want %v", got, want)
+		}
+
+		// The neighbor stores the quality of its link to the sender, as the sender perceives it.
+		receiver := newTestNode(1, &recordingTransmitter{})
+		receiver.handleHello(hello)
+		if got, want := receiver.oneHopNeighbors[0].neighborLinkQuality, 0.75; got != want {
+			t.Errorf("neighborLinkQuality = %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"time"
+)
+
+// linkQueue holds the messages sent across a directed link which have yet to be delivered, so that they arrive in the
+// order they were sent.
+type linkQueue struct {
+	pending []queuedMessage
+
+	// draining is set while a goroutine is delivering the pending messages.
+	draining bool
+}
+
+// queuedMessage is a message waiting to cross a link.
+type queuedMessage struct {
+	msg interface{}
+
+	// arriveAt is the tick, since the epoch, at which the message arrives after the link's delay.
+	arriveAt int
+
+	// done is called with whether the message was delivered.
+	done func(delivered bool)
+}
+
+// sendAcross queues a message sent at the tick to cross the directed link, after the link's delay, without blocking.
+// Messages across the same link are delivered one at a time in the order they were queued, so a node's messages reach
+// each neighbor in the order they were sent.
+func (c *Controller) sendAcross(from, to NodeID, msg interface{}, epoch time.Time, tick int, done func(delivered bool)) {
+	delay := c.linkDelay(QueryMsg{FromNode: from, ToNode: to, AtTime: tick})
+
+	c.linkQueuesMu.Lock()
+	defer c.linkQueuesMu.Unlock()
+	q, in := c.linkQueues[[2]NodeID{from, to}]
+	if !in {
+		q = &linkQueue{}
+		c.linkQueues[[2]NodeID{from, to}] = q
+	}
+	// A later message may not overtake an earlier one, even if the link's delay has since shortened.
+	arriveAt := tick + delay
+	if n := len(q.pending); n > 0 && q.pending[n-1].arriveAt > arriveAt {
+		arriveAt = q.pending[n-1].arriveAt
+	}
+	q.pending = append(q.pending, queuedMessage{msg: msg, arriveAt: arriveAt, done: done})
+	if !q.draining {
+		q.draining = true
+		go c.drainLink(q, to, epoch)
+	}
+}
+
+// drainLink delivers the link's pending messages in order, returning once none are left.
+func (c *Controller) drainLink(q *linkQueue, to NodeID, epoch time.Time) {
+	for {
+		c.linkQueuesMu.Lock()
+		if len(q.pending) == 0 {
+			q.draining = false
+			c.linkQueuesMu.Unlock()
+			return
+		}
+		next := q.pending[0]
+		q.pending = q.pending[1:]
+		c.linkQueuesMu.Unlock()
+
+		next.done(c.deliverAt(to, next.msg, epoch, next.arriveAt))
+	}
+}
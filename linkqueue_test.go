@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// waitForLinks blocks until every message queued on a link has been delivered or dropped.
+func waitForLinks(t *testing.T, c *Controller) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		c.linkQueuesMu.Lock()
+		idle := true
+		for _, q := range c.linkQueues {
+			idle = idle && !q.draining
+		}
+		c.linkQueuesMu.Unlock()
+		if idle {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("link queues did not drain")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestController_sendAcross_fifo(t *testing.T) {
+	tests := []struct {
+		name     string
+		topology string
+	}{
+		{name: "no delay", topology: "0 UP 0 1\n"},
+		{name: "delay", topology: "0 UP 0 1 2\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topology, err := NewNetworkTypology(strings.NewReader(tt.topology))
+			if err != nil {
+				t.Fatalf("NewNetworkTypology() error = %v", err)
+			}
+			c := NewController(*topology, time.Millisecond)
+			for id := NodeID(0); id < 2; id++ {
+				c.nodes = append(c.nodes, newTestNode(id, &recordingTransmitter{}))
+				c.nodeStarted[id] = make(chan struct{})
+				c.nodeStopped[id] = make(chan struct{})
+				close(c.nodeStarted[id])
+			}
+			// The receiver is slow to read, so later messages are routed while earlier ones are still in flight.
+			in := make(chan interface{})
+			c.nodeChannels[1] = in
+			epoch := time.Now()
+
+			const rounds = 50
+			for i := 0; i < rounds; i++ {
+				c.handleHelloMessage(&HelloMessage{Source: 0, Sequence: i}, epoch)
+				c.handleTCMessage(&TCMessage{Source: 0, FromNeighbor: 0, Sequence: i}, epoch)
+			}
+			for i := 0; i < rounds; i++ {
+				if msg, ok := (<-in).(*HelloMessage); !ok || msg.Sequence != i {
+					t.Fatalf("round %d: received %v, want HELLO %d before TC %d", i, msg, i, i)
+				}
+				if msg, ok := (<-in).(*TCMessage); !ok || msg.Sequence != i {
+					t.Fatalf("round %d: received %v, want TC %d", i, msg, i)
+				}
+			}
+			waitForLinks(t, c)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// LogFormat determines how a Node writes its log files.
+type LogFormat int
+
+const (
+	// LogText writes each message in its String() format, one per line.
+	LogText LogFormat = iota
+
+	// LogJSON writes one JSON object per line, describing the tick, node, direction, message type, and the line that
+	// LogText would have written.
+	LogJSON
+)
+
+// ParseLogFormat parses a LogFormat from its name, either "text" or "json".
+func ParseLogFormat(s string) (LogFormat, error) {
+	switch s {
+	case "text":
+		return LogText, nil
+	case "json":
+		return LogJSON, nil
+	default:
+		return LogText, fmt.Errorf("unknown log format: '%s'", s)
+	}
+}
+
+// logRecord is a single line of a LogJSON log file.
+type logRecord struct {
+	Tick      int    `json:"tick"`
+	Node      NodeID `json:"node"`
+	Direction string `json:"direction"`
+	MsgType   string `json:"msgType"`
+	Raw       string `json:"raw"`
+}
+
+// messageType determines the OLSR message type of a message.
+func messageType(msg interface{}) string {
+	switch msg.(type) {
+	case *HelloMessage:
+		return "HELLO"
+	case *TCMessage:
+		return "TC"
+	case *MIDMessage:
+		return "MID"
+	case *DataMessage:
+		return "DATA"
+	default:
+		return "UNKNOWN"
+	}
+}
+
+// writeLog writes a line describing the message to one of the Node's logs, in the Node's LogFormat.
+// The direction is one of "in", "out", or "received".
+func (n *Node) writeLog(w io.Writer, direction string, msg interface{}, raw string) error {
+	if n.logFormat != LogJSON {
+		_, err := fmt.Fprintln(w, raw)
+		return err
+	}
+	return json.NewEncoder(w).Encode(logRecord{
+		Tick:      n.currentTick,
+		Node:      n.id,
+		Direction: direction,
+		MsgType:   messageType(msg),
+		Raw:       raw,
+	})
+}
+
+// jsonLogWriter is the output of a log.Logger, such as the standard one, under LogJSON. It writes each line logged as a
+// JSON object with the time and the line, so that it can be ingested along with the Node log files.
+type jsonLogWriter struct {
+	w   io.Writer
+	now func() time.Time
+}
+
+// jsonLogLine is a single line written by a jsonLogWriter.
+type jsonLogLine struct {
+	Time string `json:"time"`
+	Msg  string `json:"msg"`
+}
+
+// newJSONLogWriter creates a jsonLogWriter writing to w. The log.Logger using it should have no flags, as the time is
+// included by the jsonLogWriter.
+func newJSONLogWriter(w io.Writer) *jsonLogWriter {
+	return &jsonLogWriter{w: w, now: time.Now}
+}
+
+// Write writes p, a single line from a log.Logger, as a JSON object.
+func (w *jsonLogWriter) Write(p []byte) (int, error) {
+	err := json.NewEncoder(w.w).Encode(jsonLogLine{
+		Time: w.now().Format(time.RFC3339Nano),
+		Msg:  strings.TrimSuffix(string(p), "\n"),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
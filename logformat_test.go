@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseLogFormat(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    LogFormat
+		wantErr bool
+	}{
+		{s: "text", want: LogText},
+		{s: "json", want: LogJSON},
+		{s: "xml", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseLogFormat(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLogFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLogFormat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNode_writeLog(t *testing.T) {
+	tests := []struct {
+		name   string
+		format LogFormat
+		want   []string
+	}{
+		{
+			name:   "text",
+			format: LogText,
+			want:   []string{"* 0 HELLO UNIDIR  BIDIR  MPR ", "* 0 TC 0 0 MS 1"},
+		},
+		{
+			name:   "json",
+			format: LogJSON,
+			want: []string{
+				`{"tick":0,"node":0,"direction":"out","msgType":"HELLO","raw":"* 0 HELLO UNIDIR  BIDIR  MPR "}`,
+				`{"tick":0,"node":0,"direction":"out","msgType":"TC","raw":"* 0 TC 0 0 MS 1"}`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := newTestNode(0, &recordingTransmitter{})
+			n.logFormat = tt.format
+			n.msSet[1] = 1
+			n.tick(nil)
+
+			got := strings.Split(strings.TrimSuffix(n.outputLog.(*bufferCloser).String(), "\n"), "\n")
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("output log = %q, want %q", got, tt.want)
+			}
+			if tt.format != LogJSON {
+				return
+			}
+			for _, line := range got {
+				var record logRecord
+				if err := json.Unmarshal([]byte(line), &record); err != nil {
+					t.Errorf("output log line is not JSON: %q: %v", line, err)
+				}
+			}
+		})
+	}
+}
+
+func TestJSONLogWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := newJSONLogWriter(&buf)
+	w.now = func() time.Time { return time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC) }
+	logger := log.New(w, "", 0)
+
+	logger.Printf("node %s: Sent:\t%s", NodeID(0), "* 0 TC 0 0 MS 1")
+	logger.Print("warning: line\n")
+
+	want := `{"time":"2024-01-02T03:04:05Z","msg":"node 0: Sent:\t* 0 TC 0 0 MS 1"}` + "\n" +
+		`{"time":"2024-01-02T03:04:05Z","msg":"warning: line"}` + "\n"
+	if got := buf.String(); got != want {
+		t.Errorf("output = %q, want %q", got, want)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"flag"
 	"fmt"
+	"log"
 	"os"
 	"time"
 )
@@ -12,12 +13,36 @@ func main() {
 	nf := flag.String("nf", "", "Node configuration file path (Required)")
 	t := flag.Int("t", 1000, "Tick duration in milliseconds. Specifies how fast the simulation will Run")
 	d := flag.Int("rt", 120, "Number of ticks to Run the simulation for.")
+	sg := flag.Int("sg", -1, "Stop the simulation this many ticks after all data messages are delivered or dropped. Disabled when negative.")
+	ld := flag.String("ld", "./log", "Directory to write node log files to.")
+	lf := flag.String("lf", "text", "Format of node log files and of the log written to standard error, either text or json.")
+	synchronous := flag.Bool("sync", false, "Run all nodes in a single goroutine, in lock-step ticks, as fast as possible.")
+	collisions := flag.Bool("collisions", false, "Drop all messages a node receives in a tick from more than one sender. Requires -sync.")
+	strict := flag.Bool("strict", false, "Fail if the topology has links involving a node with no configuration, rather than creating it.")
+	sf := flag.String("sf", "table", "Format of the statistics written after the run, either table, csv, or json.")
+	rf := flag.String("rf", "", "Append a tab-separated record of the run's results to this file.")
+	debug := flag.Bool("debug", false, "Log whenever a neighbor's advertised relationship to a node changes, or a stale TC is discarded.")
 	flag.Parse()
 
+	format, err := ParseLogFormat(*lf)
+	if err != nil {
+		fmt.Printf("invalid log format: %s", err)
+		os.Exit(1)
+	}
+	if format == LogJSON {
+		log.SetFlags(0)
+		log.SetOutput(newJSONLogWriter(os.Stderr))
+	}
+
 	if *tf == "" || *nf == "" {
 		flag.PrintDefaults()
 		os.Exit(1)
 	}
+	// Collisions are only modelled by the lock-step ticks of a synchronous run.
+	if *collisions && !*synchronous {
+		fmt.Printf("-collisions requires -sync")
+		os.Exit(1)
+	}
 
 	f, err := os.Open(*tf)
 	if err != nil {
@@ -46,9 +71,49 @@ func main() {
 	if err := f.Close(); err != nil {
 		fmt.Printf("could not close node configuration file: %s", err)
 	}
+	for _, warning := range ValidateScenario(configs) {
+		log.Printf("warning: %s", warning)
+	}
+
+	reportFormat, err := ParseReportFormat(*sf)
+	if err != nil {
+		fmt.Printf("invalid statistics format: %s", err)
+		os.Exit(1)
+	}
 
 	td := time.Millisecond * time.Duration(*t)
 	c := NewController(*nwt, td)
-	c.Initialize(configs)
+	c.SetLogDir(*ld)
+	c.SetLogFormat(format)
+	c.SetStrictTopology(*strict)
+	if *debug {
+		c.SetNeighborTransitionLog(log.Default())
+		c.SetStaleTCLog(log.Default())
+	}
+	if err := c.Initialize(configs); err != nil {
+		fmt.Printf("invalid scenario: %s", err)
+		os.Exit(1)
+	}
+	c.SetSynchronous(*synchronous)
+	c.SetCollisions(*collisions)
+	if *sg >= 0 {
+		c.StopWhenResolved(*sg)
+	}
+	c.RecordConvergence(*rf != "")
 	c.Start(*d)
+
+	for _, id := range c.NeverScheduled() {
+		log.Printf("warning: node %s: message was never sent, as the run ended before its delay", id)
+	}
+	stats := c.Stats()
+	if err := stats.ReportAs(os.Stdout, reportFormat); err != nil {
+		fmt.Printf("could not write statistics: %s", err)
+	}
+	if *rf != "" {
+		stats.Scenario = *nf
+		stats.Parameters = fmt.Sprintf("tf=%s t=%d rt=%d sg=%d sync=%t", *tf, *t, *d, *sg, *synchronous)
+		if err := stats.AppendTo(*rf); err != nil {
+			fmt.Printf("could not append results: %s", err)
+		}
+	}
 }
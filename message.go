@@ -1,7 +1,9 @@
 package main
 
 import (
+	"encoding/base64"
 	"fmt"
+	"strconv"
 	"strings"
 )
 
@@ -21,6 +23,14 @@ type HelloMessage struct {
 	Bidirectional   []NodeID
 	MultipointRelay []NodeID
 
+	// LinkQuality optionally holds the sender's perceived quality, in the range [0, 1], of the link from each listed
+	// neighbor. A nil map represents the legacy HELLO format without link quality.
+	LinkQuality map[NodeID]float64
+
+	// UnknownCategories holds any neighbor categories of an extended HELLO format which are not recognized. They are
+	// ignored when updating neighbor state, but preserved so that the message is re-encoded as it was received.
+	UnknownCategories []HelloCategory
+
 	// Sequence numbers are added to ensure hello messages are delivered in order.
 	// The sequence number is needed for the simulation, as hello messages may be delivered out-of-order due to
 	// scheduling of goroutines.
@@ -29,15 +39,45 @@ type HelloMessage struct {
 	Sequence int
 }
 
+// HelloCategory is a neighbor category of an extended HELLO format, such as one added by a later version of the
+// protocol, which is not recognized. Its neighbors are kept as they were written.
+type HelloCategory struct {
+	Name      string
+	Neighbors []string
+}
+
+// String writes the known categories, followed by any unknown categories in the order they were parsed.
 func (m HelloMessage) String() string {
-	f := "* %d HELLO UNIDIR %s BIDIR %s MPR %s"
-	return fmt.Sprintf(
+	f := "* %s HELLO UNIDIR %s BIDIR %s MPR %s"
+	s := fmt.Sprintf(
 		f,
 		m.Source,
-		separatedString(m.Unidirectional, " "),
-		separatedString(m.Bidirectional, " "),
-		separatedString(m.MultipointRelay, " "),
+		m.neighborString(m.Unidirectional),
+		m.neighborString(m.Bidirectional),
+		m.neighborString(m.MultipointRelay),
 	)
+	for _, category := range m.UnknownCategories {
+		s += fmt.Sprintf(" %s %s", category.Name, strings.Join(category.Neighbors, " "))
+	}
+	return s
+}
+
+// neighborString creates a space separated string of neighbors, suffixing each with ":{LQ}" when link quality is
+// advertised for it.
+func (m HelloMessage) neighborString(ids []NodeID) string {
+	if m.LinkQuality == nil {
+		return separatedString(ids, " ")
+	}
+	var strs []string
+	for _, id := range ids {
+		lq, in := m.LinkQuality[id]
+		if !in {
+			strs = append(strs, id.String())
+			continue
+		}
+		strs = append(strs, fmt.Sprintf("%s:%.2f", id, lq))
+	}
+	return strings.Join(strs, " ")
 }
 
 // DataMessage represents a DATA OLSR message.
@@ -46,23 +86,389 @@ type DataMessage struct {
 	Destination  NodeID
 	NextHop      NodeID
 	FromNeighbor NodeID
-	Data         string
+
+	// Data is either a plain string or an encoded DataPayload.
+	Data string
+
+	// Path holds each node which forwarded the message, in order. Not included in the String() format.
+	Path []NodeID
+
+	// Receivers holds the members of the multicast group Destination which this copy of the message is still to be
+	// delivered to. Empty for unicast messages. Not included in the String() format.
+	Receivers []NodeID
+
+	// Priority is the message's class of service, higher being more important, which decides the messages carried by
+	// a congested link. Zero by default. Included in the String() format only when non-zero, or when the data itself
+	// begins with the priority token.
+	Priority int
 }
 
+// dataPriorityToken introduces the optional priority of a DataMessage in its String() format.
+const dataPriorityToken = "PRIO"
+
 func (m DataMessage) String() string {
-	f := "%d %d DATA %d %d %s"
+	if m.Priority != 0 || strings.HasPrefix(m.Data, dataPriorityToken+" ") {
+		f := "%s %s DATA %s %s " + dataPriorityToken + " %d %s"
+		return fmt.Sprintf(f, m.NextHop, m.FromNeighbor, m.Source, m.Destination, m.Priority, m.Data)
+	}
+	f := "%s %s DATA %s %s %s"
 	return fmt.Sprintf(f, m.NextHop, m.FromNeighbor, m.Source, m.Destination, m.Data)
 }
 
+// dataPayloadPrefix marks DataMessage data which holds an encoded DataPayload.
+const dataPayloadPrefix = "payload"
+
+// DataPayload is an optional structured payload carried as the data of a DataMessage.
+type DataPayload struct {
+	// Sequence is an application defined sequence number.
+	Sequence int
+
+	// Timestamp is an application defined timestamp, such as the tick the payload was created at.
+	Timestamp int64
+
+	// Body holds arbitrary bytes.
+	Body []byte
+}
+
+// Encode creates the DataMessage data representing the payload, in the form: payload:{SEQ}:{TIMESTAMP}:{BASE64_BODY}
+// The encoding contains no whitespace, keeping the DataMessage String() format parseable.
+func (p DataPayload) Encode() string {
+	return fmt.Sprintf("%s:%d:%d:%s", dataPayloadPrefix, p.Sequence, p.Timestamp, base64.StdEncoding.EncodeToString(p.Body))
+}
+
+// IsDataPayload determines whether DataMessage data holds an encoded DataPayload.
+func IsDataPayload(data string) bool {
+	return strings.HasPrefix(data, dataPayloadPrefix+":")
+}
+
+// DecodeDataPayload parses DataMessage data created by DataPayload.Encode.
+func DecodeDataPayload(data string) (DataPayload, error) {
+	fields := strings.Split(data, ":")
+	if len(fields) != 4 || fields[0] != dataPayloadPrefix {
+		return DataPayload{}, fmt.Errorf("decode data payload: must be of the form '%s:{SEQ}:{TIMESTAMP}:{BODY}': '%s'", dataPayloadPrefix, data)
+	}
+	seq, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return DataPayload{}, fmt.Errorf("decode data payload: sequence is not an integer: '%s'", fields[1])
+	}
+	ts, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		return DataPayload{}, fmt.Errorf("decode data payload: timestamp is not an integer: '%s'", fields[2])
+	}
+	body, err := base64.StdEncoding.DecodeString(fields[3])
+	if err != nil {
+		return DataPayload{}, fmt.Errorf("decode data payload: body is not base64: %s", err)
+	}
+	// An empty body encodes the same as a nil one, so it decodes as nil, round-tripping the zero DataPayload.
+	if len(body) == 0 {
+		body = nil
+	}
+	return DataPayload{Sequence: seq, Timestamp: ts, Body: body}, nil
+}
+
 // TCMessage represents a topology control (TC) OLSR message.
 type TCMessage struct {
 	Source             NodeID
 	FromNeighbor       NodeID
 	Sequence           int
 	MultipointRelaySet []NodeID
+
+	// MessageSequence is incremented by the originator for every TCMessage it sends, unlike the ANSN in Sequence,
+	// identifying copies of the same TCMessage received over several paths. Like a HelloMessage's sequence number, it
+	// is not part of the String() format.
+	MessageSequence int
+
+	// Incremental marks a TCMessage which advertises only the changes to the MS set since the previous TCMessage,
+	// in Added and Removed, rather than the full MultipointRelaySet.
+	Incremental bool
+	Added       []NodeID
+	Removed     []NodeID
 }
 
 func (m TCMessage) String() string {
-	f := "* %d TC %d %d MS %s"
+	if m.Incremental {
+		f := "* %s TC %s %d ADD %s DEL %s"
+		return fmt.Sprintf(f, m.FromNeighbor, m.Source, m.Sequence, separatedString(m.Added, " "), separatedString(m.Removed, " "))
+	}
+	f := "* %s TC %s %d MS %s"
 	return fmt.Sprintf(f, m.FromNeighbor, m.Source, m.Sequence, separatedString(m.MultipointRelaySet, " "))
 }
+
+// MIDMessage represents a Multiple Interface Declaration (MID) OLSR message, declaring the interface addresses of a
+// node besides its main address. Like a TCMessage, it is flooded through the network by MPRs.
+type MIDMessage struct {
+	// Source is the main address of the node declaring its interfaces.
+	Source       NodeID
+	FromNeighbor NodeID
+	Sequence     int
+	Interfaces   []NodeID
+}
+
+func (m MIDMessage) String() string {
+	f := "* %s MID %s %d IFACES %s"
+	return fmt.Sprintf(f, m.FromNeighbor, m.Source, m.Sequence, separatedString(m.Interfaces, " "))
+}
+
+// ErrParseMessage is returned when a message can not be parsed from its String() format.
+type ErrParseMessage struct {
+	msg string
+}
+
+func (e ErrParseMessage) Error() string {
+	return fmt.Sprintf("parse message: %s", e.msg)
+}
+
+// ParseMessage parses a HelloMessage, TCMessage, MIDMessage, or DataMessage from its String() format.
+// Fields excluded from the format, such as sequence numbers of HELLO messages, are left as zero values.
+func ParseMessage(s string) (interface{}, error) {
+	fields := strings.Fields(s)
+	if len(fields) < 3 {
+		return nil, ErrParseMessage{msg: fmt.Sprintf("too few fields: '%s'", s)}
+	}
+	switch fields[2] {
+	case "HELLO":
+		return parseHelloMessage(fields)
+	case "TC":
+		return parseTCMessage(fields)
+	case "MID":
+		return parseMIDMessage(fields)
+	case "DATA":
+		return parseDataMessage(s)
+	default:
+		return nil, ErrParseMessage{msg: fmt.Sprintf("unknown message type: '%s'", fields[2])}
+	}
+}
+
+// parseHelloMessage parses the fields of: * {SRC} HELLO UNIDIR {IDS} BIDIR {IDS} MPR {IDS}
+// where each ID may be suffixed with ":{LQ}". For forward compatibility, the known categories may be interleaved with
+// or followed by unknown ones, each an upper-case marker followed by neighbors, which are kept in UnknownCategories.
+func parseHelloMessage(fields []string) (*HelloMessage, error) {
+	if fields[0] != "*" {
+		return nil, ErrParseMessage{msg: fmt.Sprintf("HELLO must start with '*': '%s'", fields[0])}
+	}
+	src, err := parseNodeID(fields[1])
+	if err != nil {
+		return nil, ErrParseMessage{msg: fmt.Sprintf("invalid HELLO source: '%s'", fields[1])}
+	}
+	m := &HelloMessage{Source: src}
+
+	// The three known neighbor lists must appear in order.
+	sections := []struct {
+		marker string
+		ids    *[]NodeID
+	}{
+		{marker: "UNIDIR", ids: &m.Unidirectional},
+		{marker: "BIDIR", ids: &m.Bidirectional},
+		{marker: "MPR", ids: &m.MultipointRelay},
+	}
+	known := func(marker string) bool {
+		for _, section := range sections {
+			if section.marker == marker {
+				return true
+			}
+		}
+		return false
+	}
+	// next is the index of the next known section expected. The fields belong to the known section before it, unless
+	// unknown is set, when they belong to the last unknown category.
+	next, unknown := 0, false
+	for _, field := range fields[3:] {
+		if isHelloCategoryMarker(field) {
+			switch {
+			case next < len(sections) && field == sections[next].marker:
+				next++
+				unknown = false
+			case known(field):
+				return nil, ErrParseMessage{msg: fmt.Sprintf("HELLO category '%s' out of order", field)}
+			case next == 0:
+				return nil, ErrParseMessage{msg: fmt.Sprintf("HELLO missing '%s'", sections[0].marker)}
+			default:
+				m.UnknownCategories = append(m.UnknownCategories, HelloCategory{Name: field})
+				unknown = true
+			}
+			continue
+		}
+		if next == 0 {
+			return nil, ErrParseMessage{msg: fmt.Sprintf("HELLO missing '%s'", sections[0].marker)}
+		}
+		if unknown {
+			last := &m.UnknownCategories[len(m.UnknownCategories)-1]
+			last.Neighbors = append(last.Neighbors, field)
+			continue
+		}
+		id, lq, hasLQ, err := parseNeighbor(field)
+		if err != nil {
+			return nil, err
+		}
+		*sections[next-1].ids = append(*sections[next-1].ids, id)
+		if hasLQ {
+			if m.LinkQuality == nil {
+				m.LinkQuality = make(map[NodeID]float64)
+			}
+			m.LinkQuality[id] = lq
+		}
+	}
+	if next < len(sections) {
+		return nil, ErrParseMessage{msg: fmt.Sprintf("HELLO missing '%s'", sections[next].marker)}
+	}
+	return m, nil
+}
+
+// isHelloCategoryMarker determines whether a HELLO field names a neighbor category, being an upper-case letter
+// followed by upper-case letters, digits, or underscores, rather than a neighbor.
+func isHelloCategoryMarker(field string) bool {
+	for i, r := range field {
+		switch {
+		case r >= 'A' && r <= 'Z':
+		case i > 0 && (r >= '0' && r <= '9' || r == '_'):
+		default:
+			return false
+		}
+	}
+	return field != ""
+}
+
+// parseNeighbor parses a HELLO neighbor in the form {ID} or {ID}:{LQ}.
+func parseNeighbor(field string) (id NodeID, lq float64, hasLQ bool, err error) {
+	rawID, rawLQ, hasLQ := strings.Cut(field, ":")
+	id, err = parseNodeID(rawID)
+	if err != nil {
+		return 0, 0, false, ErrParseMessage{msg: fmt.Sprintf("invalid HELLO neighbor: '%s'", field)}
+	}
+	if !hasLQ {
+		return id, 0, false, nil
+	}
+	lq, err = strconv.ParseFloat(rawLQ, 64)
+	if err != nil || !(lq >= 0 && lq <= 1) {
+		return 0, 0, false, ErrParseMessage{msg: fmt.Sprintf("invalid HELLO link quality: '%s'", field)}
+	}
+	return id, lq, true, nil
+}
+
+// parseTCMessage parses the fields of: * {FROM} TC {SRC} {SEQ} MS {IDS}
+// or, for an incremental TC: * {FROM} TC {SRC} {SEQ} ADD {IDS} DEL {IDS}
+func parseTCMessage(fields []string) (*TCMessage, error) {
+	if fields[0] != "*" {
+		return nil, ErrParseMessage{msg: fmt.Sprintf("TC must start with '*': '%s'", fields[0])}
+	}
+	if len(fields) < 6 || (fields[5] != "MS" && fields[5] != "ADD") {
+		return nil, ErrParseMessage{msg: "TC must be of the form: '* {FROM} TC {SRC} {SEQ} MS {IDS}' or '* {FROM} TC {SRC} {SEQ} ADD {IDS} DEL {IDS}'"}
+	}
+	from, err := parseNodeID(fields[1])
+	if err != nil {
+		return nil, ErrParseMessage{msg: fmt.Sprintf("invalid TC from-neighbor: '%s'", fields[1])}
+	}
+	src, err := parseNodeID(fields[3])
+	if err != nil {
+		return nil, ErrParseMessage{msg: fmt.Sprintf("invalid TC source: '%s'", fields[3])}
+	}
+	seq, err := strconv.Atoi(fields[4])
+	if err != nil || seq < 0 {
+		return nil, ErrParseMessage{msg: fmt.Sprintf("invalid TC sequence number: '%s'", fields[4])}
+	}
+	m := &TCMessage{Source: src, FromNeighbor: from, Sequence: seq}
+	if fields[5] == "ADD" {
+		m.Incremental = true
+		rest := fields[6:]
+		end := 0
+		for end < len(rest) && rest[end] != "DEL" {
+			end++
+		}
+		if end == len(rest) {
+			return nil, ErrParseMessage{msg: "incremental TC missing 'DEL'"}
+		}
+		if m.Added, err = parseTCNodeIDs(rest[:end]); err != nil {
+			return nil, err
+		}
+		if m.Removed, err = parseTCNodeIDs(rest[end+1:]); err != nil {
+			return nil, err
+		}
+		return m, nil
+	}
+	if m.MultipointRelaySet, err = parseTCNodeIDs(fields[6:]); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// parseTCNodeIDs parses a list of node IDs advertised in a TCMessage.
+func parseTCNodeIDs(fields []string) ([]NodeID, error) {
+	var ids []NodeID
+	for _, field := range fields {
+		id, err := parseNodeID(field)
+		if err != nil {
+			return nil, ErrParseMessage{msg: fmt.Sprintf("invalid TC MS set entry: '%s'", field)}
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// parseMIDMessage parses the fields of: * {FROM} MID {SRC} {SEQ} IFACES {IDS}
+func parseMIDMessage(fields []string) (*MIDMessage, error) {
+	if fields[0] != "*" {
+		return nil, ErrParseMessage{msg: fmt.Sprintf("MID must start with '*': '%s'", fields[0])}
+	}
+	if len(fields) < 6 || fields[5] != "IFACES" {
+		return nil, ErrParseMessage{msg: "MID must be of the form: '* {FROM} MID {SRC} {SEQ} IFACES {IDS}'"}
+	}
+	from, err := parseNodeID(fields[1])
+	if err != nil {
+		return nil, ErrParseMessage{msg: fmt.Sprintf("invalid MID from-neighbor: '%s'", fields[1])}
+	}
+	src, err := parseNodeID(fields[3])
+	if err != nil {
+		return nil, ErrParseMessage{msg: fmt.Sprintf("invalid MID source: '%s'", fields[3])}
+	}
+	seq, err := strconv.Atoi(fields[4])
+	if err != nil || seq < 0 {
+		return nil, ErrParseMessage{msg: fmt.Sprintf("invalid MID sequence number: '%s'", fields[4])}
+	}
+	m := &MIDMessage{Source: src, FromNeighbor: from, Sequence: seq}
+	for _, field := range fields[6:] {
+		id, err := parseNodeID(field)
+		if err != nil {
+			return nil, ErrParseMessage{msg: fmt.Sprintf("invalid MID interface address: '%s'", field)}
+		}
+		m.Interfaces = append(m.Interfaces, id)
+	}
+	return m, nil
+}
+
+// parseDataMessage parses: {NEXT_HOP} {FROM} DATA {SRC} {DST} [PRIO {PRIORITY}] {DATA}
+// The data is the remainder of the string, and may contain spaces.
+func parseDataMessage(s string) (*DataMessage, error) {
+	fields := strings.SplitN(s, " ", 6)
+	if len(fields) != 6 || fields[2] != "DATA" {
+		return nil, ErrParseMessage{msg: "DATA must be of the form: '{NEXT_HOP} {FROM} DATA {SRC} {DST} [PRIO {PRIORITY}] {DATA}'"}
+	}
+	ids := make([]NodeID, 0, 4)
+	for _, field := range []string{fields[0], fields[1], fields[3], fields[4]} {
+		id, err := parseNodeID(field)
+		if err != nil {
+			return nil, ErrParseMessage{msg: fmt.Sprintf("invalid DATA node ID: '%s'", field)}
+		}
+		ids = append(ids, id)
+	}
+	data, priority := fields[5], 0
+	if strings.HasPrefix(data, dataPriorityToken+" ") {
+		prio := strings.SplitN(data, " ", 3)
+		if len(prio) != 3 {
+			return nil, ErrParseMessage{msg: fmt.Sprintf("missing DATA after priority: '%s'", s)}
+		}
+		var err error
+		priority, err = strconv.Atoi(prio[1])
+		if err != nil {
+			return nil, ErrParseMessage{msg: fmt.Sprintf("invalid DATA priority: '%s'", prio[1])}
+		}
+		data = prio[2]
+	}
+	return &DataMessage{
+		NextHop:      ids[0],
+		FromNeighbor: ids[1],
+		Source:       ids[2],
+		Destination:  ids[3],
+		Data:         data,
+		Priority:     priority,
+	}, nil
+}
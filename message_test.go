@@ -1,6 +1,10 @@
 package main
 
-import "testing"
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
 
 func TestTCMessage_String(t *testing.T) {
 	type fields struct {
@@ -8,6 +12,9 @@ func TestTCMessage_String(t *testing.T) {
 		frombr NodeID
 		seq    int
 		ms     []NodeID
+		inc    bool
+		add    []NodeID
+		del    []NodeID
 	}
 	tests := []struct {
 		name   string
@@ -24,6 +31,18 @@ func TestTCMessage_String(t *testing.T) {
 			},
 			want: "* 10 TC 0 2 MS 1 2",
 		},
+		{
+			name: "incremental",
+			fields: fields{
+				src:    0,
+				frombr: 10,
+				seq:    3,
+				inc:    true,
+				add:    []NodeID{3},
+				del:    []NodeID{1, 2},
+			},
+			want: "* 10 TC 0 3 ADD 3 DEL 1 2",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -32,6 +51,9 @@ func TestTCMessage_String(t *testing.T) {
 				FromNeighbor:       tt.fields.frombr,
 				Sequence:           tt.fields.seq,
 				MultipointRelaySet: tt.fields.ms,
+				Incremental:        tt.fields.inc,
+				Added:              tt.fields.add,
+				Removed:            tt.fields.del,
 			}
 			if got := m.String(); got != tt.want {
 				t.Errorf("String() = %v, want %v", got, tt.want)
@@ -40,13 +62,32 @@ func TestTCMessage_String(t *testing.T) {
 	}
 }
 
+func TestMIDMessage_String(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  MIDMessage
+		want string
+	}{
+		{name: "check format", msg: MIDMessage{Source: 0, FromNeighbor: 10, Sequence: 2, Interfaces: []NodeID{11, 12}}, want: "* 10 MID 0 2 IFACES 11 12"},
+		{name: "no interfaces", msg: MIDMessage{Source: 0, FromNeighbor: 0, Sequence: 1}, want: "* 0 MID 0 1 IFACES "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.msg.String(); got != tt.want {
+				t.Errorf("String() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
 func TestDataMessage_String(t *testing.T) {
 	type fields struct {
-		src     NodeID
-		dst     NodeID
-		nxtHop  NodeID
-		fromnbr NodeID
-		data    string
+		src      NodeID
+		dst      NodeID
+		nxtHop   NodeID
+		fromnbr  NodeID
+		data     string
+		priority int
 	}
 	tests := []struct {
 		name   string
@@ -64,6 +105,29 @@ func TestDataMessage_String(t *testing.T) {
 			},
 			want: "3 9 DATA 1 4 hello there",
 		},
+		{
+			name: "priority",
+			fields: fields{
+				src:      1,
+				dst:      4,
+				nxtHop:   3,
+				fromnbr:  9,
+				data:     "hello there",
+				priority: 2,
+			},
+			want: "3 9 DATA 1 4 PRIO 2 hello there",
+		},
+		{
+			name: "data beginning with the priority token",
+			fields: fields{
+				src:     1,
+				dst:     4,
+				nxtHop:  3,
+				fromnbr: 9,
+				data:    "PRIO 5 hello",
+			},
+			want: "3 9 DATA 1 4 PRIO 0 PRIO 5 hello",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -73,10 +137,14 @@ func TestDataMessage_String(t *testing.T) {
 				NextHop:      tt.fields.nxtHop,
 				FromNeighbor: tt.fields.fromnbr,
 				Data:         tt.fields.data,
+				Priority:     tt.fields.priority,
 			}
 			if got := m.String(); got != tt.want {
 				t.Errorf("String() = %v, want %v", got, tt.want)
 			}
+			if got, err := ParseMessage(m.String()); err != nil || !reflect.DeepEqual(got, m) {
+				t.Errorf("ParseMessage(String()) = %#v, %v, want %#v", got, err, m)
+			}
 		})
 	}
 }
@@ -87,6 +155,8 @@ func TestHelloMessage_String(t *testing.T) {
 		unidir []NodeID
 		bidir  []NodeID
 		mpr    []NodeID
+		lq     map[NodeID]float64
+		extra  []HelloCategory
 	}
 	tests := []struct {
 		name   string
@@ -103,14 +173,36 @@ func TestHelloMessage_String(t *testing.T) {
 			},
 			want: "* 4 HELLO UNIDIR 1 2 3 BIDIR 5 6 MPR 7 8",
 		},
+		{
+			name: "with link quality",
+			fields: fields{
+				src:    4,
+				unidir: []NodeID{1},
+				bidir:  []NodeID{5, 6},
+				mpr:    []NodeID{7},
+				lq:     map[NodeID]float64{1: 0.5, 5: 1, 7: 0.25},
+			},
+			want: "* 4 HELLO UNIDIR 1:0.50 BIDIR 5:1.00 6 MPR 7:0.25",
+		},
+		{
+			name: "with unknown categories",
+			fields: fields{
+				src:   4,
+				bidir: []NodeID{5},
+				extra: []HelloCategory{{Name: "LOST", Neighbors: []string{"9", "10:x"}}, {Name: "EMPTY"}},
+			},
+			want: "* 4 HELLO UNIDIR  BIDIR 5 MPR  LOST 9 10:x EMPTY ",
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			m := &HelloMessage{
-				Source:          tt.fields.src,
-				Unidirectional:  tt.fields.unidir,
-				Bidirectional:   tt.fields.bidir,
-				MultipointRelay: tt.fields.mpr,
+				Source:            tt.fields.src,
+				Unidirectional:    tt.fields.unidir,
+				Bidirectional:     tt.fields.bidir,
+				MultipointRelay:   tt.fields.mpr,
+				LinkQuality:       tt.fields.lq,
+				UnknownCategories: tt.fields.extra,
 			}
 			if got := m.String(); got != tt.want {
 				t.Errorf("String() = %v, want %v", got, tt.want)
@@ -118,3 +210,240 @@ func TestHelloMessage_String(t *testing.T) {
 		})
 	}
 }
+
+func TestDataPayload_Encode(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload DataPayload
+		want    string
+	}{
+		{
+			name:    "with body",
+			payload: DataPayload{Sequence: 3, Timestamp: 42, Body: []byte("hello there")},
+			want:    "payload:3:42:aGVsbG8gdGhlcmU=",
+		},
+		{
+			name:    "empty body",
+			payload: DataPayload{Sequence: 0, Timestamp: 0, Body: nil},
+			want:    "payload:0:0:",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.payload.Encode(); got != tt.want {
+				t.Errorf("Encode() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecodeDataPayload(t *testing.T) {
+	tests := []struct {
+		name      string
+		data      string
+		isPayload bool
+		want      DataPayload
+		wantErr   bool
+	}{
+		{
+			name:      "valid",
+			isPayload: true,
+			data:      "payload:3:42:aGVsbG8gdGhlcmU=",
+			want:      DataPayload{Sequence: 3, Timestamp: 42, Body: []byte("hello there")},
+			wantErr:   false,
+		},
+		{
+			name:      "empty body",
+			isPayload: true,
+			data:      "payload:0:0:",
+			want:      DataPayload{},
+			wantErr:   false,
+		},
+		{
+			name:      "plain string",
+			isPayload: false,
+			data:      "(0 -> 2)",
+			want:      DataPayload{},
+			wantErr:   true,
+		},
+		{
+			name:      "invalid sequence",
+			isPayload: true,
+			data:      "payload:x:42:",
+			want:      DataPayload{},
+			wantErr:   true,
+		},
+		{
+			name:      "invalid body",
+			isPayload: true,
+			data:      "payload:1:42:!!",
+			want:      DataPayload{},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := DecodeDataPayload(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("DecodeDataPayload() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got := IsDataPayload(tt.data); got != tt.isPayload {
+				t.Errorf("IsDataPayload() = %v, want %v", got, tt.isPayload)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DecodeDataPayload() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMessage(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name: "hello",
+			s:    "* 0 HELLO UNIDIR 1 BIDIR 2 3 MPR 4",
+			want: &HelloMessage{
+				Source:          0,
+				Unidirectional:  []NodeID{1},
+				Bidirectional:   []NodeID{2, 3},
+				MultipointRelay: []NodeID{4},
+			},
+		},
+		{
+			name: "empty hello",
+			s:    "* 0 HELLO UNIDIR  BIDIR  MPR ",
+			want: &HelloMessage{Source: 0},
+		},
+		{
+			name: "hello with link quality",
+			s:    "* 10.0.0.1 HELLO UNIDIR  BIDIR 1:0.50 2 MPR ",
+			want: &HelloMessage{
+				Source:        0x0A000001,
+				Bidirectional: []NodeID{1, 2},
+				LinkQuality:   map[NodeID]float64{1: 0.5},
+			},
+		},
+		{
+			name: "hello with unknown categories",
+			s:    "* 0 HELLO UNIDIR 1 SYM_V2 7 8:x BIDIR 2 MPR 4 LOST 9",
+			want: &HelloMessage{
+				Source:          0,
+				Unidirectional:  []NodeID{1},
+				Bidirectional:   []NodeID{2},
+				MultipointRelay: []NodeID{4},
+				UnknownCategories: []HelloCategory{
+					{Name: "SYM_V2", Neighbors: []string{"7", "8:x"}},
+					{Name: "LOST", Neighbors: []string{"9"}},
+				},
+			},
+		},
+		{
+			name: "tc",
+			s:    "* 1 TC 2 7 MS 3 4",
+			want: &TCMessage{Source: 2, FromNeighbor: 1, Sequence: 7, MultipointRelaySet: []NodeID{3, 4}},
+		},
+		{
+			name: "empty tc",
+			s:    "* 1 TC 2 7 MS ",
+			want: &TCMessage{Source: 2, FromNeighbor: 1, Sequence: 7},
+		},
+		{
+			name: "data with spaces",
+			s:    "3 1 DATA 0 5 hello 5, from 0",
+			want: &DataMessage{Source: 0, Destination: 5, NextHop: 3, FromNeighbor: 1, Data: "hello 5, from 0"},
+		},
+		{
+			name: "data with priority",
+			s:    "3 1 DATA 0 5 PRIO -1 hello 5, from 0",
+			want: &DataMessage{Source: 0, Destination: 5, NextHop: 3, FromNeighbor: 1, Data: "hello 5, from 0", Priority: -1},
+		},
+		{
+			name: "incremental tc",
+			s:    "* 1 TC 2 8 ADD 5 DEL 3 4",
+			want: &TCMessage{Source: 2, FromNeighbor: 1, Sequence: 8, Incremental: true, Added: []NodeID{5}, Removed: []NodeID{3, 4}},
+		},
+		{
+			name: "empty incremental tc",
+			s:    "* 1 TC 2 8 ADD  DEL ",
+			want: &TCMessage{Source: 2, FromNeighbor: 1, Sequence: 8, Incremental: true},
+		},
+		{
+			name: "mid",
+			s:    "* 1 MID 2 3 IFACES 10.0.0.2 10.0.1.2",
+			want: &MIDMessage{Source: 2, FromNeighbor: 1, Sequence: 3, Interfaces: []NodeID{0x0A000002, 0x0A000102}},
+		},
+		{
+			name: "empty mid",
+			s:    "* 1 MID 2 3 IFACES ",
+			want: &MIDMessage{Source: 2, FromNeighbor: 1, Sequence: 3},
+		},
+		{name: "empty", s: "", wantErr: true},
+		{name: "unknown type", s: "* 0 PING", wantErr: true},
+		{name: "hello sections out of order", s: "* 0 HELLO BIDIR  UNIDIR  MPR ", wantErr: true},
+		{name: "hello missing section", s: "* 0 HELLO UNIDIR 1 BIDIR 2", wantErr: true},
+		{name: "hello repeated section", s: "* 0 HELLO UNIDIR 1 BIDIR 2 MPR  BIDIR 3", wantErr: true},
+		{name: "hello unknown category before UNIDIR", s: "* 0 HELLO LOST 9 UNIDIR  BIDIR  MPR ", wantErr: true},
+		{name: "hello missing section after unknown category", s: "* 0 HELLO UNIDIR 1 LOST 9 MPR ", wantErr: true},
+		{name: "hello link quality out of range", s: "* 0 HELLO UNIDIR 1:1.5 BIDIR  MPR ", wantErr: true},
+		{name: "hello link quality not a number", s: "* 0 HELLO UNIDIR 1:NaN BIDIR  MPR ", wantErr: true},
+		{name: "tc missing MS", s: "* 1 TC 2 7", wantErr: true},
+		{name: "incremental tc missing DEL", s: "* 1 TC 2 8 ADD 5", wantErr: true},
+		{name: "tc negative sequence", s: "* 1 TC 2 -7 MS ", wantErr: true},
+		{name: "mid missing IFACES", s: "* 1 MID 2 3 4", wantErr: true},
+		{name: "mid bad interface", s: "* 1 MID 2 3 IFACES x", wantErr: true},
+		{name: "data missing data", s: "3 1 DATA 0 5", wantErr: true},
+		{name: "data missing data after priority", s: "3 1 DATA 0 5 PRIO 2", wantErr: true},
+		{name: "data bad priority", s: "3 1 DATA 0 5 PRIO x hello", wantErr: true},
+		{name: "negative ID", s: "* 1 TC -2 7 MS ", wantErr: true},
+		{name: "ID over 32 bits", s: "* 1 TC 4294967296 7 MS ", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseMessage(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseMessage() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) && !tt.wantErr {
+				t.Errorf("ParseMessage() got = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func FuzzParseMessage(f *testing.F) {
+	for _, seed := range []string{
+		"* 0 HELLO UNIDIR 1 BIDIR 2 3 MPR 4",
+		"* 10.0.0.1 HELLO UNIDIR  BIDIR 1:0.50 2 MPR ",
+		"* 0 HELLO UNIDIR 1 BIDIR 2 MPR 4 LOST 9 10:x",
+		"* 1 TC 2 7 MS 3 4",
+		"* 1 TC 2 8 ADD 5 DEL 3 4",
+		"* 1 MID 2 3 IFACES 10.0.0.2",
+		"3 1 DATA 0 5 hello 5, from 0",
+		"3 1 DATA 0 5 payload:1:2:aGk=",
+		"3 1 DATA 0 5 PRIO 2 hello 5, from 0",
+	} {
+		f.Add(seed)
+	}
+	f.Fuzz(func(t *testing.T, s string) {
+		msg, err := ParseMessage(s)
+		if err != nil {
+			return
+		}
+		// Any successful parse must round-trip through the canonical String() format.
+		canonical := msg.(fmt.Stringer).String()
+		reparsed, err := ParseMessage(canonical)
+		if err != nil {
+			t.Fatalf("ParseMessage(%q) of String() of %q failed: %v", canonical, s, err)
+		}
+		if got := reparsed.(fmt.Stringer).String(); got != canonical {
+			t.Errorf("String() of reparsed message = %q, want %q", got, canonical)
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// metric is a single Prometheus metric family, labelled by node.
+type metric struct {
+	name       string
+	help       string
+	metricType string
+
+	// samples holds a value for each node and label set.
+	samples []metricSample
+}
+
+// metricSample is a single value of a metric.
+type metricSample struct {
+	node NodeID
+
+	// labels are any labels in addition to the node label, each prefixed by a comma.
+	labels string
+
+	value int
+}
+
+// WriteMetrics writes the current metrics of every node in the Prometheus text exposition format.
+// Each series is labelled by node ID.
+func (c *Controller) WriteMetrics(w io.Writer) error {
+	nodes := make([]*Node, len(c.nodes))
+	copy(nodes, c.nodes)
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].id < nodes[j].id
+	})
+
+	sent := metric{
+		name:       "olsrsim_messages_sent_total",
+		help:       "Messages sent by a node, by message type.",
+		metricType: "counter",
+	}
+	forwarded := metric{
+		name:       "olsrsim_messages_forwarded_total",
+		help:       "Messages forwarded by a node on behalf of other nodes, by message type.",
+		metricType: "counter",
+	}
+	delivered := metric{
+		name:       "olsrsim_data_delivered_total",
+		help:       "Data messages received by a node as the destination.",
+		metricType: "counter",
+	}
+	dropped := metric{
+		name:       "olsrsim_data_dropped_total",
+		help:       "Data messages dropped by a node due to having no route.",
+		metricType: "counter",
+	}
+	routes := metric{
+		name:       "olsrsim_routing_table_size",
+		help:       "Destinations within a node's routing table.",
+		metricType: "gauge",
+	}
+	neighbors := metric{
+		name:       "olsrsim_neighbors",
+		help:       "One-hop neighbors known by a node.",
+		metricType: "gauge",
+	}
+	converged := metric{
+		name:       "olsrsim_converged",
+		help:       "Whether a node has a route to every other node in the simulation.",
+		metricType: "gauge",
+	}
+
+	for _, n := range nodes {
+		n.mu.RLock()
+		counters := n.counters
+		routingTableSize := len(n.routingTable)
+		neighborCount := len(n.oneHopNeighbors)
+		hasAllRoutes := 1
+		for _, other := range nodes {
+			if _, in := n.routingTable[other.id]; other.id != n.id && !in {
+				hasAllRoutes = 0
+				break
+			}
+		}
+		n.mu.RUnlock()
+
+		sent.samples = append(sent.samples,
+			metricSample{node: n.id, labels: `,type="hello"`, value: counters.HelloSent},
+			metricSample{node: n.id, labels: `,type="tc"`, value: counters.TCSent},
+			metricSample{node: n.id, labels: `,type="data"`, value: counters.DataOriginated},
+		)
+		forwarded.samples = append(forwarded.samples,
+			metricSample{node: n.id, labels: `,type="tc"`, value: counters.TCForwarded},
+			metricSample{node: n.id, labels: `,type="data"`, value: counters.DataForwarded},
+		)
+		delivered.samples = append(delivered.samples, metricSample{node: n.id, value: counters.DataDelivered})
+		dropped.samples = append(dropped.samples, metricSample{node: n.id, value: counters.DataDropped})
+		routes.samples = append(routes.samples, metricSample{node: n.id, value: routingTableSize})
+		neighbors.samples = append(neighbors.samples, metricSample{node: n.id, value: neighborCount})
+		converged.samples = append(converged.samples, metricSample{node: n.id, value: hasAllRoutes})
+	}
+
+	bw := bufio.NewWriter(w)
+	for _, m := range []metric{sent, forwarded, delivered, dropped, routes, neighbors, converged} {
+		fmt.Fprintf(bw, "# HELP %s %s\n", m.name, m.help)
+		fmt.Fprintf(bw, "# TYPE %s %s\n", m.name, m.metricType)
+		for _, sample := range m.samples {
+			fmt.Fprintf(bw, "%s{node=\"%s\"%s} %d\n", m.name, sample.node, sample.labels, sample.value)
+		}
+	}
+	return bw.Flush()
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestController_WriteMetrics(t *testing.T) {
+	c := NewController(NetworkTypology{}, time.Millisecond)
+
+	n1 := newTestNode(1, &recordingTransmitter{})
+	n1.counters = MessageCounters{HelloSent: 4, TCSent: 2, TCForwarded: 3, DataOriginated: 1}
+	n1.oneHopNeighbors[0] = oneHopNeighborEntry{neighborID: 0, state: bidirectional, holdUntil: 15}
+	n1.calculateRoutingTable()
+
+	n0 := newTestNode(0, &recordingTransmitter{})
+	n0.counters = MessageCounters{HelloSent: 5, DataForwarded: 2, DataDelivered: 1, DataDropped: 1}
+	c.nodes = append(c.nodes, n1, n0)
+
+	want := `# HELP olsrsim_messages_sent_total Messages sent by a node, by message type.
+# TYPE olsrsim_messages_sent_total counter
+olsrsim_messages_sent_total{node="0",type="hello"} 5
+olsrsim_messages_sent_total{node="0",type="tc"} 0
+olsrsim_messages_sent_total{node="0",type="data"} 0
+olsrsim_messages_sent_total{node="1",type="hello"} 4
+olsrsim_messages_sent_total{node="1",type="tc"} 2
+olsrsim_messages_sent_total{node="1",type="data"} 1
+# HELP olsrsim_messages_forwarded_total Messages forwarded by a node on behalf of other nodes, by message type.
+# TYPE olsrsim_messages_forwarded_total counter
+olsrsim_messages_forwarded_total{node="0",type="tc"} 0
+olsrsim_messages_forwarded_total{node="0",type="data"} 2
+olsrsim_messages_forwarded_total{node="1",type="tc"} 3
+olsrsim_messages_forwarded_total{node="1",type="data"} 0
+# HELP olsrsim_data_delivered_total Data messages received by a node as the destination.
+# TYPE olsrsim_data_delivered_total counter
+olsrsim_data_delivered_total{node="0"} 1
+olsrsim_data_delivered_total{node="1"} 0
+# HELP olsrsim_data_dropped_total Data messages dropped by a node due to having no route.
+# TYPE olsrsim_data_dropped_total counter
+olsrsim_data_dropped_total{node="0"} 1
+olsrsim_data_dropped_total{node="1"} 0
+# HELP olsrsim_routing_table_size Destinations within a node's routing table.
+# TYPE olsrsim_routing_table_size gauge
+olsrsim_routing_table_size{node="0"} 0
+olsrsim_routing_table_size{node="1"} 1
+# HELP olsrsim_neighbors One-hop neighbors known by a node.
+# TYPE olsrsim_neighbors gauge
+olsrsim_neighbors{node="0"} 0
+olsrsim_neighbors{node="1"} 1
+# HELP olsrsim_converged Whether a node has a route to every other node in the simulation.
+# TYPE olsrsim_converged gauge
+olsrsim_converged{node="0"} 0
+olsrsim_converged{node="1"} 1
+`
+	var buf bytes.Buffer
+	if err := c.WriteMetrics(&buf); err != nil {
+		t.Fatalf("WriteMetrics() error = %v", err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("WriteMetrics() = \n%s\nwant\n%s", got, want)
+	}
+}
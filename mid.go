@@ -0,0 +1,134 @@
+package main
+
+import (
+	"log"
+)
+
+// interfaceAssociation is an entry within a Node's interface association table, associating an interface address
+// with the main address of the node it belongs to.
+type interfaceAssociation struct {
+	main      NodeID
+	holdUntil int
+}
+
+// isOwnAddress determines whether the address is the Node's main address or one of its interfaces.
+func (n *Node) isOwnAddress(addr NodeID) bool {
+	if addr == n.id {
+		return true
+	}
+	for _, iface := range n.interfaces {
+		if iface == addr {
+			return true
+		}
+	}
+	return false
+}
+
+// MainAddress returns the main address of the node the address belongs to, according to the MIDMessage(s) the Node
+// has received. An address not declared as an interface is its own main address.
+func (n *Node) MainAddress(addr NodeID) NodeID {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	if assoc, in := n.interfaceAssociations[addr]; in {
+		return assoc.main
+	}
+	return addr
+}
+
+// sendMID sends a MIDMessage declaring the Node's interfaces.
+func (n *Node) sendMID() {
+	n.midSequenceNum = (n.midSequenceNum + 1) % seqMax
+	n.transmitMID(&MIDMessage{
+		Source:       n.id,
+		FromNeighbor: n.id,
+		Sequence:     n.midSequenceNum,
+		Interfaces:   append([]NodeID(nil), n.interfaces...),
+	})
+}
+
+// transmitMID sends a MIDMessage and logs it to the output log.
+func (n *Node) transmitMID(msg *MIDMessage) {
+	if msg.Source == n.id {
+		n.counters.MIDSent++
+	} else {
+		n.counters.MIDForwarded++
+	}
+	n.counters.MIDBytes += len(msg.String())
+	n.output.Send(msg)
+
+	log.Printf("node %s: Sent:\t%s", n.id, msg)
+	err := n.writeLog(n.outputLog, "out", msg, msg.String())
+	if err != nil {
+		log.Panicf("node %s: unable to log mid Message to output: %s", n.id, err)
+	}
+}
+
+// handleMID records the interfaces declared by a MIDMessage in the interface association table, replacing those
+// previously declared by its originator, then forwards it if this Node is an MPR of the neighbor it was received
+// from, as with a TCMessage. A MIDMessage which is not newer than the last one from its originator is a duplicate,
+// received over another path, and is discarded.
+func (n *Node) handleMID(msg *MIDMessage) {
+	if msg.Source == n.id {
+		return
+	}
+	if !n.isKnownNeighbor(msg.FromNeighbor, msg) {
+		return
+	}
+	if seq, in := n.midSequences[msg.Source]; in && !seqNewer(msg.Sequence, seq) {
+		return
+	}
+	n.midSequences[msg.Source] = msg.Sequence
+
+	for iface, assoc := range n.interfaceAssociations {
+		if assoc.main == msg.Source {
+			delete(n.interfaceAssociations, iface)
+		}
+	}
+	for _, iface := range msg.Interfaces {
+		if iface == msg.Source || n.isOwnAddress(iface) {
+			continue
+		}
+		n.interfaceAssociations[iface] = interfaceAssociation{main: msg.Source, holdUntil: n.currentTick + n.topologyHoldTime}
+	}
+	n.routesChanged = true
+
+	if _, in := n.msSet[msg.FromNeighbor]; !in {
+		return
+	}
+	// Update the from-neighbor field on a copy, as the received message may be shared with other nodes.
+	fwd := *msg
+	fwd.FromNeighbor = n.id
+	n.transmitMID(&fwd)
+}
+
+// expireInterfaceAssociations removes the interface associations which have not been refreshed by a MIDMessage within
+// the topology hold time.
+func (n *Node) expireInterfaceAssociations() {
+	for iface, assoc := range n.interfaceAssociations {
+		if assoc.holdUntil <= n.currentTick {
+			delete(n.interfaceAssociations, iface)
+			n.routesChanged = true
+		}
+	}
+}
+
+// addInterfaceRoutes adds a route to each associated interface address, through the route to its node's main
+// address, per RFC 3626 section 10. An interface address which is already routed directly keeps its route.
+func (n *Node) addInterfaceRoutes() {
+	for _, iface := range sortedNodeIDs(n.interfaceAssociations) {
+		if _, in := n.routingTable[iface]; in {
+			continue
+		}
+		route, in := n.routingTable[n.interfaceAssociations[iface].main]
+		if !in {
+			continue
+		}
+		n.routingTable[iface] = routingEntry{
+			dst:      iface,
+			nextHop:  route.nextHop,
+			nextHops: append([]NodeID(nil), route.nextHops...),
+			distance: route.distance,
+		}
+	}
+}
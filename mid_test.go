@@ -0,0 +1,186 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNode_sendMID(t *testing.T) {
+	tests := []struct {
+		name       string
+		interfaces []NodeID
+		interval   Ticks
+		want       []int
+	}{
+		{name: "no interfaces", interfaces: nil, interval: 4, want: []int{}},
+		{name: "interfaces", interfaces: []NodeID{6, 7}, interval: 4, want: []int{0, 4, 8}},
+		{name: "interval below a tick", interfaces: []NodeID{6}, interval: 0, want: []int{0, 1, 2, 3, 4, 5, 6, 7, 8, 9}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &recordingTransmitter{}
+			n := newTestNode(0, out)
+			n.interfaces = tt.interfaces
+			n.SetMIDInterval(tt.interval)
+
+			got := make([]int, 0)
+			seq := 0
+			for tick := 0; tick < 10; tick++ {
+				out.sent = nil
+				n.tick(nil)
+				for _, msg := range out.sent {
+					mid, ok := msg.(*MIDMessage)
+					if !ok {
+						continue
+					}
+					got = append(got, tick)
+					if mid.Sequence <= seq || !reflect.DeepEqual(mid.Interfaces, tt.interfaces) {
+						t.Errorf("tick %d: sent %s after sequence %d", tick, mid, seq)
+					}
+					seq = mid.Sequence
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("MIDMessage(s) sent at ticks %v, want %v", got, tt.want)
+			}
+			if c := n.Counters(); c.MIDSent != len(tt.want) {
+				t.Errorf("MIDSent = %d, want %d", c.MIDSent, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestNode_handleMID(t *testing.T) {
+	tests := []struct {
+		name string
+		// selector makes neighbor 1 select the node as an mpr.
+		selector bool
+		msgs     []*MIDMessage
+		// ticks is the number of ticks to run after the messages are received.
+		ticks         int
+		wantMain      map[NodeID]NodeID
+		wantForwarded int
+		wantRoutes    []RoutingEntry
+	}{
+		{
+			name:          "associated",
+			msgs:          []*MIDMessage{{Source: 2, FromNeighbor: 1, Sequence: 1, Interfaces: []NodeID{8, 9}}},
+			wantMain:      map[NodeID]NodeID{8: 2, 9: 2, 2: 2},
+			wantForwarded: 0,
+			wantRoutes: []RoutingEntry{
+				{Destination: 1, NextHop: 1, Distance: 1},
+				{Destination: 2, NextHop: 1, Distance: 2},
+				{Destination: 8, NextHop: 1, Distance: 2},
+				{Destination: 9, NextHop: 1, Distance: 2},
+			},
+		},
+		{
+			name:     "forwarded for an mpr selector, once",
+			selector: true,
+			msgs: []*MIDMessage{
+				{Source: 2, FromNeighbor: 1, Sequence: 1, Interfaces: []NodeID{8}},
+				{Source: 2, FromNeighbor: 1, Sequence: 1, Interfaces: []NodeID{8}},
+			},
+			wantMain:      map[NodeID]NodeID{8: 2},
+			wantForwarded: 1,
+		},
+		{
+			name: "replaced by a newer declaration",
+			msgs: []*MIDMessage{
+				{Source: 2, FromNeighbor: 1, Sequence: 1, Interfaces: []NodeID{8, 9}},
+				{Source: 2, FromNeighbor: 1, Sequence: 2, Interfaces: []NodeID{9}},
+			},
+			wantMain: map[NodeID]NodeID{8: 8, 9: 2},
+		},
+		{
+			name: "stale declaration ignored",
+			msgs: []*MIDMessage{
+				{Source: 2, FromNeighbor: 1, Sequence: 2, Interfaces: []NodeID{9}},
+				{Source: 2, FromNeighbor: 1, Sequence: 1, Interfaces: []NodeID{8}},
+			},
+			wantMain: map[NodeID]NodeID{8: 8, 9: 2},
+		},
+		{
+			name:     "own address not associated",
+			msgs:     []*MIDMessage{{Source: 2, FromNeighbor: 1, Sequence: 1, Interfaces: []NodeID{0, 5}}},
+			wantMain: map[NodeID]NodeID{0: 0, 5: 5},
+		},
+		{
+			name:       "expired",
+			msgs:       []*MIDMessage{{Source: 2, FromNeighbor: 1, Sequence: 1, Interfaces: []NodeID{8}}},
+			ticks:      4,
+			wantMain:   map[NodeID]NodeID{8: 8},
+			wantRoutes: []RoutingEntry{{Destination: 1, NextHop: 1, Distance: 1}, {Destination: 2, NextHop: 1, Distance: 2}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &recordingTransmitter{}
+			n := newTestNode(0, out)
+			n.interfaces = []NodeID{5}
+			n.SetTopologyHoldTime(3)
+			n.SeedNeighbors([]OneHopNeighborEntry{{ID: 1, State: bidirectional}}, map[NodeID][]NodeID{1: {2}})
+			if tt.selector {
+				n.msSet[1] = 1
+			}
+
+			msgs := make([]interface{}, 0, len(tt.msgs))
+			for _, msg := range tt.msgs {
+				msgs = append(msgs, msg)
+			}
+			n.tick(msgs)
+			for i := 0; i < tt.ticks; i++ {
+				n.tick(nil)
+			}
+
+			for addr, want := range tt.wantMain {
+				if got := n.MainAddress(addr); got != want {
+					t.Errorf("MainAddress(%s) = %s, want %s", addr, got, want)
+				}
+			}
+			if got := n.Counters().MIDForwarded; got != tt.wantForwarded {
+				t.Errorf("MIDForwarded = %d, want %d", got, tt.wantForwarded)
+			}
+			if tt.wantRoutes != nil {
+				if got := n.Snapshot().RoutingTable; !reflect.DeepEqual(got, tt.wantRoutes) {
+					t.Errorf("RoutingTable = %v, want %v", got, tt.wantRoutes)
+				}
+			}
+		})
+	}
+}
+
+func TestController_MIDDataToInterface(t *testing.T) {
+	// Node 5 is reachable from node 0 only through node 1. Node 0 addresses its message to node 5's interface, 6,
+	// which it can only route after node 5's MIDMessage(s) reach it.
+	topology, err := NewNetworkTypology(strings.NewReader("0 UP 0 1\n0 UP 1 0\n0 UP 1 5\n0 UP 5 1\n"))
+	if err != nil {
+		t.Fatalf("NewNetworkTypology() error = %v", err)
+	}
+	c := NewController(*topology, time.Hour)
+	c.SetLogDir(t.TempDir())
+	err = c.Initialize([]NodeConfig{
+		{ID: 0, Message: NodeMessage{Message: "to an interface", Delay: 30, Destination: 6}},
+		{ID: 1, Message: NodeMessage{Sent: true}},
+		{ID: 5, Message: NodeMessage{Sent: true}, Interfaces: []NodeID{6}},
+	})
+	if err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	c.SetSynchronous(true)
+	c.Start(40)
+
+	n0, _ := c.node(0)
+	if got := n0.MainAddress(6); got != 5 {
+		t.Errorf("node 0 MainAddress(6) = %s, want 5", got)
+	}
+	n5, _ := c.node(5)
+	if got := n5.Counters().DataDelivered; got != 1 {
+		t.Errorf("node 5 DataDelivered = %d, want 1", got)
+	}
+	if s := c.Stats(); s.MIDSent == 0 || s.MIDForwarded == 0 {
+		t.Errorf("Stats() MIDSent = %d, MIDForwarded = %d, want both non-zero", s.MIDSent, s.MIDForwarded)
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"math/bits"
+	"strings"
+)
+
+// MPRSelector selects the multipoint relays (MPRs) of a Node from its neighbor tables.
+type MPRSelector interface {
+	// SelectMPRs determines the set of one-hop neighbors to use as MPRs, such that they cover as many two-hop
+	// neighbors as possible.
+	SelectMPRs(oneHopNeighbors map[NodeID]oneHopNeighborEntry, twoHopNeighbors map[NodeID]map[NodeID]NodeID) map[NodeID]NodeID
+}
+
+// mprCandidates determines the one-hop neighbors which may be selected as MPRs, in NodeID order. Selectors only
+// try to cover the two-hop neighbors reached by a candidate, so those only reachable via unidirectional neighbors are
+// left uncovered, rather than exhausting the candidates.
+func mprCandidates(oneHopNeighbors map[NodeID]oneHopNeighborEntry, twoHopNeighbors map[NodeID]map[NodeID]NodeID) []NodeID {
+	candidates := make([]NodeID, 0, len(twoHopNeighbors))
+	for _, neighbor := range sortedNodeIDs(twoHopNeighbors) {
+		// Only consider nodes as MPRs if they are bidirectional.
+		ohn, _ := oneHopNeighbors[neighbor]
+		if ohn.state == unidirectional {
+			continue
+		}
+		candidates = append(candidates, neighbor)
+	}
+	return candidates
+}
+
+// verifyMPRCoverage checks that every two-hop neighbor reachable via a bidirectional one-hop neighbor is covered by at
+// least one of the selected MPRs, and that every MPR is such a neighbor.
+func verifyMPRCoverage(oneHopNeighbors map[NodeID]oneHopNeighborEntry, twoHopNeighbors map[NodeID]map[NodeID]NodeID, mprs map[NodeID]NodeID) error {
+	covered := make(map[NodeID]bool)
+	for _, id := range sortedNodeIDs(mprs) {
+		if ohn, in := oneHopNeighbors[id]; !in || ohn.state == unidirectional {
+			return fmt.Errorf("mpr %s is not a bidirectional one-hop neighbor", id)
+		}
+		for k := range twoHopNeighbors[id] {
+			covered[k] = true
+		}
+	}
+	for _, id := range mprCandidates(oneHopNeighbors, twoHopNeighbors) {
+		for _, k := range sortedNodeIDs(twoHopNeighbors[id]) {
+			if !covered[k] {
+				return fmt.Errorf("two-hop neighbor %s is reachable via %s but not covered by any mpr", k, id)
+			}
+		}
+	}
+	return nil
+}
+
+// verifyAdvertisedMPRs checks that the one-hop neighbors in the mpr state, which a HelloMessage advertises as MPRs,
+// are exactly the selected MPRs.
+func verifyAdvertisedMPRs(oneHopNeighbors map[NodeID]oneHopNeighborEntry, mprs map[NodeID]NodeID) error {
+	for _, id := range sortedNodeIDs(oneHopNeighbors) {
+		if _, selected := mprs[id]; oneHopNeighbors[id].state == mpr && !selected {
+			return fmt.Errorf("neighbor %s is in the mpr state but was not selected as an mpr", id)
+		}
+	}
+	for _, id := range sortedNodeIDs(mprs) {
+		if ohn, in := oneHopNeighbors[id]; !in || ohn.state != mpr {
+			return fmt.Errorf("mpr %s was selected but is not a neighbor in the mpr state", id)
+		}
+	}
+	return nil
+}
+
+// mapMPRSelector is the default MPRSelector. It greedily selects the candidate covering the most uncovered two-hop
+// neighbors, with ties broken by the lowest NodeID, until all two-hop neighbors are covered.
+// Each candidate's number of uncovered two-hop neighbors is kept up to date as two-hop neighbors are covered, so each
+// selection is a single pass over the candidates rather than a re-sort. With 50 neighbors and 500 two-hop neighbors,
+// this is roughly 4x faster than recounting and re-sorting the candidates for each selection (see
+// BenchmarkMPRSelector).
+type mapMPRSelector struct{}
+
+func (mapMPRSelector) SelectMPRs(oneHopNeighbors map[NodeID]oneHopNeighborEntry, twoHopNeighbors map[NodeID]map[NodeID]NodeID) map[NodeID]NodeID {
+	candidates := mprCandidates(oneHopNeighbors, twoHopNeighbors)
+
+	// reachedBy maps each uncovered two-hop neighbor to the indices of the candidates which reach it.
+	reachedBy := make(map[NodeID][]int)
+	uncovered := make([]int, len(candidates))
+	for i, id := range candidates {
+		for k := range twoHopNeighbors[id] {
+			reachedBy[k] = append(reachedBy[k], i)
+			uncovered[i]++
+		}
+	}
+
+	// Set of MPRs
+	mprs := make(map[NodeID]NodeID)
+
+	// Every uncovered two-hop neighbor is reached by a candidate, so one is always found.
+	for len(reachedBy) > 0 {
+		best := 0
+		for i, count := range uncovered {
+			if count > uncovered[best] {
+				best = i
+			}
+		}
+		id := candidates[best]
+		mprs[id] = id
+
+		for k := range twoHopNeighbors[id] {
+			for _, i := range reachedBy[k] {
+				uncovered[i]--
+			}
+			delete(reachedBy, k)
+		}
+	}
+	return mprs
+}
+
+// bitset is a dense set of compact indices.
+type bitset []uint64
+
+// set adds i to the set, growing it as needed.
+func (b bitset) set(i int) bitset {
+	for len(b) <= i/64 {
+		b = append(b, 0)
+	}
+	b[i/64] |= 1 << (i % 64)
+	return b
+}
+
+// or adds every member of o to b, which must be at least as long as o.
+func (b bitset) or(o bitset) {
+	for i := range o {
+		b[i] |= o[i]
+	}
+}
+
+// andNot removes every member of o from b.
+func (b bitset) andNot(o bitset) {
+	for i := 0; i < len(b) && i < len(o); i++ {
+		b[i] &^= o[i]
+	}
+}
+
+// andCount determines the number of members of b which are also members of o.
+func (b bitset) andCount(o bitset) int {
+	c := 0
+	for i := 0; i < len(b) && i < len(o); i++ {
+		c += bits.OnesCount64(b[i] & o[i])
+	}
+	return c
+}
+
+func (b bitset) empty() bool {
+	for _, w := range b {
+		if w != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// bitsetMPRSelector is an MPRSelector for large networks, which assigns each two-hop neighbor a compact index and
+// represents the two-hop neighbors reached by each candidate as a bitset. It selects the same MPRs as mapMPRSelector.
+// Selection itself is a series of bitwise operations, so the cost is dominated by reading the map-based neighbor
+// tables: in a dense 200-node graph it is roughly 2x faster than mapMPRSelector (see BenchmarkMPRSelector).
+type bitsetMPRSelector struct{}
+
+func (bitsetMPRSelector) SelectMPRs(oneHopNeighbors map[NodeID]oneHopNeighborEntry, twoHopNeighbors map[NodeID]map[NodeID]NodeID) map[NodeID]NodeID {
+	candidates := mprCandidates(oneHopNeighbors, twoHopNeighbors)
+
+	// Assign each two-hop neighbor an index while recording the two-hop neighbors each candidate reaches.
+	index := make(map[NodeID]int)
+	reaches := make([]bitset, len(candidates))
+	for i, id := range candidates {
+		for k := range twoHopNeighbors[id] {
+			idx, in := index[k]
+			if !in {
+				idx = len(index)
+				index[k] = idx
+			}
+			reaches[i] = reaches[i].set(idx)
+		}
+	}
+
+	remainingTwoHops := make(bitset, (len(index)+63)/64)
+	for _, reach := range reaches {
+		remainingTwoHops.or(reach)
+	}
+
+	mprs := make(map[NodeID]NodeID)
+	for !remainingTwoHops.empty() {
+		best, bestCount := 0, 0
+		for i, reach := range reaches {
+			if count := reach.andCount(remainingTwoHops); count > bestCount {
+				best, bestCount = i, count
+			}
+		}
+		mprs[candidates[best]] = candidates[best]
+		remainingTwoHops.andNot(reaches[best])
+	}
+	return mprs
+}
+
+// ExplainMPR describes, from the Node's current neighbor tables, why the one-hop neighbor was or was not selected as
+// an MPR: the two-hop neighbors it covers, which of those no other eligible neighbor covers, and, if it was not
+// selected, the MPRs covering its two-hop neighbors instead.
+func (n *Node) ExplainMPR(id NodeID) string {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	entry, in := n.oneHopNeighbors[id]
+	if !in {
+		return fmt.Sprintf("%s is not a one-hop neighbor of %s", id, n.id)
+	}
+	var b strings.Builder
+	switch entry.state {
+	case mpr:
+		fmt.Fprintf(&b, "%s is an MPR of %s", id, n.id)
+	case unidirectional:
+		fmt.Fprintf(&b, "%s is not an MPR of %s: the link is not bidirectional", id, n.id)
+	default:
+		fmt.Fprintf(&b, "%s is not an MPR of %s", id, n.id)
+	}
+
+	twoHops := sortedNodeIDs(n.twoHopNeighbors[id])
+	fmt.Fprintf(&b, "\n  covers %d two-hop neighbors: %s", len(twoHops), explainNodeIDs(twoHops))
+
+	// A two-hop neighbor is uniquely covered if no other eligible neighbor covers it, so the neighbor must be selected.
+	candidates := mprCandidates(n.oneHopNeighbors, n.twoHopNeighbors)
+	unique := make([]NodeID, 0)
+	for _, k := range twoHops {
+		coveredByOther := false
+		for _, other := range candidates {
+			if _, reaches := n.twoHopNeighbors[other][k]; reaches && other != id {
+				coveredByOther = true
+				break
+			}
+		}
+		if !coveredByOther {
+			unique = append(unique, k)
+		}
+	}
+	fmt.Fprintf(&b, "\n  uniquely covers: %s", explainNodeIDs(unique))
+
+	if entry.state == mpr {
+		return b.String()
+	}
+	for _, k := range twoHops {
+		coveredBy := make([]NodeID, 0)
+		for _, other := range sortedNodeIDs(n.twoHopNeighbors) {
+			if _, reaches := n.twoHopNeighbors[other][k]; reaches && n.oneHopNeighbors[other].state == mpr {
+				coveredBy = append(coveredBy, other)
+			}
+		}
+		if len(coveredBy) == 0 {
+			fmt.Fprintf(&b, "\n  %s is not covered by any MPR", k)
+			continue
+		}
+		fmt.Fprintf(&b, "\n  %s is covered by MPR %s", k, separatedString(coveredBy, ", "))
+	}
+	return b.String()
+}
+
+// explainNodeIDs formats the NodeID(s) for ExplainMPR.
+func explainNodeIDs(ids []NodeID) string {
+	if len(ids) == 0 {
+		return "none"
+	}
+	return separatedString(ids, ", ")
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"reflect"
+	"testing"
+)
+
+// randomNeighborTables creates neighbor tables with the given number of one-hop neighbors, each reaching every
+// two-hop neighbor with the given probability. Roughly one in ten one-hop neighbors is unidirectional.
+func randomNeighborTables(r *rand.Rand, oneHops, twoHops int, p float64) (map[NodeID]oneHopNeighborEntry, map[NodeID]map[NodeID]NodeID) {
+	oneHopNeighbors := make(map[NodeID]oneHopNeighborEntry)
+	twoHopNeighbors := make(map[NodeID]map[NodeID]NodeID)
+	for i := 1; i <= oneHops; i++ {
+		id := NodeID(i)
+		state := bidirectional
+		if r.Intn(10) == 0 {
+			state = unidirectional
+		}
+		oneHopNeighbors[id] = oneHopNeighborEntry{neighborID: id, state: state}
+		twoHopNeighbors[id] = make(map[NodeID]NodeID)
+		for j := 1; j <= twoHops; j++ {
+			if r.Float64() < p {
+				twoHop := NodeID(oneHops + j)
+				twoHopNeighbors[id][twoHop] = twoHop
+			}
+		}
+	}
+	return oneHopNeighbors, twoHopNeighbors
+}
+
+func TestMPRSelector(t *testing.T) {
+	selectors := map[string]MPRSelector{
+		"map":    mapMPRSelector{},
+		"bitset": bitsetMPRSelector{},
+	}
+	tests := []struct {
+		name            string
+		oneHopNeighbors map[NodeID]oneHopNeighborEntry
+		twoHopNeighbors map[NodeID]map[NodeID]NodeID
+		want            map[NodeID]NodeID
+	}{
+		{
+			name:            "no neighbors",
+			oneHopNeighbors: map[NodeID]oneHopNeighborEntry{},
+			twoHopNeighbors: map[NodeID]map[NodeID]NodeID{},
+			want:            map[NodeID]NodeID{},
+		},
+		{
+			name: "one neighbor covers all",
+			oneHopNeighbors: map[NodeID]oneHopNeighborEntry{
+				1: {neighborID: 1, state: bidirectional},
+				2: {neighborID: 2, state: bidirectional},
+			},
+			twoHopNeighbors: map[NodeID]map[NodeID]NodeID{
+				1: {3: 3, 4: 4},
+				2: {3: 3},
+			},
+			want: map[NodeID]NodeID{1: 1},
+		},
+		{
+			name: "no redundant mprs",
+			oneHopNeighbors: map[NodeID]oneHopNeighborEntry{
+				1: {neighborID: 1, state: bidirectional},
+				2: {neighborID: 2, state: bidirectional},
+				3: {neighborID: 3, state: bidirectional},
+			},
+			twoHopNeighbors: map[NodeID]map[NodeID]NodeID{
+				1: {10: 10, 11: 11, 12: 12},
+				2: {10: 10, 11: 11},
+				3: {13: 13},
+			},
+			want: map[NodeID]NodeID{1: 1, 3: 3},
+		},
+		{
+			name: "ties broken by lowest id",
+			oneHopNeighbors: map[NodeID]oneHopNeighborEntry{
+				1: {neighborID: 1, state: bidirectional},
+				2: {neighborID: 2, state: bidirectional},
+			},
+			twoHopNeighbors: map[NodeID]map[NodeID]NodeID{
+				1: {3: 3},
+				2: {3: 3},
+			},
+			want: map[NodeID]NodeID{1: 1},
+		},
+		{
+			name: "only uncoverable two-hop neighbors",
+			oneHopNeighbors: map[NodeID]oneHopNeighborEntry{
+				1: {neighborID: 1, state: unidirectional},
+			},
+			twoHopNeighbors: map[NodeID]map[NodeID]NodeID{
+				1: {3: 3},
+			},
+			want: map[NodeID]NodeID{},
+		},
+		{
+			name: "two-hop neighbor only via unidirectional neighbor left uncovered",
+			oneHopNeighbors: map[NodeID]oneHopNeighborEntry{
+				1: {neighborID: 1, state: unidirectional},
+				2: {neighborID: 2, state: bidirectional},
+			},
+			twoHopNeighbors: map[NodeID]map[NodeID]NodeID{
+				1: {3: 3, 4: 4},
+				2: {3: 3},
+			},
+			want: map[NodeID]NodeID{2: 2},
+		},
+	}
+	for _, tt := range tests {
+		for name, selector := range selectors {
+			t.Run(fmt.Sprintf("%s/%s", tt.name, name), func(t *testing.T) {
+				if got := selector.SelectMPRs(tt.oneHopNeighbors, tt.twoHopNeighbors); !reflect.DeepEqual(got, tt.want) {
+					t.Errorf("SelectMPRs() = %v, want %v", got, tt.want)
+				}
+			})
+		}
+	}
+}
+
+func TestBitsetMPRSelector_matchesMap(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		oneHopNeighbors, twoHopNeighbors := randomNeighborTables(r, 1+r.Intn(20), 1+r.Intn(100), r.Float64()/2)
+		want := mapMPRSelector{}.SelectMPRs(oneHopNeighbors, twoHopNeighbors)
+		if got := (bitsetMPRSelector{}).SelectMPRs(oneHopNeighbors, twoHopNeighbors); !reflect.DeepEqual(got, want) {
+			t.Fatalf("bitsetMPRSelector.SelectMPRs() = %v, want %v", got, want)
+		}
+	}
+}
+
+// BenchmarkMPRSelector measures MPR selection by a node with 50 one-hop neighbors each reaching a third of the
+// two-hop neighbors, in a dense 200-node graph and in a graph with 500 two-hop neighbors.
+func BenchmarkMPRSelector(b *testing.B) {
+	for _, twoHops := range []int{149, 500} {
+		r := rand.New(rand.NewSource(1))
+		oneHopNeighbors, twoHopNeighbors := randomNeighborTables(r, 50, twoHops, 1.0/3)
+		for _, name := range []string{"map", "bitset"} {
+			selector := map[string]MPRSelector{"map": mapMPRSelector{}, "bitset": bitsetMPRSelector{}}[name]
+			b.Run(fmt.Sprintf("%s/twoHops=%d", name, twoHops), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					selector.SelectMPRs(oneHopNeighbors, twoHopNeighbors)
+				}
+			})
+		}
+	}
+}
+
+func TestNode_ExplainMPR(t *testing.T) {
+	n := newTestNode(0, &recordingTransmitter{})
+	n.oneHopNeighbors = map[NodeID]oneHopNeighborEntry{
+		1: {neighborID: 1, state: bidirectional},
+		2: {neighborID: 2, state: bidirectional},
+		3: {neighborID: 3, state: unidirectional},
+	}
+	n.twoHopNeighbors = map[NodeID]map[NodeID]NodeID{
+		1: {10: 10, 11: 11},
+		2: {11: 11},
+		3: {12: 12},
+	}
+	n.selectMPRs()
+
+	tests := []struct {
+		name string
+		id   NodeID
+		want string
+	}{
+		{
+			name: "mpr",
+			id:   1,
+			want: "1 is an MPR of 0\n  covers 2 two-hop neighbors: 10, 11\n  uniquely covers: 10",
+		},
+		{
+			name: "covered by another mpr",
+			id:   2,
+			want: "2 is not an MPR of 0\n  covers 1 two-hop neighbors: 11\n  uniquely covers: none\n  11 is covered by MPR 1",
+		},
+		{
+			name: "unidirectional",
+			id:   3,
+			want: "3 is not an MPR of 0: the link is not bidirectional\n  covers 1 two-hop neighbors: 12\n  uniquely covers: 12\n  12 is not covered by any MPR",
+		},
+		{
+			name: "not a neighbor",
+			id:   4,
+			want: "4 is not a one-hop neighbor of 0",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := n.ExplainMPR(tt.id); got != tt.want {
+				t.Errorf("ExplainMPR() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_verifyMPRCoverage(t *testing.T) {
+	oneHops := map[NodeID]oneHopNeighborEntry{
+		1: {neighborID: 1, state: bidirectional},
+		2: {neighborID: 2, state: bidirectional},
+		3: {neighborID: 3, state: unidirectional},
+	}
+	twoHops := map[NodeID]map[NodeID]NodeID{
+		1: {10: 10, 11: 11},
+		2: {11: 11, 12: 12},
+		3: {13: 13},
+	}
+	tests := []struct {
+		name    string
+		mprs    map[NodeID]NodeID
+		wantErr bool
+	}{
+		{name: "complete", mprs: map[NodeID]NodeID{1: 1, 2: 2}, wantErr: false},
+		{name: "uncovered two-hop neighbor", mprs: map[NodeID]NodeID{1: 1}, wantErr: true},
+		{name: "unidirectional mpr", mprs: map[NodeID]NodeID{1: 1, 2: 2, 3: 3}, wantErr: true},
+		{name: "unknown mpr", mprs: map[NodeID]NodeID{1: 1, 2: 2, 4: 4}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verifyMPRCoverage(oneHops, twoHops, tt.mprs); (err != nil) != tt.wantErr {
+				t.Errorf("verifyMPRCoverage() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+	for _, selector := range []MPRSelector{mapMPRSelector{}, bitsetMPRSelector{}} {
+		if err := verifyMPRCoverage(oneHops, twoHops, selector.SelectMPRs(oneHops, twoHops)); err != nil {
+			t.Errorf("%T: verifyMPRCoverage() error = %v", selector, err)
+		}
+	}
+}
+
+// noMPRSelector never selects any MPRs.
+type noMPRSelector struct{}
+
+func (noMPRSelector) SelectMPRs(map[NodeID]oneHopNeighborEntry, map[NodeID]map[NodeID]NodeID) map[NodeID]NodeID {
+	return map[NodeID]NodeID{}
+}
+
+func TestNode_strictMPRCoverage(t *testing.T) {
+	n := newTestNode(0, &recordingTransmitter{})
+	n.SetMPRSelector(noMPRSelector{})
+	n.oneHopNeighbors[1] = oneHopNeighborEntry{neighborID: 1, state: bidirectional}
+	n.SetStrict(true)
+	defer func() {
+		if recover() == nil {
+			t.Errorf("handleHello() did not panic in strict mode")
+		}
+	}()
+	n.handleHello(&HelloMessage{Source: 1, Unidirectional: []NodeID{0}, Bidirectional: []NodeID{2}})
+}
+
+func TestNode_SetMPRSelector(t *testing.T) {
+	n := newTestNode(0, &recordingTransmitter{})
+	n.oneHopNeighbors[1] = oneHopNeighborEntry{neighborID: 1, state: bidirectional}
+	n.twoHopNeighbors[1] = map[NodeID]NodeID{2: 2}
+	n.selectMPRs()
+	if got, want := sortedNodeIDs(n.mprs), []NodeID{1}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("mprs = %v, want %v", got, want)
+	}
+
+	n.SetMPRSelector(noMPRSelector{})
+	if got := sortedNodeIDs(n.mprs); len(got) != 0 {
+		t.Errorf("mprs after SetMPRSelector(noMPRSelector{}) = %v, want none", got)
+	}
+	if got := n.oneHopNeighbors[1].state; got != bidirectional {
+		t.Errorf("neighbor 1 state after SetMPRSelector(noMPRSelector{}) = %v, want %v", got, bidirectional)
+	}
+
+	n.SetMPRSelector(bitsetMPRSelector{})
+	if got, want := sortedNodeIDs(n.mprs), []NodeID{1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("mprs after SetMPRSelector(bitsetMPRSelector{}) = %v, want %v", got, want)
+	}
+}
+
+func TestNode_strictAdvertisedMPRs(t *testing.T) {
+	tests := []struct {
+		name      string
+		diverge   func(n *Node)
+		wantPanic bool
+	}{
+		{name: "consistent", diverge: func(n *Node) {}},
+		{
+			name: "mpr demoted without reselection",
+			diverge: func(n *Node) {
+				entry := n.oneHopNeighbors[1]
+				entry.state = bidirectional
+				n.oneHopNeighbors[1] = entry
+			},
+			wantPanic: true,
+		},
+		{
+			name: "neighbor promoted without selection",
+			diverge: func(n *Node) {
+				n.oneHopNeighbors[3] = oneHopNeighborEntry{neighborID: 3, state: mpr, holdUntil: 100}
+			},
+			wantPanic: true,
+		},
+		{
+			// Expelling an mpr removes it from both, so the HELLO sent after it expires is consistent.
+			name: "mpr expired",
+			diverge: func(n *Node) {
+				n.currentTick = 20
+				n.tick(nil)
+				if _, in := n.oneHopNeighbors[1]; in {
+					t.Fatalf("neighbor 1 was not expelled")
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := newTestNode(0, &recordingTransmitter{})
+			n.SetStrict(true)
+			// Node 1 is a bidirectional neighbor, and the only one covering two-hop neighbor 2.
+			n.handleHello(&HelloMessage{Source: 1, Bidirectional: []NodeID{2}, Sequence: 0})
+			n.handleHello(&HelloMessage{Source: 1, Bidirectional: []NodeID{0, 2}, Sequence: 1})
+			if _, in := n.mprs[1]; !in {
+				t.Fatalf("mprs = %v, want 1 selected", n.mprs)
+			}
+			tt.diverge(n)
+
+			defer func() {
+				if got := recover() != nil; got != tt.wantPanic {
+					t.Errorf("tick() panicked = %v, want %v", got, tt.wantPanic)
+				}
+			}()
+			n.TriggerHello()
+			n.tick(nil)
+		})
+	}
+}
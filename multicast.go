@@ -0,0 +1,149 @@
+package main
+
+import (
+	"log"
+)
+
+// Multicast group addresses are the dotted-quad addresses 224.0.0.0 to 239.255.255.255, as in IPv4. A DataMessage
+// addressed to a group is delivered to every node which joined the group, other than its source.
+const (
+	multicastFirst NodeID = 0xE0000000
+	multicastLast  NodeID = 0xEFFFFFFF
+)
+
+// IsMulticast determines whether the NodeID is a multicast group address.
+func IsMulticast(id NodeID) bool {
+	return id >= multicastFirst && id <= multicastLast
+}
+
+// multicastGroups builds the sorted membership of each multicast group joined by the nodes.
+func multicastGroups(configs []NodeConfig) map[NodeID][]NodeID {
+	groups := make(map[NodeID][]NodeID)
+	for _, config := range configs {
+		for _, group := range config.Groups {
+			groups[group] = append(groups[group], config.ID)
+		}
+	}
+	for _, members := range groups {
+		sortNodeIDs(members)
+	}
+	return groups
+}
+
+// multicastReceivers determines the members of the group a DataMessage from the source is delivered to.
+func multicastReceivers(groups map[NodeID][]NodeID, src, group NodeID) []NodeID {
+	receivers := make([]NodeID, 0, len(groups[group]))
+	for _, member := range groups[group] {
+		if member != src {
+			receivers = append(receivers, member)
+		}
+	}
+	return receivers
+}
+
+// dataReceivers is the number of deliveries a DataMessage resolves into: one for a unicast message, or one for each
+// receiver carried by a multicast message.
+func dataReceivers(msg *DataMessage) int {
+	if IsMulticast(msg.Destination) {
+		return len(msg.Receivers)
+	}
+	return 1
+}
+
+// isGroupMember determines whether the Node joined the multicast group.
+func (n *Node) isGroupMember(group NodeID) bool {
+	for _, g := range n.groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+// sendMulticast sends a copy of a multicast DataMessage to each next hop on the routes to its receivers, with each
+// copy carrying the receivers reached through that next hop. A message originated by this Node is addressed to every
+// other member of the group. Receivers without a route are dropped, unless no receiver has a route, in which case
+// nothing is sent and false is returned.
+func (n *Node) sendMulticast(msg *DataMessage) bool {
+	if msg.Source == n.id && msg.Receivers == nil {
+		msg.Receivers = multicastReceivers(n.groupMembers, n.id, msg.Destination)
+	}
+	if len(msg.Receivers) == 0 {
+		log.Printf("node %s: no other members of group %s:\t%s", n.id, msg.Destination, msg)
+		return true
+	}
+
+	// Neighbor or mpr changes earlier in the tick may have invalidated the routes' next hops.
+	n.refreshRoutes()
+
+	byNextHop := make(map[NodeID][]NodeID)
+	unreachable := make([]NodeID, 0)
+	for _, receiver := range msg.Receivers {
+		route, in := n.routingTable[receiver]
+		if !in {
+			unreachable = append(unreachable, receiver)
+			continue
+		}
+		byNextHop[route.nextHop] = append(byNextHop[route.nextHop], receiver)
+	}
+	if len(byNextHop) == 0 {
+		return false
+	}
+	if len(unreachable) > 0 {
+		dropped := *msg
+		dropped.Receivers = unreachable
+		n.resolveData(&dropped, dataDropped)
+	}
+
+	for _, nextHop := range sortedNodeIDs(byNextHop) {
+		// Each copy is handed off to a different next hop, so none may share the path.
+		out := *msg
+		out.Path = append([]NodeID(nil), msg.Path...)
+		out.FromNeighbor = n.id
+		out.NextHop = nextHop
+		out.Receivers = byNextHop[nextHop]
+
+		n.output.Send(&out)
+		err := n.writeLog(n.inputLog, "out", &out, out.String())
+		if err != nil {
+			log.Panicf("%s could not write out log: %s", n.id, err)
+		}
+		log.Printf("node %s: Sent to %s:\t%s\n", n.id, separatedString(out.Receivers, " "), &out)
+		if msg.Source == n.id {
+			n.counters.DataOriginated++
+		} else {
+			n.counters.DataForwarded++
+		}
+	}
+	return true
+}
+
+// handleMulticastData delivers a multicast DataMessage if this Node is one of its receivers, then forwards it towards
+// the remaining receivers.
+func (n *Node) handleMulticastData(msg *DataMessage) {
+	remaining := make([]NodeID, 0, len(msg.Receivers))
+	for _, receiver := range msg.Receivers {
+		if receiver != n.id {
+			remaining = append(remaining, receiver)
+			continue
+		}
+		delivered := *msg
+		delivered.Receivers = []NodeID{n.id}
+		if !n.isGroupMember(msg.Destination) {
+			// Membership is fixed at initialization, so this only happens with a misaddressed message.
+			log.Printf("node %s: not a member of group %s", n.id, msg.Destination)
+			n.resolveData(&delivered, dataDropped)
+			continue
+		}
+		log.Printf("node %s: delivered from %s via [%s]:\t%s", n.id, msg.Source, separatedString(msg.Path, " "), msg.Data)
+		n.receiveData(&delivered)
+	}
+	if len(remaining) == 0 {
+		return
+	}
+	msg.Path = append(msg.Path, n.id)
+	msg.Receivers = remaining
+	if !n.sendData(msg) {
+		n.resolveData(msg, dataDropped)
+	}
+}
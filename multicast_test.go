@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestController_multicast(t *testing.T) {
+	const group NodeID = 0xE0000001
+	// A line of nodes 0 - 1 - 2 - 3, with 4 and 5 branching off 1.
+	topology, err := NewNetworkTypology(strings.NewReader(
+		"0 UP 0 1\n0 UP 1 0\n" +
+			"0 UP 1 2\n0 UP 2 1\n" +
+			"0 UP 2 3\n0 UP 3 2\n" +
+			"0 UP 1 4\n0 UP 4 1\n" +
+			"0 UP 1 5\n0 UP 5 1\n"))
+	if err != nil {
+		t.Fatalf("NewNetworkTypology() error = %v", err)
+	}
+	logDir, err := os.MkdirTemp("", "olsrsim")
+	if err != nil {
+		t.Fatalf("MkdirTemp() error = %v", err)
+	}
+	defer os.RemoveAll(logDir)
+
+	c := NewController(*topology, time.Millisecond)
+	c.SetLogDir(logDir)
+	c.SetSynchronous(true)
+	c.Initialize([]NodeConfig{
+		{ID: 0, Message: NodeMessage{Message: "hello group", Delay: 30, Destination: group}},
+		{ID: 1, Message: NodeMessage{Sent: true}},
+		{ID: 2, Message: NodeMessage{Sent: true}, Groups: []NodeID{group}},
+		{ID: 3, Message: NodeMessage{Sent: true}, Groups: []NodeID{group}},
+		{ID: 4, Message: NodeMessage{Sent: true}, Groups: []NodeID{group}},
+		{ID: 5, Message: NodeMessage{Sent: true}},
+	})
+	if err := c.ScheduleData(5, 3, "hello 3", 30); err != nil {
+		t.Fatalf("ScheduleData() error = %v", err)
+	}
+	c.StopWhenResolved(0)
+	if got := c.Start(100); got >= 100 {
+		t.Errorf("Start() = %d, want the simulation to stop once every data message is resolved", got)
+	}
+
+	tests := []struct {
+		name          string
+		id            NodeID
+		wantOrigin    int
+		wantForwarded int
+		wantDelivered int
+	}{
+		{name: "source", id: 0, wantOrigin: 1},
+		{name: "branching relay", id: 1, wantForwarded: 3},
+		{name: "member relaying to a member", id: 2, wantForwarded: 2, wantDelivered: 1},
+		{name: "member at the end of the line", id: 3, wantDelivered: 2},
+		{name: "member on a branch", id: 4, wantDelivered: 1},
+		{name: "unicast source", id: 5, wantOrigin: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n, _ := c.node(tt.id)
+			got := n.Counters()
+			if got.DataOriginated != tt.wantOrigin || got.DataForwarded != tt.wantForwarded || got.DataDelivered != tt.wantDelivered {
+				t.Errorf("node %d originated, forwarded, delivered = %d, %d, %d, want %d, %d, %d", tt.id,
+					got.DataOriginated, got.DataForwarded, got.DataDelivered, tt.wantOrigin, tt.wantForwarded, tt.wantDelivered)
+			}
+			if got.DataDropped != 0 {
+				t.Errorf("node %d DataDropped = %d, want 0", tt.id, got.DataDropped)
+			}
+		})
+	}
+}
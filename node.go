@@ -2,12 +2,19 @@ package main
 
 import (
 	"context"
+	"encoding/binary"
 	"fmt"
 	"io"
 	"log"
+	"net"
 	"os"
+	"path/filepath"
+	"reflect"
 	"sort"
 	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -25,13 +32,31 @@ type topologyEntry struct {
 	seq int
 }
 
+// tcSequence is the sequence number, the ANSN, of the newest TCMessage accepted from an originator. It is held as long
+// as the entries that TCMessage advertised, even if it advertised none, so that older TCMessage(s) are still
+// recognized as stale.
+type tcSequence struct {
+	seq       int
+	holdUntil int
+}
+
+// tcMessageID identifies a TCMessage, however many neighbors it is received from, by its originator and message
+// sequence number.
+type tcMessageID struct {
+	source NodeID
+	seq    int
+}
+
 type routingEntry struct {
 	// dst is the destination node address (NodeID in this case).
 	dst NodeID
 
-	// nextHop is where to send a message to in order to reach the destination.
+	// nextHop is where to send a message to in order to reach the destination, the lowest of nextHops.
 	nextHop NodeID
 
+	// nextHops are every next hop with a route of the same, shortest, distance to the destination, sorted.
+	nextHops []NodeID
+
 	// distance is the number of hops needed to reach the destination.
 	distance int
 }
@@ -56,17 +81,134 @@ type oneHopNeighborEntry struct {
 	neighborID NodeID
 	state      NeighborState
 	holdUntil  int
+
+	// neighborLinkQuality is the neighbor's perceived quality of the link from this node, as advertised in its
+	// HelloMessage(s). Zero when the neighbor does not advertise link quality.
+	neighborLinkQuality float64
+}
+
+// String returns the state as advertised within a HelloMessage.
+func (s NeighborState) String() string {
+	switch s {
+	case bidirectional:
+		return "BIDIR"
+	case unidirectional:
+		return "UNIDIR"
+	case mpr:
+		return "MPR"
+	default:
+		return fmt.Sprintf("NeighborState(%d)", int(s))
+	}
 }
 
 // NodeID is a unique identifier used to differentiate nodes.
 type NodeID uint
 
+// dottedQuadThreshold is the smallest NodeID displayed as a dotted-quad address rather than an integer.
+const dottedQuadThreshold NodeID = 1 << 16
+
 func (n NodeID) String() string {
+	if n >= dottedQuadThreshold {
+		return fmt.Sprintf("%d.%d.%d.%d", byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+	}
 	return strconv.Itoa(int(n))
 }
 
+// nodeIDPattern matches a NodeID in the text formats of topology and node configuration files: either an integer or a
+// dotted-quad address.
+const nodeIDPattern = `\d{1,3}(?:\.\d{1,3}){3}|\d+`
+
+// parseNodeID parses either an integer or a dotted-quad address into a NodeID.
+func parseNodeID(s string) (NodeID, error) {
+	if strings.Contains(s, ".") {
+		return parseDottedQuad(s)
+	}
+	// IDs are limited to 32 bits, as with dotted-quad addresses.
+	id, err := strconv.ParseUint(s, 10, 32)
+	if err != nil {
+		return 0, err
+	}
+	return NodeID(id), nil
+}
+
+// parseDottedQuad parses an IPv4 dotted-quad address, such as 10.0.0.1, into the NodeID of its uint32 value.
+func parseDottedQuad(s string) (NodeID, error) {
+	ip := net.ParseIP(s).To4()
+	if ip == nil || strings.Count(s, ".") != 3 {
+		return 0, fmt.Errorf("not a dotted-quad address: '%s'", s)
+	}
+	return NodeID(binary.BigEndian.Uint32(ip)), nil
+}
+
+// sortNodeIDs sorts the NodeID(s) in increasing order.
+func sortNodeIDs(ids []NodeID) {
+	sort.Slice(ids, func(i, j int) bool {
+		return ids[i] < ids[j]
+	})
+}
+
+// sortedNodeIDs returns the keys of the map in increasing order.
+func sortedNodeIDs[V any](m map[NodeID]V) []NodeID {
+	ids := make([]NodeID, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sortNodeIDs(ids)
+	return ids
+}
+
+// tcForwardPolicy determines how a Node handles TC forwards which exceed its per-tick limit.
+type tcForwardPolicy int
+
+const (
+	// deferTCForwards queues excess TC forwards, sending them in subsequent ticks.
+	deferTCForwards tcForwardPolicy = iota
+
+	// dropTCForwards drops excess TC forwards.
+	dropTCForwards
+)
+
+// routeTieBreak determines how a Node chooses between next hops offering routes of the same distance.
+type routeTieBreak int
+
+const (
+	// lowestNextHop sends all traffic for a destination via the next hop with the lowest NodeID.
+	lowestNextHop routeTieBreak = iota
+
+	// equalCostMultipath spreads DataMessage(s) for a destination across every equal-cost next hop in turn, and
+	// reports a routing entry for each.
+	equalCostMultipath
+)
+
+// dataOutcome is the fate of a DataMessage within the simulation.
+type dataOutcome int
+
+const (
+	// dataDelivered is a DataMessage which reached its destination.
+	dataDelivered dataOutcome = iota
+
+	// dataDropped is a DataMessage which was discarded before reaching its destination.
+	dataDropped
+)
+
+// Transmitter sends messages sent by a Node onto the wireless medium.
+type Transmitter interface {
+	Send(msg interface{})
+}
+
+// chanTransmitter is a Transmitter which sends all messages on a channel.
+type chanTransmitter chan<- interface{}
+
+// Send blocks until the message is sent on the channel.
+func (t chanTransmitter) Send(msg interface{}) {
+	t <- msg
+}
+
 // Node represents a network node in the ad-hoc network.
 type Node struct {
+	// mu guards the Node's state, enabling it to be inspected while the Node is running.
+	mu sync.RWMutex
+
 	id NodeID
 
 	// outputLog is where the Node will write all messages that it has Sent.
@@ -78,21 +220,34 @@ type Node struct {
 	// receivedLog is where the Node will write all Data it has received.
 	receivedLog io.WriteCloser
 
+	// logFormat determines how the Node writes its logs.
+	logFormat LogFormat
+
+	// recorder, if set, records everything the Node processes so that the simulation can be replayed.
+	recorder *simulationRecorder
+
 	// input represents the Node's wireless receiver.
 	input <-chan interface{}
 
 	// output represents the Node's wireless transmitter.
-	output chan<- interface{}
+	output Transmitter
 
 	// nodeMsg will be Sent by the node based on the message's Delay.
 	nodeMsg NodeMessage
 
+	// nodeMsgRetries is the number of times sending nodeMsg has been retried for lack of a route.
+	nodeMsgRetries int
+
 	// routingTable maps destinations to routing entries.
 	routingTable map[NodeID]routingEntry
 
 	// routesChanged determines if the routingTable needs to be recalculated.
 	routesChanged bool
 
+	// addedTopology holds the topology entries added since the routingTable was last calculated, with which it may be
+	// extended rather than recalculated. Unused if routesChanged is set.
+	addedTopology []topologyEntry
+
 	// topologyTable represents the Node's current perception of the network topology.
 	// The first NodeID is the destination's mpr, while the second NodeID is the destination.
 	topologyTable map[NodeID]map[NodeID]topologyEntry
@@ -100,9 +255,81 @@ type Node struct {
 	// topologyHoldTime is how long, in ticks, topology table entries will be held until they are expelled.
 	topologyHoldTime int
 
-	// tcSequenceNum is the current TCMessage sequence number.
+	// compactTopology makes the Node keep, per destination, only the topology entry from the most recent TCMessage
+	// whose originator is reachable, rather than an entry from every advertising originator. This bounds the
+	// topologyTable to one entry per destination, at the cost of redundancy: when the retained originator is lost,
+	// the destination is unreachable until another originator's next TCMessage, rather than immediately rerouted.
+	// In a 100-node full mesh, this cuts the memory retained by the table from ~540KB to ~22KB, while processing each
+	// TCMessage takes about 2.5x as long (see BenchmarkNode_handleTC).
+	compactTopology bool
+
+	// helloInterval is the number of ticks between HelloMessage(s).
+	helloInterval int
+
+	// helloPhase is a tick at which a HelloMessage is sent, from which the following ones are spaced by helloInterval.
+	helloPhase int
+
+	// tcInterval is the base number of ticks between TCMessage(s), before scaling by willingness.
+	tcInterval int
+
+	// tcPhase is a tick at which a TCMessage is due, from which the following ones are spaced by the scaled
+	// tcInterval.
+	tcPhase int
+
+	// willingness scales the tcInterval.
+	willingness Willingness
+
+	// interfaces are the Node's interface addresses besides its main address, declared in its MIDMessage(s).
+	interfaces []NodeID
+
+	// midInterval is the number of ticks between MIDMessage(s), sent only if the Node has interfaces.
+	midInterval int
+
+	// midPhase is a tick at which a MIDMessage is due, from which the following ones are spaced by midInterval.
+	midPhase int
+
+	// midSequenceNum is the sequence number of the Node's most recent MIDMessage.
+	midSequenceNum int
+
+	// tcSequences is the ANSN of the newest TCMessage accepted from each originator.
+	tcSequences map[NodeID]tcSequence
+
+	// tcDuplicates holds, until the tick it is forgotten, each TCMessage already received, so that copies received
+	// from other neighbors or again later are neither processed nor forwarded.
+	tcDuplicates map[tcMessageID]int
+
+	// midSequences is the sequence number of the newest MIDMessage received from each originator, so that duplicates
+	// are neither processed nor forwarded again.
+	midSequences map[NodeID]int
+
+	// interfaceAssociations maps each interface address declared in other nodes' MIDMessage(s) to its main address.
+	interfaceAssociations map[NodeID]interfaceAssociation
+
+	// tcSequenceNum is the current TCMessage sequence number, the advertised neighbor sequence number (ANSN).
 	tcSequenceNum int
 
+	// tcMessageSequenceNum is the message sequence number of the Node's next TCMessage.
+	tcMessageSequenceNum int
+
+	// emptyTCIntervals is the number of TC intervals to keep sending empty TCMessage(s) after the msSet empties.
+	emptyTCIntervals int
+
+	// emptyTCsSent is the number of empty TCMessage(s) sent since the msSet last emptied.
+	emptyTCsSent int
+
+	// advertisedMSSet is the sorted msSet advertised in the most recent TCMessage, nil before the first TCMessage.
+	advertisedMSSet []NodeID
+
+	// incrementalTC makes the Node advertise only the changes to its msSet in most TCMessage(s), sending a full
+	// TCMessage after every tcRefreshInterval incremental ones so that nodes which missed a change recover.
+	incrementalTC bool
+
+	// tcRefreshInterval is the number of incremental TCMessage(s) sent between full TCMessage(s).
+	tcRefreshInterval int
+
+	// incrementalTCsSent is the number of incremental TCMessage(s) sent since the last full TCMessage.
+	incrementalTCsSent int
+
 	// oneHopNeighbors is the set of 1-hop neighbors discovered by this node.
 	oneHopNeighbors map[NodeID]oneHopNeighborEntry
 
@@ -110,9 +337,22 @@ type Node struct {
 	// The second map is used for uniqueness and merely maps NodeID(s) to themselves.
 	twoHopNeighbors map[NodeID]map[NodeID]NodeID
 
+	// mprSelector selects the Node's MPRs whenever its neighbor tables change.
+	mprSelector MPRSelector
+
+	// mprs is the MPR set most recently selected by the mprSelector, less any neighbors since expelled.
+	mprs map[NodeID]NodeID
+
 	// msSet is the set of nodes that have selected this Node as an mpr.
 	msSet map[NodeID]NodeID
 
+	// msHoldUntil is the tick at which each msSet entry will be expelled, unless refreshed by a HelloMessage which
+	// still selects this Node. Entries without a hold time are kept until a HelloMessage deselects this Node.
+	msHoldUntil map[NodeID]int
+
+	// msHoldTime is how long, in ticks, msSet entries will be held until they are expelled.
+	msHoldTime int
+
 	// currentTick is the number of ticks since the node came online.
 	currentTick int
 
@@ -126,15 +366,125 @@ type Node struct {
 	// sequence number received from a Node.
 	helloSequences map[NodeID]int
 
+	// helloHistories records the HelloMessage(s) recently received from each neighbor, to measure link quality.
+	helloHistories map[NodeID]helloHistory
+
+	// advertiseLinkQuality makes the Node advertise its perceived link quality to each neighbor in its HelloMessage(s).
+	advertiseLinkQuality bool
+
 	// helloSequenceNum is the Node's HelloMessage sequence number.
 	helloSequenceNum int
+
+	// uncoveredTwoHops are the two-hop neighbors which were not covered by any mpr during the last mpr selection.
+	uncoveredTwoHops []NodeID
+
+	// helloTriggered makes the Node send a HelloMessage during the next tick, outside the HELLO interval.
+	helloTriggered bool
+
+	// tcTriggered makes the Node send a TCMessage during the next tick, outside the TC interval.
+	tcTriggered bool
+
+	// pendingData are DataMessage(s) to originate during the next tick.
+	pendingData []*DataMessage
+
+	// counters tracks the messages handled by the Node.
+	counters MessageCounters
+
+	// tcForwardedFor is the number of times each TCMessage was forwarded.
+	tcForwardedFor map[tcMessageID]int
+
+	// gate, if set, pauses the Node's clock while the simulation is paused.
+	gate *pauseGate
+
+	// clock, if set, drives Run, one tick per value received, rather than a ticker of tickDuration. Run returns once
+	// it is closed.
+	clock <-chan time.Time
+
+	// onDataResolved, if set, is called once a DataMessage is delivered to this Node or dropped by it.
+	onDataResolved func(msg *DataMessage, outcome dataOutcome)
+
+	// maxTCForwards is the maximum number of TCMessage(s) forwarded per tick, modelling limited airtime.
+	// Zero represents no limit.
+	maxTCForwards int
+
+	// tcForwardPolicy determines how TC forwards exceeding maxTCForwards are handled.
+	tcForwardPolicy tcForwardPolicy
+
+	// tcForwardsThisTick is the number of TCMessage(s) forwarded during the current tick.
+	tcForwardsThisTick int
+
+	// tcForwardQueue holds deferred TC forwards, in the order they were received.
+	tcForwardQueue []*TCMessage
+
+	// droppedTCForwards is the number of TC forwards dropped due to maxTCForwards.
+	droppedTCForwards int
+
+	// tableCaps limits the size of the neighbor and topology tables.
+	tableCaps TableCaps
+
+	// deliveryOrder, if set, orders the messages received within a tick, in place of their arrival order.
+	deliveryOrder *deliveryOrder
+
+	// neighborTransitionLog, if set, is where a debug message is logged whenever a neighbor's advertised relationship to
+	// the Node changes.
+	neighborTransitionLog *log.Logger
+
+	// staleTCLog, if set, is where a debug message is logged whenever a TCMessage is discarded for being older than the
+	// topology table.
+	staleTCLog *log.Logger
+
+	// groups are the multicast groups the Node joined.
+	groups []NodeID
+
+	// groupMembers is the membership of every multicast group, by group. Shared by all nodes and never modified.
+	groupMembers map[NodeID][]NodeID
+
+	// routeTieBreak determines how the Node chooses between equal-cost next hops.
+	routeTieBreak routeTieBreak
+
+	// ecmpNext is the number of DataMessage(s) sent to each destination with equalCostMultipath, used to rotate
+	// through its next hops.
+	ecmpNext map[NodeID]int
+
+	// progress is the number of ticks the Node has completed. Accessed atomically, so that it can be read while the
+	// Node is blocked holding mu.
+	progress int64
+
+	// validateNeighbors makes the Node drop DataMessage(s) and TCMessage(s) whose FromNeighbor is not a currently
+	// known one-hop neighbor, guarding against stale in-flight messages sent before a link went down.
+	validateNeighbors bool
+
+	// strictOLSR makes the Node discard TCMessage(s) whose FromNeighbor is not a symmetric one-hop neighbor, per
+	// RFC 3626 section 9.5.
+	strictOLSR bool
+
+	// warnListedInTC makes the Node log a warning whenever it receives a TCMessage listing it as an MPR selector of
+	// an originator which is not its symmetric neighbor. Such TCMessage(s) are counted regardless.
+	warnListedInTC bool
+
+	// routingHistoryDepth is the number of routing table changes retained in routingHistory. Zero disables history.
+	routingHistoryDepth int
+
+	// routingHistory holds the most recent routing table changes, oldest first.
+	routingHistory []routingHistoryEntry
+
+	// routeChanges counts the changes to the route to each destination since it was first added. See RouteStability.
+	routeChanges map[NodeID]int
+
+	// strict makes the Node panic on self-inconsistent configuration, rather than logging a warning, so that tests
+	// fail loudly.
+	strict bool
 }
 
 // Run starts the Node "listening" for messages.
 func (n *Node) Run(ctx context.Context) {
 	// Continuously listen for new messages until done received by Controller.
-	ticker := time.NewTicker(n.tickDuration)
-	defer ticker.Stop()
+	clock := n.clock
+	if clock == nil {
+		ticker := time.NewTicker(n.tickDuration)
+		defer ticker.Stop()
+		clock = ticker.C
+	}
 	defer func(log io.WriteCloser) {
 		_ = log.Close()
 	}(n.inputLog)
@@ -142,96 +492,272 @@ func (n *Node) Run(ctx context.Context) {
 		_ = log.Close()
 	}(n.outputLog)
 
+	n.mu.Lock()
 	n.currentTick = 0
-	for range ticker.C {
+	n.mu.Unlock()
+	if n.recorder != nil {
+		defer func() {
+			n.mu.RLock()
+			defer n.mu.RUnlock()
+			n.recorder.printf("stop %d %d", n.id, n.currentTick)
+		}()
+	}
+	for range clock {
 		select {
 		case <-ctx.Done():
-			log.Printf("node %d: recevied done message", n.id)
+			log.Printf("node %s: received done message", n.id)
 			return
-
-		case msg := <-n.input:
-			_, err := fmt.Fprintln(n.inputLog, msg)
-			if err != nil {
-				log.Panicf("%d could not write out log: %s", n.id, err)
-			}
-			log.Printf("node %d: received:\t%s\n", n.id, msg)
-
-			n.handler(msg)
 		default:
 		}
-
-		if n.currentTick%5 == 0 {
-			n.sendHello()
+		// Block on the clock while the simulation is paused.
+		if !n.gate.wait(ctx) {
+			log.Printf("node %s: received done message", n.id)
+			return
 		}
-		if n.currentTick%10 == 0 && len(n.msSet) > 0 {
-			n.sendTC()
+
+		// A closed input signals shutdown, just as a cancelled context does.
+		msgs, open := n.receiveAll()
+		if !open {
+			log.Printf("node %s: input closed", n.id)
+			return
 		}
-		if n.currentTick == n.nodeMsg.Delay && !n.nodeMsg.Sent {
-			// Attempt to send Data message
-			msg := &DataMessage{
-				Source:       n.id,
-				Destination:  n.nodeMsg.Destination,
-				NextHop:      0,
-				FromNeighbor: 0,
-				Data:         n.nodeMsg.Message,
+
+		n.mu.Lock()
+		n.tick(msgs)
+		n.mu.Unlock()
+	}
+}
+
+// receiveAll drains all messages currently available on the Node's input, without blocking.
+// Returns false if the input has been closed.
+func (n *Node) receiveAll() ([]interface{}, bool) {
+	msgs := make([]interface{}, 0)
+	for {
+		select {
+		case msg, ok := <-n.input:
+			if !ok {
+				return msgs, false
 			}
-			if !n.sendData(msg) {
-				n.nodeMsg.Delay += 30
-			} else {
-				n.nodeMsg.Sent = true
+			msgs = append(msgs, msg)
+		default:
+			return msgs, true
+		}
+	}
+}
+
+// messageFingerprint identifies the content of a control message. DataMessage(s) have no fingerprint, as identical
+// DataMessage(s) are distinct transmissions of the same data.
+func messageFingerprint(msg interface{}) (string, bool) {
+	switch m := msg.(type) {
+	case *HelloMessage:
+		// The sequence number is not part of the text format.
+		return fmt.Sprintf("%s SEQ %d", m, m.Sequence), true
+	case *TCMessage:
+		return m.String(), true
+	case *MIDMessage:
+		return m.String(), true
+	default:
+		return "", false
+	}
+}
+
+// tick runs a single tick of the Node in two deterministic phases.
+// Phase 1 processes every message received since the previous tick, in the order received, or in the seeded order
+// given by the Node's deliveryOrder.
+// Phase 2 sends the Node's own messages and expires old table entries, so emissions always reflect all messages
+// received during the tick.
+func (n *Node) tick(msgs []interface{}) {
+	if n.deliveryOrder != nil {
+		n.deliveryOrder.sort(n.id, n.currentTick, msgs)
+	}
+	if n.recorder != nil {
+		n.recorder.recordTick(n.id, n.currentTick, msgs, n.pendingData, n.helloTriggered, n.tcTriggered)
+	}
+
+	// Phase 1: process received messages.
+	n.flushTCForwards()
+	seen := make(map[string]struct{})
+	for _, msg := range msgs {
+		// Identical control messages, such as a TC relayed twice, are only processed once per tick.
+		if fingerprint, ok := messageFingerprint(msg); ok {
+			if _, in := seen[fingerprint]; in {
+				log.Printf("node %s: ignored duplicate:\t%s\n", n.id, msg)
+				continue
 			}
+			seen[fingerprint] = struct{}{}
 		}
 
-		// Remove old entries from the neighbor tables.
-		for k, entry := range n.oneHopNeighbors {
+		err := n.writeLog(n.inputLog, "in", msg, fmt.Sprint(msg))
+		if err != nil {
+			log.Panicf("%s could not write out log: %s", n.id, err)
+		}
+		log.Printf("node %s: received:\t%s\n", n.id, msg)
+
+		n.handler(msg)
+	}
+
+	// Phase 2: emissions and expiry.
+	if n.emissionDue(n.helloPhase, n.helloInterval) || n.helloTriggered {
+		n.sendHello()
+	}
+	tcInterval := n.willingness.scaleTCInterval(n.tcInterval)
+	if (n.emissionDue(n.tcPhase, tcInterval) && !n.tcDeferred(tcInterval) && n.shouldSendTC()) || n.tcTriggered {
+		n.sendTC()
+	}
+	if len(n.interfaces) > 0 && n.emissionDue(n.midPhase, n.midInterval) {
+		n.sendMID()
+	}
+	n.helloTriggered = false
+	n.tcTriggered = false
+	if n.currentTick == n.nodeMsg.Delay && !n.nodeMsg.Sent {
+		// Attempt to send Data message
+		msg := &DataMessage{
+			Source:       n.id,
+			Destination:  n.nodeMsg.Destination,
+			NextHop:      0,
+			FromNeighbor: 0,
+			Data:         n.nodeMsg.Message,
+		}
+		if n.sendData(msg) {
+			n.nodeMsg.Sent = true
+		} else if n.nodeMsgRetries < nodeMsgMaxRetries {
+			n.nodeMsgRetries++
+			n.nodeMsg.Delay += nodeMsgRetryInterval
+		} else {
+			n.nodeMsg.Sent = true
+			n.resolveData(msg, dataDropped)
+		}
+	}
+	// Attempt to send externally originated Data messages.
+	for _, msg := range n.pendingData {
+		if !n.sendData(msg) {
+			n.resolveData(msg, dataDropped)
+		}
+	}
+	n.pendingData = nil
+
+	// Remove old entries from the neighbor tables, and reselect the MPRs, and so the uncovered two-hop neighbors, without
+	// the expired neighbors.
+	expired := false
+	for k, entry := range n.oneHopNeighbors {
+		if entry.holdUntil <= n.currentTick {
+			delete(n.oneHopNeighbors, k)
+			delete(n.twoHopNeighbors, k)
+			delete(n.mprs, k)
+			delete(n.helloSequences, k)
+			delete(n.helloHistories, k)
+			n.routesChanged = true
+			expired = true
+		}
+	}
+	if expired {
+		n.selectMPRs()
+	}
+	// Remove old entries from the MS set, whose selectors have stopped selecting this Node.
+	for k, holdUntil := range n.msHoldUntil {
+		if holdUntil <= n.currentTick {
+			delete(n.msSet, k)
+			delete(n.msHoldUntil, k)
+		}
+	}
+	// Remove old entries from the TC tables.
+	for _, dst := range n.topologyTable {
+		for k, entry := range dst {
 			if entry.holdUntil <= n.currentTick {
-				delete(n.oneHopNeighbors, k)
-				delete(n.twoHopNeighbors, k)
+				delete(dst, k)
+				n.routesChanged = true
 			}
 		}
-		// Remove old entries from the TC tables.
-		for _, dst := range n.topologyTable {
-			for k, entry := range dst {
-				if entry.holdUntil <= n.currentTick {
-					delete(dst, k)
-				}
-			}
+	}
+	for originator, last := range n.tcSequences {
+		if last.holdUntil <= n.currentTick {
+			delete(n.tcSequences, originator)
 		}
-
-		if n.routesChanged {
-			n.calculateRoutingTable()
-			n.routesChanged = false
+	}
+	for k, holdUntil := range n.tcDuplicates {
+		if holdUntil <= n.currentTick {
+			delete(n.tcDuplicates, k)
 		}
-
-		n.currentTick++
 	}
+	n.expireInterfaceAssociations()
+
+	n.refreshRoutes()
+
+	n.currentTick++
+	atomic.StoreInt64(&n.progress, int64(n.currentTick))
+}
+
+// TriggerHello makes the Node send a HelloMessage during its next tick, regardless of the HELLO interval.
+func (n *Node) TriggerHello() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.helloTriggered = true
+}
+
+// TriggerTC makes the Node send a TCMessage during its next tick, regardless of the TC interval or whether its msSet
+// is empty.
+func (n *Node) TriggerTC() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.tcTriggered = true
+}
+
+// Originate makes the Node send a DataMessage to the destination during its next tick.
+// The message is dropped if there is no route to the destination at that time.
+func (n *Node) Originate(dst NodeID, data string) {
+	n.OriginatePriority(dst, data, 0)
 }
 
 // sendData sends the Node's NodeMessage as a DataMessage if there is a route to the destination.
 func (n *Node) sendData(msg *DataMessage) bool {
+	// A message originated for this Node is delivered locally, never reaching the network.
+	if msg.Source == n.id && n.isOwnAddress(msg.Destination) {
+		log.Printf("node %s: delivered locally:\t%s", n.id, msg.Data)
+		n.receiveData(msg)
+		return true
+	}
+
+	if IsMulticast(msg.Destination) {
+		return n.sendMulticast(msg)
+	}
+
+	// Neighbor or mpr changes earlier in the tick may have invalidated the route's next hop.
+	n.refreshRoutes()
+
 	route, in := n.routingTable[msg.Destination]
 	if in {
 		msg.FromNeighbor = n.id
 		msg.NextHop = route.nextHop
+		if n.routeTieBreak == equalCostMultipath && len(route.nextHops) > 1 {
+			msg.NextHop = route.nextHops[n.ecmpNext[msg.Destination]%len(route.nextHops)]
+			n.ecmpNext[msg.Destination]++
+		}
 
-		n.output <- msg
-		_, err := fmt.Fprintln(n.inputLog, msg)
+		n.output.Send(msg)
+		err := n.writeLog(n.inputLog, "out", msg, msg.String())
 		if err != nil {
-			log.Panicf("%d could not write out log: %s", n.id, err)
+			log.Panicf("%s could not write out log: %s", n.id, err)
+		}
+		log.Printf("node %s: Sent:\t%s\n", n.id, msg)
+		if msg.Source == n.id {
+			n.counters.DataOriginated++
+		} else {
+			n.counters.DataForwarded++
 		}
-		log.Printf("node %d: Sent:\t%s\n", n.id, msg)
 		return true
 	}
 	return false
 }
 
-// sendHello sends a HelloMessage for this node.
-func (n *Node) sendHello() {
+// buildHello creates a HelloMessage advertising the given one-hop neighbors, with each neighbor list sorted.
+func buildHello(oneHop map[NodeID]oneHopNeighborEntry, src NodeID) *HelloMessage {
 	// Gather one-hop neighbor entries.
 	biNeighbors := make([]NodeID, 0)
 	uniNeighbors := make([]NodeID, 0)
 	mprNeighbors := make([]NodeID, 0)
-	for _, o := range n.oneHopNeighbors {
+	for _, o := range oneHop {
 		switch o.state {
 		case unidirectional:
 			uniNeighbors = append(uniNeighbors, o.neighborID)
@@ -240,51 +766,134 @@ func (n *Node) sendHello() {
 		case mpr:
 			mprNeighbors = append(mprNeighbors, o.neighborID)
 		default:
-			log.Panicf("node %d: invalid one-hop neighbor type: %d", n.id, o.state)
+			log.Panicf("node %s: invalid one-hop neighbor type: %d", src, o.state)
 		}
 	}
+	for _, ids := range [][]NodeID{uniNeighbors, biNeighbors, mprNeighbors} {
+		sortNodeIDs(ids)
+	}
 
-	hello := &HelloMessage{
-		Source:          n.id,
+	return &HelloMessage{
+		Source:          src,
 		Unidirectional:  uniNeighbors,
 		Bidirectional:   biNeighbors,
 		MultipointRelay: mprNeighbors,
-		Sequence:        n.helloSequenceNum,
 	}
+}
+
+// sendHello sends a HelloMessage for this node.
+func (n *Node) sendHello() {
+	if n.strict {
+		if err := verifyAdvertisedMPRs(n.oneHopNeighbors, n.mprs); err != nil {
+			log.Panicf("node %s: %s", n.id, err)
+		}
+	}
+	hello := buildHello(n.oneHopNeighbors, n.id)
+	hello.LinkQuality = n.advertisedLinkQuality()
+	hello.Sequence = n.helloSequenceNum
 	n.helloSequenceNum++
-	n.output <- hello
-	log.Printf("node %d: Sent:\t%s", n.id, hello)
-	_, err := fmt.Fprintln(n.outputLog, hello)
+	n.counters.HelloSent++
+	n.counters.HelloBytes += len(hello.String())
+	n.output.Send(hello)
+	log.Printf("node %s: Sent:\t%s", n.id, hello)
+	err := n.writeLog(n.outputLog, "out", hello, hello.String())
 	if err != nil {
-		log.Panicf("node %d: unable to log hello Message to output: %s", n.id, err)
+		log.Panicf("node %s: unable to log hello Message to output: %s", n.id, err)
 	}
 }
 
+// tcDeferred determines whether the Node's first periodic TCMessage is held back. Until a full TC interval has passed,
+// a Node which knows no neighbors has nothing to advertise, as at tick 0, when every node sends its first HELLO before
+// any neighbor discovery has happened.
+func (n *Node) tcDeferred(interval int) bool {
+	return n.currentTick < interval && len(n.oneHopNeighbors) == 0 && len(n.msSet) == 0
+}
+
+// SetEmptyTCIntervals sets the number of TC intervals for which the Node keeps sending empty TCMessage(s) after its
+// msSet empties, so that other nodes flush the entries it previously advertised rather than waiting for them to
+// expire. Zero, the default, stops sending TCMessage(s) as soon as the msSet empties.
+func (n *Node) SetEmptyTCIntervals(intervals int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.emptyTCIntervals = intervals
+}
+
+// shouldSendTC determines whether a TCMessage is due. TCMessage(s) are sent while the msSet is non-empty, and for
+// emptyTCIntervals intervals after it empties so that other nodes flush the previously advertised entries.
+func (n *Node) shouldSendTC() bool {
+	if len(n.msSet) > 0 {
+		n.emptyTCsSent = 0
+		return true
+	}
+	// Nothing has been advertised, so there is nothing to flush.
+	if n.advertisedMSSet == nil {
+		return false
+	}
+	if n.emptyTCsSent < n.emptyTCIntervals {
+		n.emptyTCsSent++
+		return true
+	}
+	return false
+}
+
 // sendTC sends a TCMessage including the most recent MultipointRelaySet set for this node.
+// The sequence number is an advertised neighbor sequence number (ANSN), only incremented when the advertised
+// MultipointRelaySet changes, or for an incremental TCMessage, so that receivers can tell when they missed a delta.
 func (n *Node) sendTC() {
 	// Get the MS set node IDs to include in the TC message.
-	msSet := make([]NodeID, 0)
-	for _, id := range n.msSet {
-		msSet = append(msSet, id)
-	}
-	sort.SliceStable(msSet, func(i, j int) bool {
-		return msSet[i] < msSet[j]
-	})
+	msSet := sortedNodeIDs(n.msSet)
 
+	incremental := n.incrementalTC && n.advertisedMSSet != nil && n.incrementalTCsSent < n.tcRefreshInterval
+	if incremental || (n.advertisedMSSet != nil && !reflect.DeepEqual(msSet, n.advertisedMSSet)) {
+		n.tcSequenceNum = (n.tcSequenceNum + 1) % seqMax
+	}
 	tc := &TCMessage{
-		Source:             n.id,
-		FromNeighbor:       n.id,
-		Sequence:           n.tcSequenceNum,
-		MultipointRelaySet: msSet,
-	}
-	n.output <- tc
-	log.Printf("node %d: Sent:\t%s", n.id, tc)
-	_, err := fmt.Fprintln(n.outputLog, tc)
-	if err != nil {
-		log.Panicf("node %d: unable to log tc Message to output: %s", n.id, err)
+		Source:          n.id,
+		FromNeighbor:    n.id,
+		Sequence:        n.tcSequenceNum,
+		MessageSequence: n.tcMessageSequenceNum,
 	}
+	n.tcMessageSequenceNum = (n.tcMessageSequenceNum + 1) % seqMax
+	if incremental {
+		tc.Incremental = true
+		tc.Added, tc.Removed = diffNodeIDs(n.advertisedMSSet, msSet)
+		n.incrementalTCsSent++
+	} else {
+		tc.MultipointRelaySet = msSet
+		n.incrementalTCsSent = 0
+	}
+	n.advertisedMSSet = msSet
+	n.transmitTC(tc)
+}
 
-	n.tcSequenceNum++
+// diffNodeIDs determines the IDs added to and removed from the sorted set before to create the sorted set after.
+func diffNodeIDs(before, after []NodeID) (added, removed []NodeID) {
+	i, j := 0, 0
+	for i < len(before) || j < len(after) {
+		switch {
+		case j == len(after) || (i < len(before) && before[i] < after[j]):
+			removed = append(removed, before[i])
+			i++
+		case i == len(before) || after[j] < before[i]:
+			added = append(added, after[j])
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+	return added, removed
+}
+
+// SetIncrementalTC enables or disables incremental TCMessage(s), which advertise only the changes to the Node's msSet.
+// A full TCMessage is sent after every refreshInterval incremental ones. Full TCMessage(s) are sent by default.
+func (n *Node) SetIncrementalTC(enabled bool, refreshInterval int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.incrementalTC = enabled
+	n.tcRefreshInterval = refreshInterval
 }
 
 // handler de-multiplexes messages to their respective handlers.
@@ -296,11 +905,42 @@ func (n *Node) handler(msg interface{}) {
 		n.handleData(msg.(*DataMessage))
 	case *TCMessage:
 		n.handleTC(msg.(*TCMessage))
+	case *MIDMessage:
+		n.handleMID(msg.(*MIDMessage))
 	default:
-		log.Panicf("node %d: invalid message type: %s\n", n.id, t)
+		log.Panicf("node %s: invalid message type: %s\n", n.id, t)
 	}
 }
 
+// SetEqualCostMultipath enables or disables spreading DataMessage(s) for a destination across every equal-cost next
+// hop in turn, rather than sending them all via the next hop with the lowest NodeID. Disabled by default.
+func (n *Node) SetEqualCostMultipath(enabled bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.routeTieBreak = lowestNextHop
+	if enabled {
+		n.routeTieBreak = equalCostMultipath
+	}
+}
+
+// refreshRoutes updates the routing table if any neighbor or topology changes have occurred since it was last
+// calculated. Topology entries which were only added extend the routing table in place, while any other change
+// recalculates it from scratch.
+func (n *Node) refreshRoutes() {
+	if !n.routesChanged && len(n.addedTopology) == 0 {
+		return
+	}
+	previous := n.routingTable
+	if n.routesChanged || !n.extendRoutingTable(n.addedTopology) {
+		n.calculateRoutingTable()
+	}
+	countRouteChanges(previous, n.routingTable, n.routeChanges)
+	n.recordRoutingTable()
+	n.routesChanged = false
+	n.addedTopology = nil
+}
+
 // calculateRoutingTable calculates all reachable destinations based on the topologyTable.
 func (n *Node) calculateRoutingTable() {
 	// Wipe the table clean, ensuring no stale routes.
@@ -309,46 +949,29 @@ func (n *Node) calculateRoutingTable() {
 	// Add all symmetric one-hop neighbors.
 	for _, neighbor := range n.oneHopNeighbors {
 		if neighbor.state == bidirectional || neighbor.state == mpr {
-			n.routingTable[neighbor.neighborID] = routingEntry{
-				dst:      neighbor.neighborID,
-				nextHop:  neighbor.neighborID,
-				distance: 1,
-			}
+			n.addRoute(neighbor.neighborID, []NodeID{neighbor.neighborID}, 1)
 		}
 	}
 
-	// Add all two-hop neighbors.
-	for neighbor, reachableTwoHops := range n.twoHopNeighbors {
-		for dst := range reachableTwoHops {
-			_, in := n.routingTable[dst]
-			if !in {
-				n.routingTable[dst] = routingEntry{
-					dst:      dst,
-					nextHop:  neighbor,
-					distance: 2,
-				}
-			}
+	// Add all two-hop neighbors, through every neighbor reaching them.
+	for neighbor, twoHops := range n.twoHopNeighbors {
+		for dst := range twoHops {
+			n.addRoute(dst, []NodeID{neighbor}, 2)
 		}
 	}
 
 	// Add all remaining routes from topology table.
 	for h := 2; h < 256; h++ {
 		newEntry := false
-		for _, neighborDsts := range n.topologyTable {
-			for _, entry := range neighborDsts {
-				// Check if there already exists a routing entry for the destination.
-				_, in := n.routingTable[entry.dst]
-				if !in {
-					// No destination. Check if there's a routing entry that can reach the MultipointRelay of the destination.
-					rEntry, in := n.routingTable[entry.originator]
-					if in && rEntry.distance == h {
-						newEntry = true
-						n.routingTable[entry.dst] = routingEntry{
-							dst:      entry.dst,
-							nextHop:  rEntry.nextHop,
-							distance: h + 1,
-						}
-					}
+		for _, originator := range sortedNodeIDs(n.topologyTable) {
+			// Check if there's a routing entry that can reach the MultipointRelay of the destinations.
+			rEntry, in := n.routingTable[originator]
+			if !in || rEntry.distance != h {
+				continue
+			}
+			for _, entry := range n.topologyTable[originator] {
+				if n.addRoute(entry.dst, rEntry.nextHops, h+1) {
+					newEntry = true
 				}
 			}
 		}
@@ -356,10 +979,52 @@ func (n *Node) calculateRoutingTable() {
 			break
 		}
 	}
+
+	// Equal-cost next hops were gathered in no particular order, so sort them for a deterministic choice.
+	for dst, entry := range n.routingTable {
+		sortNodeIDs(entry.nextHops)
+		entry.nextHop = entry.nextHops[0]
+		n.routingTable[dst] = entry
+	}
+
+	n.addInterfaceRoutes()
+}
+
+// addRoute adds a route to the destination via the next hops, unless a shorter route exists. The next hops of a route
+// with the same distance are merged. Returns whether a new destination was added.
+func (n *Node) addRoute(dst NodeID, nextHops []NodeID, distance int) bool {
+	entry, in := n.routingTable[dst]
+	if !in {
+		n.routingTable[dst] = routingEntry{
+			dst:      dst,
+			nextHop:  nextHops[0],
+			nextHops: append([]NodeID(nil), nextHops...),
+			distance: distance,
+		}
+		return true
+	}
+	if entry.distance != distance {
+		return false
+	}
+	for _, nextHop := range nextHops {
+		known := false
+		for _, other := range entry.nextHops {
+			if other == nextHop {
+				known = true
+				break
+			}
+		}
+		if !known {
+			entry.nextHops = append(entry.nextHops, nextHop)
+		}
+	}
+	n.routingTable[dst] = entry
+	return false
 }
 
-// updateOneHopNeighbors adds all new one-hop neighbors that can be reached.
-func updateOneHopNeighbors(msg *HelloMessage, oneHopNeighbors map[NodeID]oneHopNeighborEntry, holdUntil int, id NodeID) map[NodeID]oneHopNeighborEntry {
+// updateOneHopNeighbors adds all new one-hop neighbors that can be reached. Whenever a known neighbor's advertised
+// relationship to the Node changes, a debug message is logged to transitions, if set.
+func updateOneHopNeighbors(msg *HelloMessage, oneHopNeighbors map[NodeID]oneHopNeighborEntry, holdUntil int, id NodeID, transitions *log.Logger) map[NodeID]oneHopNeighborEntry {
 	entry, in := oneHopNeighbors[msg.Source]
 	if !in {
 		// First time neighbor
@@ -381,12 +1046,18 @@ func updateOneHopNeighbors(msg *HelloMessage, oneHopNeighbors map[NodeID]oneHopN
 			}
 		}
 
+		prior := entry.state
 		if included {
 			entry.state = bidirectional
 		} else {
 			entry.state = unidirectional
 		}
 
+		// An mpr is a bidirectional neighbor, so only a change in symmetry is a change in relationship.
+		if transitions != nil && (prior == unidirectional) != (entry.state == unidirectional) {
+			transitions.Printf("node %s: neighbor %s: relationship changed: %s -> %s", id, msg.Source, prior, entry.state)
+		}
+
 		oneHopNeighbors[msg.Source] = entry
 	}
 	return oneHopNeighbors
@@ -407,50 +1078,8 @@ func updateTwoHopNeighbors(msg *HelloMessage, twoHopNeighbors map[NodeID]map[Nod
 	return twoHopNeighbors
 }
 
-// calculateMPRs creates a new mpr set based on the current neighbor tables.
-func calculateMPRs(oneHopNeighbors map[NodeID]oneHopNeighborEntry, twoHopNeighbors map[NodeID]map[NodeID]NodeID) map[NodeID]oneHopNeighborEntry {
-	// Copy one hop neighbors
-	remainingTwoHops := make(map[NodeID]NodeID)
-	nodes := make([]struct {
-		id      NodeID
-		reaches int
-	}, 0)
-	for neighbor, twoHops := range twoHopNeighbors {
-		// Only consider nodes as MPRs if they are bidirectional.
-		ohn, _ := oneHopNeighbors[neighbor]
-		if ohn.state == unidirectional {
-			continue
-		}
-		nodes = append(nodes, struct {
-			id      NodeID
-			reaches int
-		}{id: neighbor, reaches: len(twoHops)})
-
-		for k := range twoHops {
-			remainingTwoHops[k] = k
-		}
-	}
-
-	// Sort neighbors based on the number of two-hop neighbors they reach.
-	sort.SliceStable(nodes, func(i, j int) bool {
-		return nodes[i].reaches > nodes[j].reaches
-	})
-
-	// Set of MPRs
-	mprs := make(map[NodeID]NodeID)
-
-	for len(remainingTwoHops) > 0 {
-		maxTwoHops := nodes[0]
-		nodes = nodes[1:]
-
-		mprs[maxTwoHops.id] = maxTwoHops.id
-
-		for k := range twoHopNeighbors[maxTwoHops.id] {
-			delete(remainingTwoHops, k)
-		}
-	}
-
-	// Update states of one-hop neighbors based on newly selected MPRs.
+// markMPRs updates the states of one-hop neighbors based on newly selected MPRs.
+func markMPRs(oneHopNeighbors map[NodeID]oneHopNeighborEntry, mprs map[NodeID]NodeID) map[NodeID]oneHopNeighborEntry {
 	for id, neigh := range oneHopNeighbors {
 		_, in := mprs[id]
 		if in {
@@ -466,8 +1095,55 @@ func calculateMPRs(oneHopNeighbors map[NodeID]oneHopNeighborEntry, twoHopNeighbo
 	return oneHopNeighbors
 }
 
+// uncoveredTwoHops finds all two-hop neighbors which can not be reached via a selected mpr, sorted by NodeID.
+func uncoveredTwoHops(oneHopNeighbors map[NodeID]oneHopNeighborEntry, twoHopNeighbors map[NodeID]map[NodeID]NodeID) []NodeID {
+	covered := make(map[NodeID]NodeID)
+	for neighbor, twoHops := range twoHopNeighbors {
+		if oneHopNeighbors[neighbor].state != mpr {
+			continue
+		}
+		for k := range twoHops {
+			covered[k] = k
+		}
+	}
+
+	uncovered := make([]NodeID, 0)
+	seen := make(map[NodeID]NodeID)
+	for _, twoHops := range twoHopNeighbors {
+		for k := range twoHops {
+			_, isCovered := covered[k]
+			_, isSeen := seen[k]
+			if !isCovered && !isSeen {
+				seen[k] = k
+				uncovered = append(uncovered, k)
+			}
+		}
+	}
+	sortNodeIDs(uncovered)
+	return uncovered
+}
+
+// UncoveredTwoHops returns the two-hop neighbors not covered by any mpr, sorted by NodeID.
+// In a network with only bidirectional links this is empty; otherwise it indicates coverage gaps caused by
+// asymmetric links.
+func (n *Node) UncoveredTwoHops() []NodeID {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	uncovered := make([]NodeID, len(n.uncoveredTwoHops))
+	copy(uncovered, n.uncoveredTwoHops)
+	return uncovered
+}
+
 // handleHello handles the processing of a HelloMessage.
 func (n *Node) handleHello(msg *HelloMessage) {
+	// Ignore hello messages Sent by this node, which would make it its own neighbor.
+	if msg.Source == n.id {
+		return
+	}
+	if !n.admitOneHopNeighbor(msg.Source) {
+		return
+	}
 	// Ignore hello messages Sent out-of-order
 	seq, in := n.helloSequences[msg.Source]
 	if !in {
@@ -479,14 +1155,27 @@ func (n *Node) handleHello(msg *HelloMessage) {
 			n.helloSequences[msg.Source] = msg.Sequence
 		}
 	}
+	n.recordHello(msg.Source, msg.Sequence)
+
+	symmetricBefore := n.isSymmetricNeighbor(msg.Source)
+	twoHopsBefore := tableKeys(n.twoHopNeighbors[msg.Source])
 
 	// Update one-hop neighbors.
-	n.oneHopNeighbors = updateOneHopNeighbors(msg, n.oneHopNeighbors, n.currentTick+n.neighborHoldTime, n.id)
+	n.oneHopNeighbors = updateOneHopNeighbors(msg, n.oneHopNeighbors, n.currentTick+n.neighborHoldTime, n.id, n.neighborTransitionLog)
+
+	// Store the reverse link quality so both directions may be combined.
+	if lq, in := msg.LinkQuality[n.id]; in {
+		entry := n.oneHopNeighbors[msg.Source]
+		entry.neighborLinkQuality = lq
+		n.oneHopNeighbors[msg.Source] = entry
+	}
 
 	// Update two-hop neighbors
 	n.twoHopNeighbors = updateTwoHopNeighbors(msg, n.twoHopNeighbors, n.id)
+	n.capTwoHopNeighbors(msg.Source, twoHopsBefore)
 
-	n.oneHopNeighbors = calculateMPRs(n.oneHopNeighbors, n.twoHopNeighbors)
+	// The mpr selection does not affect the routes, which only depend on symmetry and the two-hop neighbors.
+	n.selectMPRs()
 
 	// Update the msSet
 	_, in = n.msSet[msg.Source]
@@ -501,37 +1190,162 @@ func (n *Node) handleHello(msg *HelloMessage) {
 	// Previously an MS, but no longer are.
 	if in && !isMS {
 		delete(n.msSet, msg.Source)
+		delete(n.msHoldUntil, msg.Source)
 	}
-	// New MS.
-	if !in && isMS {
+	// New MS, or an MS selecting this Node again.
+	if isMS {
 		n.msSet[msg.Source] = msg.Source
+		n.msHoldUntil[msg.Source] = n.currentTick + n.msHoldTime
 	}
 
-	n.routesChanged = true
+	if n.neighborChanged(msg.Source, symmetricBefore, twoHopsBefore) {
+		n.routesChanged = true
+	}
 }
 
-func (n *Node) handleData(msg *DataMessage) {
-	if msg.Destination == n.id {
-		_, err := fmt.Fprintln(n.receivedLog, msg.Data)
-		if err != nil {
-			log.Panicf("node %d: unable to log Data to output: %s", n.id, err)
+// SetNeighborTransitionLog sets where a debug message is logged whenever a neighbor's advertised relationship to the
+// Node changes, between unidirectional and bidirectional. Nil, the default, disables the messages.
+func (n *Node) SetNeighborTransitionLog(l *log.Logger) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.neighborTransitionLog = l
+}
+
+// SetStaleTCLog sets where a debug message is logged whenever a TCMessage is discarded for being older than the
+// topology table, or an incremental TCMessage does not follow the known entries. Nil, the default, disables the
+// messages.
+func (n *Node) SetStaleTCLog(l *log.Logger) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.staleTCLog = l
+}
+
+// selectMPRs selects the Node's MPRs from its current neighbor tables.
+func (n *Node) selectMPRs() {
+	mprs := n.mprSelector.SelectMPRs(n.oneHopNeighbors, n.twoHopNeighbors)
+	if n.strict {
+		if err := verifyMPRCoverage(n.oneHopNeighbors, n.twoHopNeighbors, mprs); err != nil {
+			log.Panicf("node %s: %s", n.id, err)
 		}
+	}
+	n.oneHopNeighbors = markMPRs(n.oneHopNeighbors, mprs)
+	n.mprs = mprs
+	n.uncoveredTwoHops = uncoveredTwoHops(n.oneHopNeighbors, n.twoHopNeighbors)
+}
+
+// SetMPRSelector sets the MPRSelector which selects the Node's MPRs, and reselects them from the current neighbor
+// tables. mapMPRSelector by default; bitsetMPRSelector selects the same MPRs faster in large, dense networks.
+func (n *Node) SetMPRSelector(s MPRSelector) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.mprSelector = s
+	n.selectMPRs()
+}
+
+// SetValidateNeighbors enables or disables dropping DataMessage(s) and TCMessage(s) whose FromNeighbor is not a
+// currently known one-hop neighbor. Disabled by default.
+func (n *Node) SetValidateNeighbors(enabled bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.validateNeighbors = enabled
+}
+
+// isKnownNeighbor determines whether the message was received from a currently known one-hop neighbor.
+// Always true unless neighbor validation is enabled.
+func (n *Node) isKnownNeighbor(fromNeighbor NodeID, msg fmt.Stringer) bool {
+	if !n.validateNeighbors {
+		return true
+	}
+	if _, in := n.oneHopNeighbors[fromNeighbor]; in {
+		return true
+	}
+	log.Printf("node %s: dropped message from unknown neighbor %s:\t%s", n.id, fromNeighbor, msg)
+	return false
+}
+
+// SetStrictOLSR enables or disables discarding TCMessage(s) whose FromNeighbor is not a symmetric one-hop neighbor,
+// per RFC 3626 section 9.5. Disabled by default.
+func (n *Node) SetStrictOLSR(enabled bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.strictOLSR = enabled
+}
+
+// isSymmetricNeighbor determines whether the Node has a bidirectional link with the neighbor.
+func (n *Node) isSymmetricNeighbor(id NodeID) bool {
+	entry, in := n.oneHopNeighbors[id]
+	return in && (entry.state == bidirectional || entry.state == mpr)
+}
+
+// receiveData records a DataMessage for which this Node is the destination.
+func (n *Node) receiveData(msg *DataMessage) {
+	err := n.writeLog(n.receivedLog, "received", msg, msg.Data)
+	if err != nil {
+		log.Panicf("node %s: unable to log Data to output: %s", n.id, err)
+	}
+	n.counters.DataDelivered++
+	n.counters.DataDeliveredBytes += len(msg.Data)
+	n.resolveData(msg, dataDelivered)
+}
+
+func (n *Node) handleData(msg *DataMessage) {
+	if !n.isKnownNeighbor(msg.FromNeighbor, msg) {
+		return
+	}
+	if IsMulticast(msg.Destination) {
+		n.handleMulticastData(msg)
+		return
+	}
+	if n.isOwnAddress(msg.Destination) {
+		log.Printf("node %s: delivered from %s via [%s]:\t%s", n.id, msg.Source, separatedString(msg.Path, " "), msg.Data)
+		n.receiveData(msg)
 		return
 	}
-	n.sendData(msg)
+	msg.Path = append(msg.Path, n.id)
+	if !n.sendData(msg) {
+		n.resolveData(msg, dataDropped)
+	}
 }
 
-func updateTopologyTable(msg *TCMessage, topologyTable map[NodeID]map[NodeID]topologyEntry, holdUntil int, id NodeID) map[NodeID]map[NodeID]topologyEntry {
-	entries, in := topologyTable[msg.Source]
-	if in {
-		// Check if sequence number is new.
-		for _, dst := range msg.MultipointRelaySet {
-			entry, in := entries[dst]
-			if in && entry.seq > msg.Sequence {
-				return topologyTable
-			}
+// resolveData reports a DataMessage which was delivered to, or dropped by, this Node.
+func (n *Node) resolveData(msg *DataMessage, outcome dataOutcome) {
+	if outcome == dataDropped {
+		n.counters.DataDropped++
+		log.Printf("node %s: no route, dropped:\t%s", n.id, msg)
+	}
+	if n.onDataResolved != nil {
+		n.onDataResolved(msg, outcome)
+	}
+}
+
+// seqMax is the number of distinct sequence numbers, after which sequence numbers wrap around to zero.
+const seqMax = 1 << 16
+
+// seqNewer determines whether sequence number a is newer than b, accounting for wraparound per RFC 3626 section 19.
+func seqNewer(a, b int) bool {
+	return (a > b && a-b <= seqMax/2) || (b > a && b-a > seqMax/2)
+}
+
+// updateTopologyTable updates the topology table with a TCMessage. Whenever the TCMessage is discarded for being older
+// than the topology table, a debug message is logged to staleTCs, if set.
+func updateTopologyTable(msg *TCMessage, topologyTable map[NodeID]map[NodeID]topologyEntry, sequences map[NodeID]tcSequence, holdUntil int, id NodeID, staleTCs *log.Logger) map[NodeID]map[NodeID]topologyEntry {
+	if msg.Incremental {
+		return applyTCDelta(msg, topologyTable, sequences, holdUntil, id, staleTCs)
+	}
+	// The first TC from a source records its sequence number. Afterwards, a TC is discarded if an earlier one from the
+	// source has a newer sequence number, accounting for wraparound, whether or not its entries remain.
+	if last, in := sequences[msg.Source]; in && seqNewer(last.seq, msg.Sequence) {
+		if staleTCs != nil {
+			staleTCs.Printf("node %s: discarded stale TC from %s: seq %d is older than %d", id, msg.Source, msg.Sequence, last.seq)
 		}
+		return topologyTable
 	}
+	sequences[msg.Source] = tcSequence{seq: msg.Sequence, holdUntil: holdUntil}
 	// New sequence TC message. Clear all old entries and add new entries.
 	topologyTable[msg.Source] = make(map[NodeID]topologyEntry)
 
@@ -552,14 +1366,127 @@ func updateTopologyTable(msg *TCMessage, topologyTable map[NodeID]map[NodeID]top
 	return topologyTable
 }
 
+// SetCompactTopology enables or disables keeping only one topology entry per destination, trading rerouting speed for
+// memory. Disabled by default.
+func (n *Node) SetCompactTopology(enabled bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.compactTopology = enabled
+}
+
+// compactTopologyTable removes the entries of other originators for each destination advertised by the originator,
+// provided the originator is reachable according to the routing table.
+func compactTopologyTable(topologyTable map[NodeID]map[NodeID]topologyEntry, originator NodeID, routingTable map[NodeID]routingEntry) {
+	if _, in := routingTable[originator]; !in {
+		return
+	}
+	for dst := range topologyTable[originator] {
+		for other, entries := range topologyTable {
+			if other == originator {
+				continue
+			}
+			delete(entries, dst)
+			if len(entries) == 0 {
+				delete(topologyTable, other)
+			}
+		}
+	}
+}
+
+// applyTCDelta updates the topology table with an incremental TCMessage. A delta only applies on top of the TCMessage
+// immediately preceding it, and every delta takes the next sequence number, so it is ignored if no TCMessage from the
+// source is known, or if a sequence number was missed; the source's next full TCMessage then brings the table up to
+// date.
+func applyTCDelta(msg *TCMessage, topologyTable map[NodeID]map[NodeID]topologyEntry, sequences map[NodeID]tcSequence, holdUntil int, id NodeID, staleTCs *log.Logger) map[NodeID]map[NodeID]topologyEntry {
+	last, known := sequences[msg.Source]
+	if !known || msg.Sequence != (last.seq+1)%seqMax {
+		if staleTCs != nil {
+			staleTCs.Printf("node %s: ignored incremental TC from %s: seq %d does not follow the known entries", id, msg.Source, msg.Sequence)
+		}
+		return topologyTable
+	}
+	sequences[msg.Source] = tcSequence{seq: msg.Sequence, holdUntil: holdUntil}
+
+	entries := topologyTable[msg.Source]
+	if entries == nil {
+		entries = make(map[NodeID]topologyEntry)
+		topologyTable[msg.Source] = entries
+	}
+
+	for _, dst := range msg.Removed {
+		delete(entries, dst)
+	}
+	for _, dst := range msg.Added {
+		if dst == id {
+			continue
+		}
+		entries[dst] = topologyEntry{}
+	}
+	// Every remaining entry is refreshed, as the delta confirms it is still advertised.
+	for dst := range entries {
+		entries[dst] = topologyEntry{
+			dst:        dst,
+			originator: msg.Source,
+			holdUntil:  holdUntil,
+			seq:        msg.Sequence,
+		}
+	}
+	return topologyTable
+}
+
+// SetStrict enables or disables strict mode, in which self-inconsistent configuration, such as a topology hold time
+// which is not positive, makes the Node panic rather than log a warning, so that tests fail loudly. Disabled by
+// default.
+func (n *Node) SetStrict(enabled bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.strict = enabled
+}
+
 func (n *Node) handleTC(msg *TCMessage) {
 	// Ignore TC messages Sent by this node.
 	if msg.Source == n.id {
 		return
 	}
+	if !n.isKnownNeighbor(msg.FromNeighbor, msg) {
+		return
+	}
+	if n.strictOLSR && !n.isSymmetricNeighbor(msg.FromNeighbor) {
+		log.Printf("node %s: discarded TC from non-symmetric neighbor %s:\t%s", n.id, msg.FromNeighbor, msg)
+		return
+	}
+	// A TC already received, possibly from another neighbor, has been processed and, if need be, forwarded.
+	dup := tcMessageID{source: msg.Source, seq: msg.MessageSequence}
+	if _, in := n.tcDuplicates[dup]; in {
+		return
+	}
+	n.tcDuplicates[dup] = n.currentTick + tcDuplicateHoldTime
+	// Entries stored without a positive hold time are expelled immediately, so the TC would have no effect.
+	if n.topologyHoldTime <= 0 {
+		if n.strict {
+			log.Panicf("node %s: topology hold time must be positive: %d", n.id, n.topologyHoldTime)
+		}
+		log.Printf("node %s: WARNING: topology hold time is not positive, TC entries expire immediately: %d", n.id, n.topologyHoldTime)
+	}
 
-	n.topologyTable = updateTopologyTable(msg, n.topologyTable, n.currentTick+n.topologyHoldTime, n.id)
-	n.routesChanged = true
+	// This Node is listed as an MPR selector only by the MPRs it selected, which are its symmetric neighbors. A distant
+	// originator listing it points to a misconfiguration or a loop. The entry itself is skipped by updateTopologyTable.
+	if tcListsNode(msg, n.id) && !n.isSymmetricNeighbor(msg.Source) {
+		n.counters.TCListingSelf++
+		if n.warnListedInTC {
+			log.Printf("node %s: WARNING: TC from non-neighbor %s lists this node as an MPR selector:\t%s", n.id, msg.Source, msg)
+		}
+	}
+
+	before := tableKeys(n.topologyTable[msg.Source])
+	n.topologyTable = updateTopologyTable(msg, n.topologyTable, n.tcSequences, n.currentTick+n.topologyHoldTime, n.id, n.staleTCLog)
+	if n.compactTopology {
+		compactTopologyTable(n.topologyTable, msg.Source, n.routingTable)
+	}
+	n.capTopologyTable(msg.Source, before)
+	n.noteTopologyChange(msg.Source, before)
 
 	// Only forward TC message if this node is an MultipointRelay of the neighbor which Sent the TC message.
 	doFwd := false
@@ -572,20 +1499,107 @@ func (n *Node) handleTC(msg *TCMessage) {
 		return
 	}
 
-	// Update the from-neighbor field.
-	msg.FromNeighbor = n.id
+	// Update the from-neighbor field on a copy, as the received message may be shared with other nodes.
+	fwd := *msg
+	fwd.FromNeighbor = n.id
+
+	n.forwardTC(&fwd)
+}
+
+// tcListsNode determines whether the TCMessage advertises the node as an MPR selector of its originator.
+func tcListsNode(msg *TCMessage, id NodeID) bool {
+	for _, listed := range append(append([]NodeID(nil), msg.MultipointRelaySet...), msg.Added...) {
+		if listed == id {
+			return true
+		}
+	}
+	return false
+}
+
+// SetWarnListedInTC enables or disables a warning whenever the Node receives a TCMessage listing it as an MPR selector
+// of an originator which is not its symmetric neighbor. See MessageCounters.TCListingSelf.
+func (n *Node) SetWarnListedInTC(enabled bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.warnListedInTC = enabled
+}
+
+// SetTCForwardLimit limits the number of TCMessage(s) the Node forwards per tick, modelling limited airtime. Forwards
+// exceeding the limit are dropped if dropExcess is set, and otherwise deferred to later ticks. A max of zero removes
+// the limit, which is the default.
+func (n *Node) SetTCForwardLimit(max int, dropExcess bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.maxTCForwards = max
+	n.tcForwardPolicy = deferTCForwards
+	if dropExcess {
+		n.tcForwardPolicy = dropTCForwards
+	}
+}
+
+// forwardTC forwards a TCMessage, subject to the Node's limit on TC forwards per tick.
+func (n *Node) forwardTC(msg *TCMessage) {
+	if n.maxTCForwards == 0 || n.tcForwardsThisTick < n.maxTCForwards {
+		n.tcForwardsThisTick++
+		n.transmitTC(msg)
+		return
+	}
+
+	switch n.tcForwardPolicy {
+	case deferTCForwards:
+		n.tcForwardQueue = append(n.tcForwardQueue, msg)
+	case dropTCForwards:
+		n.droppedTCForwards++
+		log.Printf("node %s: dropped tc forward:\t%s", n.id, msg)
+	default:
+		log.Panicf("node %s: invalid tc forward policy: %d", n.id, n.tcForwardPolicy)
+	}
+}
 
-	// Send the updated Message.
-	n.output <- msg
+// flushTCForwards starts a new tick of TC forwarding, first sending TC forwards deferred from previous ticks.
+func (n *Node) flushTCForwards() {
+	n.tcForwardsThisTick = 0
+	for len(n.tcForwardQueue) > 0 && (n.maxTCForwards == 0 || n.tcForwardsThisTick < n.maxTCForwards) {
+		msg := n.tcForwardQueue[0]
+		n.tcForwardQueue = n.tcForwardQueue[1:]
 
-	log.Printf("node %d: Sent:\t%s", n.id, msg)
-	_, err := fmt.Fprintln(n.outputLog, msg)
+		n.tcForwardsThisTick++
+		n.transmitTC(msg)
+	}
+}
+
+// transmitTC sends a TCMessage and logs it to the output log.
+func (n *Node) transmitTC(msg *TCMessage) {
+	if msg.Source == n.id {
+		n.counters.TCSent++
+	} else {
+		n.counters.TCForwarded++
+		n.tcForwardedFor[tcMessageID{source: msg.Source, seq: msg.MessageSequence}]++
+	}
+	n.counters.TCBytes += len(msg.String())
+	n.output.Send(msg)
+
+	log.Printf("node %s: Sent:\t%s", n.id, msg)
+	err := n.writeLog(n.outputLog, "out", msg, msg.String())
 	if err != nil {
-		log.Panicf("node %d: unable to log tc Message to output: %s", n.id, err)
+		log.Panicf("node %s: unable to log tc Message to output: %s", n.id, err)
 	}
 }
 
-// NodeMessage is a message sent by a Node after the specified Delay.
+// nodeMsgRetryInterval is the number of ticks after which a Node retries sending its configured message, if there was
+// no route to its destination.
+const nodeMsgRetryInterval = 30
+
+// nodeMsgMaxRetries is the number of times a Node retries sending its configured message before dropping it.
+const nodeMsgMaxRetries = 3
+
+// tcDuplicateHoldTime is the number of ticks for which a Node remembers a TCMessage it received, to drop its copies.
+const tcDuplicateHoldTime = 30
+
+// NodeMessage is a message sent by a Node after the specified Delay. Without a route to its destination, the Node
+// retries every nodeMsgRetryInterval ticks, up to nodeMsgMaxRetries times, before dropping it.
 type NodeMessage struct {
 	Message     string
 	Delay       int
@@ -593,45 +1607,68 @@ type NodeMessage struct {
 	Sent        bool
 }
 
-// NewNode creates a network Node.
-func NewNode(input <-chan interface{}, output chan<- interface{}, id NodeID, nodeMsg NodeMessage, tickDur time.Duration) *Node {
-	n := Node{}
-	n.id = id
-	n.input = input
-	n.output = output
-	n.nodeMsg = nodeMsg
-	n.tickDuration = tickDur
-
-	_ = os.Mkdir("./log", 0750)
+// NewNode creates a network Node, logging to files within the log directory.
+func NewNode(input <-chan interface{}, output chan<- interface{}, id NodeID, nodeMsg NodeMessage, tickDur time.Duration, logDir string) *Node {
+	_ = os.Mkdir(logDir, 0750)
 
 	// Create logging files for this node.
-	inputLog, err := os.Create(fmt.Sprintf("./log/%d_in.txt", n.id))
+	inputLog, err := os.Create(filepath.Join(logDir, fmt.Sprintf("%s_in.txt", id)))
 	if err != nil {
 		panic(err)
 	}
-	n.inputLog = inputLog
-	outputLog, err := os.Create(fmt.Sprintf("./log/%d_out.txt", n.id))
+	outputLog, err := os.Create(filepath.Join(logDir, fmt.Sprintf("%s_out.txt", id)))
 	if err != nil {
 		panic(err)
 	}
-	n.outputLog = outputLog
-	receivedLog, err := os.Create(fmt.Sprintf("./log/%d_received.txt", n.id))
+	receivedLog, err := os.Create(filepath.Join(logDir, fmt.Sprintf("%s_received.txt", id)))
 	if err != nil {
 		panic(err)
 	}
+
+	return newNode(input, chanTransmitter(output), id, nodeMsg, tickDur, inputLog, outputLog, receivedLog)
+}
+
+// newNode creates a network Node which logs to the supplied writers.
+func newNode(input <-chan interface{}, output Transmitter, id NodeID, nodeMsg NodeMessage, tickDur time.Duration, inputLog, outputLog, receivedLog io.WriteCloser) *Node {
+	n := Node{}
+	n.id = id
+	n.input = input
+	n.output = output
+	n.nodeMsg = nodeMsg
+	n.tickDuration = tickDur
+
+	n.inputLog = inputLog
+	n.outputLog = outputLog
 	n.receivedLog = receivedLog
 
 	n.helloSequences = make(map[NodeID]int)
+	n.helloHistories = make(map[NodeID]helloHistory)
+	n.tcForwardedFor = make(map[tcMessageID]int)
+	n.ecmpNext = make(map[NodeID]int)
 
 	n.routingTable = make(map[NodeID]routingEntry)
 	n.routesChanged = true
 
 	n.topologyTable = make(map[NodeID]map[NodeID]topologyEntry)
 	n.topologyHoldTime = 30
+	n.helloInterval = 5
+	n.tcInterval = 10
+	n.tcRefreshInterval = 3
+	n.willingness = WillDefault
 
 	n.oneHopNeighbors = make(map[NodeID]oneHopNeighborEntry)
 	n.twoHopNeighbors = make(map[NodeID]map[NodeID]NodeID)
 	n.msSet = make(map[NodeID]NodeID)
+	n.mprs = make(map[NodeID]NodeID)
+	n.mprSelector = mapMPRSelector{}
 	n.neighborHoldTime = 15
+	n.msHoldUntil = make(map[NodeID]int)
+	n.msHoldTime = 15
+	n.routeChanges = make(map[NodeID]int)
+	n.midInterval = 10
+	n.midSequences = make(map[NodeID]int)
+	n.tcSequences = make(map[NodeID]tcSequence)
+	n.tcDuplicates = make(map[tcMessageID]int)
+	n.interfaceAssociations = make(map[NodeID]interfaceAssociation)
 	return &n
 }
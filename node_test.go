@@ -1,10 +1,50 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
 	"reflect"
+	"runtime"
+	"strings"
 	"testing"
+	"time"
 )
 
+// bufferCloser is an in-memory io.WriteCloser used in place of a node's log files.
+type bufferCloser struct {
+	bytes.Buffer
+}
+
+func (b *bufferCloser) Close() error {
+	return nil
+}
+
+// recordingTransmitter is a Transmitter which captures every message sent.
+type recordingTransmitter struct {
+	sent []interface{}
+}
+
+func (r *recordingTransmitter) Send(msg interface{}) {
+	r.sent = append(r.sent, msg)
+}
+
+// newTestNode creates a Node which logs to memory and transmits via the supplied Transmitter.
+func newTestNode(id NodeID, output Transmitter) *Node {
+	return newNode(
+		make(chan interface{}),
+		output,
+		id,
+		NodeMessage{Sent: true},
+		time.Millisecond,
+		&bufferCloser{},
+		&bufferCloser{},
+		&bufferCloser{},
+	)
+}
+
 func Test_updateOneHopNeighbors(t *testing.T) {
 	type args struct {
 		msg             *HelloMessage
@@ -120,7 +160,7 @@ func Test_updateOneHopNeighbors(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := updateOneHopNeighbors(tt.args.msg, tt.args.oneHopNeighbors, tt.args.time+tt.args.holdTime, tt.args.id); !reflect.DeepEqual(got, tt.want) {
+			if got := updateOneHopNeighbors(tt.args.msg, tt.args.oneHopNeighbors, tt.args.time+tt.args.holdTime, tt.args.id, nil); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("updateOneHopNeighbors() = %v, want %v", got, tt.want)
 			}
 		})
@@ -208,7 +248,7 @@ func Test_updateTwoHopNeighbors(t *testing.T) {
 	}
 }
 
-func Test_calculateMPRs(t *testing.T) {
+func TestNode_selectMPRs(t *testing.T) {
 	type args struct {
 		oneHopNeighbors map[NodeID]oneHopNeighborEntry
 		twoHopNeighbors map[NodeID]map[NodeID]NodeID
@@ -299,11 +339,23 @@ func Test_calculateMPRs(t *testing.T) {
 				},
 			},
 		},
+		{
+			name: "no neighbors",
+			args: args{
+				oneHopNeighbors: map[NodeID]oneHopNeighborEntry{},
+				twoHopNeighbors: map[NodeID]map[NodeID]NodeID{},
+			},
+			want: map[NodeID]oneHopNeighborEntry{},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := calculateMPRs(tt.args.oneHopNeighbors, tt.args.twoHopNeighbors); !reflect.DeepEqual(got, tt.want) {
-				t.Errorf("calculateMPRs() = %v, want %v", got, tt.want)
+			n := newTestNode(0, &recordingTransmitter{})
+			n.oneHopNeighbors = tt.args.oneHopNeighbors
+			n.twoHopNeighbors = tt.args.twoHopNeighbors
+			n.selectMPRs()
+			if got := n.oneHopNeighbors; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("selectMPRs() = %v, want %v", got, tt.want)
 			}
 		})
 	}
@@ -467,9 +519,1562 @@ func Test_updateTopologyTable1(t *testing.T) {
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if got := updateTopologyTable(tt.args.msg, tt.args.topologyTable, tt.args.holdTime, tt.args.id); !reflect.DeepEqual(got, tt.want) {
+			sequences := tcSequencesOf(tt.args.topologyTable)
+			if got := updateTopologyTable(tt.args.msg, tt.args.topologyTable, sequences, tt.args.holdTime, tt.args.id, nil); !reflect.DeepEqual(got, tt.want) {
 				t.Errorf("updateTopologyTable() = %v, want %v", got, tt.want)
 			}
 		})
 	}
 }
+
+// tcSequencesOf records the sequence number of each originator's entries in the topology table, as if the TCMessage(s)
+// which added them were the newest accepted.
+func tcSequencesOf(topologyTable map[NodeID]map[NodeID]topologyEntry) map[NodeID]tcSequence {
+	sequences := make(map[NodeID]tcSequence)
+	for originator, entries := range topologyTable {
+		for _, entry := range entries {
+			sequences[originator] = tcSequence{seq: entry.seq, holdUntil: entry.holdUntil}
+		}
+	}
+	return sequences
+}
+
+func Test_buildHello(t *testing.T) {
+	type args struct {
+		oneHop map[NodeID]oneHopNeighborEntry
+		src    NodeID
+	}
+	tests := []struct {
+		name string
+		args args
+		want *HelloMessage
+	}{
+		{
+			name: "no neighbors",
+			args: args{
+				oneHop: map[NodeID]oneHopNeighborEntry{},
+				src:    0,
+			},
+			want: &HelloMessage{
+				Source:          0,
+				Unidirectional:  []NodeID{},
+				Bidirectional:   []NodeID{},
+				MultipointRelay: []NodeID{},
+			},
+		},
+		{
+			name: "mixed states sorted",
+			args: args{
+				oneHop: map[NodeID]oneHopNeighborEntry{
+					NodeID(6): {neighborID: 6, state: bidirectional},
+					NodeID(3): {neighborID: 3, state: unidirectional},
+					NodeID(5): {neighborID: 5, state: mpr},
+					NodeID(1): {neighborID: 1, state: unidirectional},
+					NodeID(2): {neighborID: 2, state: bidirectional},
+					NodeID(4): {neighborID: 4, state: mpr},
+				},
+				src: 0,
+			},
+			want: &HelloMessage{
+				Source:          0,
+				Unidirectional:  []NodeID{1, 3},
+				Bidirectional:   []NodeID{2, 6},
+				MultipointRelay: []NodeID{4, 5},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := buildHello(tt.args.oneHop, tt.args.src); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("buildHello() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNode_validateNeighbors(t *testing.T) {
+	tests := []struct {
+		name              string
+		validateNeighbors bool
+		neighbors         map[NodeID]oneHopNeighborEntry
+		wantData          string
+		wantTopology      bool
+	}{
+		{
+			name:              "validation disabled",
+			validateNeighbors: false,
+			neighbors:         map[NodeID]oneHopNeighborEntry{},
+			wantData:          "data\n",
+			wantTopology:      true,
+		},
+		{
+			name:              "unknown neighbor dropped",
+			validateNeighbors: true,
+			neighbors:         map[NodeID]oneHopNeighborEntry{},
+			wantData:          "",
+			wantTopology:      false,
+		},
+		{
+			name:              "known neighbor accepted",
+			validateNeighbors: true,
+			neighbors: map[NodeID]oneHopNeighborEntry{
+				NodeID(1): {neighborID: 1, state: bidirectional, holdUntil: 15},
+			},
+			wantData:     "data\n",
+			wantTopology: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := newTestNode(0, &recordingTransmitter{})
+			n.SetValidateNeighbors(tt.validateNeighbors)
+			n.oneHopNeighbors = tt.neighbors
+
+			n.handleData(&DataMessage{Source: 2, Destination: 0, NextHop: 0, FromNeighbor: 1, Data: "data"})
+			if got := n.receivedLog.(*bufferCloser).String(); got != tt.wantData {
+				t.Errorf("handleData() received = %q, want %q", got, tt.wantData)
+			}
+
+			n.handleTC(&TCMessage{Source: 2, FromNeighbor: 1, Sequence: 0, MultipointRelaySet: []NodeID{3}})
+			if _, got := n.topologyTable[2]; got != tt.wantTopology {
+				t.Errorf("handleTC() stored = %v, want %v", got, tt.wantTopology)
+			}
+		})
+	}
+}
+
+func TestNode_handleHelloLinkQuality(t *testing.T) {
+	n := newTestNode(0, &recordingTransmitter{})
+	n.handleHello(&HelloMessage{
+		Source:        1,
+		Bidirectional: []NodeID{0},
+		LinkQuality:   map[NodeID]float64{0: 0.75},
+		Sequence:      0,
+	})
+	if got := n.oneHopNeighbors[1].neighborLinkQuality; got != 0.75 {
+		t.Errorf("handleHello() neighborLinkQuality = %v, want %v", got, 0.75)
+	}
+
+	n.handleHello(&HelloMessage{Source: 2, Sequence: 0})
+	if got := n.oneHopNeighbors[2].neighborLinkQuality; got != 0 {
+		t.Errorf("handleHello() neighborLinkQuality = %v, want %v", got, 0)
+	}
+}
+
+func TestNode_emptyNetwork(t *testing.T) {
+	out := &recordingTransmitter{}
+	n := newTestNode(0, out)
+
+	n.selectMPRs()
+	n.sendHello()
+	if got, want := out.sent[0].(*HelloMessage).String(), "* 0 HELLO UNIDIR  BIDIR  MPR "; got != want {
+		t.Errorf("sendHello() = %q, want %q", got, want)
+	}
+
+	n.calculateRoutingTable()
+	if len(n.routingTable) != 0 {
+		t.Errorf("calculateRoutingTable() = %v, want empty", n.routingTable)
+	}
+}
+
+func Test_uncoveredTwoHops(t *testing.T) {
+	type args struct {
+		oneHopNeighbors map[NodeID]oneHopNeighborEntry
+		twoHopNeighbors map[NodeID]map[NodeID]NodeID
+	}
+	tests := []struct {
+		name string
+		args args
+		want []NodeID
+	}{
+		{
+			name: "all covered",
+			args: args{
+				oneHopNeighbors: map[NodeID]oneHopNeighborEntry{
+					NodeID(1): {neighborID: 1, state: mpr},
+					NodeID(2): {neighborID: 2, state: bidirectional},
+				},
+				twoHopNeighbors: map[NodeID]map[NodeID]NodeID{
+					NodeID(1): {NodeID(3): NodeID(3), NodeID(4): NodeID(4)},
+					NodeID(2): {NodeID(3): NodeID(3)},
+				},
+			},
+			want: []NodeID{},
+		},
+		{
+			name: "only via unidirectional neighbor",
+			args: args{
+				oneHopNeighbors: map[NodeID]oneHopNeighborEntry{
+					NodeID(1): {neighborID: 1, state: mpr},
+					NodeID(2): {neighborID: 2, state: unidirectional},
+				},
+				twoHopNeighbors: map[NodeID]map[NodeID]NodeID{
+					NodeID(1): {NodeID(3): NodeID(3)},
+					NodeID(2): {NodeID(5): NodeID(5), NodeID(4): NodeID(4), NodeID(3): NodeID(3)},
+				},
+			},
+			want: []NodeID{4, 5},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := uncoveredTwoHops(tt.args.oneHopNeighbors, tt.args.twoHopNeighbors); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("uncoveredTwoHops() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNode_UncoveredTwoHops(t *testing.T) {
+	n := newTestNode(0, &recordingTransmitter{})
+	if got := n.UncoveredTwoHops(); len(got) != 0 {
+		t.Errorf("UncoveredTwoHops() = %v, want empty", got)
+	}
+
+	// A unidirectional neighbor advertising a two-hop neighbor.
+	n.handleHello(&HelloMessage{Source: 1, Bidirectional: []NodeID{2}, Sequence: 0})
+	if got, want := n.UncoveredTwoHops(), []NodeID{2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("UncoveredTwoHops() = %v, want %v", got, want)
+	}
+
+	// The neighbor becomes bidirectional and is selected as an mpr.
+	n.handleHello(&HelloMessage{Source: 1, Bidirectional: []NodeID{0, 2}, Sequence: 1})
+	if got := n.UncoveredTwoHops(); len(got) != 0 {
+		t.Errorf("UncoveredTwoHops() = %v, want empty", got)
+	}
+
+	// Another unidirectional neighbor advertising a two-hop neighbor, which is uncovered until that neighbor expires.
+	n.handleHello(&HelloMessage{Source: 3, Bidirectional: []NodeID{4}, Sequence: 0})
+	if got, want := n.UncoveredTwoHops(), []NodeID{4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("UncoveredTwoHops() = %v, want %v", got, want)
+	}
+	entry := n.oneHopNeighbors[3]
+	entry.holdUntil = n.currentTick
+	n.oneHopNeighbors[3] = entry
+	n.tick(nil)
+	if got := n.UncoveredTwoHops(); len(got) != 0 {
+		t.Errorf("UncoveredTwoHops() after expiry = %v, want empty", got)
+	}
+}
+
+func TestNode_sendTransmits(t *testing.T) {
+	out := &recordingTransmitter{}
+	n := newTestNode(0, out)
+	n.oneHopNeighbors[1] = oneHopNeighborEntry{neighborID: 1, state: bidirectional, holdUntil: 15}
+	n.msSet[1] = 1
+	n.calculateRoutingTable()
+
+	n.sendHello()
+	n.sendTC()
+	n.sendData(&DataMessage{Source: 0, Destination: 1, Data: "data"})
+
+	want := []string{
+		"* 0 HELLO UNIDIR  BIDIR 1 MPR ",
+		"* 0 TC 0 0 MS 1",
+		"1 0 DATA 0 1 data",
+	}
+	if len(out.sent) != len(want) {
+		t.Fatalf("sent %d messages, want %d", len(out.sent), len(want))
+	}
+	for i, msg := range out.sent {
+		if got := msg.(fmt.Stringer).String(); got != want[i] {
+			t.Errorf("sent[%d] = %q, want %q", i, got, want[i])
+		}
+	}
+}
+
+func TestNode_forwardTCLimit(t *testing.T) {
+	tests := []struct {
+		name        string
+		dropExcess  bool
+		wantSent    []NodeID
+		wantFlushed []NodeID
+		wantDropped int
+	}{
+		{
+			name:        "defer",
+			dropExcess:  false,
+			wantSent:    []NodeID{2},
+			wantFlushed: []NodeID{2, 3},
+			wantDropped: 0,
+		},
+		{
+			name:        "drop",
+			dropExcess:  true,
+			wantSent:    []NodeID{2},
+			wantFlushed: []NodeID{2},
+			wantDropped: 2,
+		},
+	}
+	sources := func(msgs []interface{}) []NodeID {
+		ids := make([]NodeID, 0)
+		for _, msg := range msgs {
+			ids = append(ids, msg.(*TCMessage).Source)
+		}
+		return ids
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &recordingTransmitter{}
+			n := newTestNode(0, out)
+			n.SetTCForwardLimit(1, tt.dropExcess)
+			n.msSet[1] = 1
+
+			for _, src := range []NodeID{2, 3, 4} {
+				n.handleTC(&TCMessage{Source: src, FromNeighbor: 1, Sequence: 0, MultipointRelaySet: []NodeID{1}})
+			}
+			if got := sources(out.sent); !reflect.DeepEqual(got, tt.wantSent) {
+				t.Errorf("forwarded = %v, want %v", got, tt.wantSent)
+			}
+
+			n.flushTCForwards()
+			if got := sources(out.sent); !reflect.DeepEqual(got, tt.wantFlushed) {
+				t.Errorf("forwarded after flush = %v, want %v", got, tt.wantFlushed)
+			}
+			if n.droppedTCForwards != tt.wantDropped {
+				t.Errorf("droppedTCForwards = %d, want %d", n.droppedTCForwards, tt.wantDropped)
+			}
+			for _, msg := range out.sent {
+				if from := msg.(*TCMessage).FromNeighbor; from != n.id {
+					t.Errorf("forwarded FromNeighbor = %d, want %d", from, n.id)
+				}
+			}
+		})
+	}
+}
+
+func TestNodeID_String(t *testing.T) {
+	tests := []struct {
+		name string
+		n    NodeID
+		want string
+	}{
+		{
+			name: "small integer",
+			n:    7,
+			want: "7",
+		},
+		{
+			name: "below threshold",
+			n:    dottedQuadThreshold - 1,
+			want: "65535",
+		},
+		{
+			name: "dotted-quad",
+			n:    0x0A000001,
+			want: "10.0.0.1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.n.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNode_Originate(t *testing.T) {
+	out := &recordingTransmitter{}
+	n := newTestNode(0, out)
+	n.oneHopNeighbors[1] = oneHopNeighborEntry{neighborID: 1, state: bidirectional, holdUntil: 1000}
+	n.calculateRoutingTable()
+
+	n.Originate(1, "routed")
+	n.Originate(2, "unroutable")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	n.Run(ctx)
+
+	var data []string
+	for _, msg := range out.sent {
+		if dm, ok := msg.(*DataMessage); ok {
+			data = append(data, dm.String())
+		}
+	}
+	if want := []string{"1 0 DATA 0 1 routed"}; !reflect.DeepEqual(data, want) {
+		t.Errorf("Originate() sent = %v, want %v", data, want)
+	}
+}
+
+func TestNode_handleDataPath(t *testing.T) {
+	out := &recordingTransmitter{}
+	n := newTestNode(1, out)
+	n.oneHopNeighbors[2] = oneHopNeighborEntry{neighborID: 2, state: bidirectional, holdUntil: 15}
+	n.calculateRoutingTable()
+
+	n.handleData(&DataMessage{Source: 0, Destination: 2, NextHop: 1, FromNeighbor: 0, Data: "data", Path: []NodeID{3}})
+	if len(out.sent) != 1 {
+		t.Fatalf("handleData() sent %d messages, want 1", len(out.sent))
+	}
+	fwd := out.sent[0].(*DataMessage)
+	if want := []NodeID{3, 1}; !reflect.DeepEqual(fwd.Path, want) {
+		t.Errorf("handleData() Path = %v, want %v", fwd.Path, want)
+	}
+	if got, want := fwd.String(), "2 1 DATA 0 2 data"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+// TestNode_tickDurationIndependence checks that the protocol state reached after a number of ticks does not depend on
+// the tick duration, by running the same lock-step simulation with different durations.
+func TestNode_tickDurationIndependence(t *testing.T) {
+	run := func(d time.Duration) ([]NodeState, MessageCounters) {
+		topology, err := NewNetworkTypology(strings.NewReader("0 UP 0 1\n0 UP 1 0\n0 UP 1 2\n0 UP 2 1\n20 DOWN 1 2\n"))
+		if err != nil {
+			t.Fatalf("NewNetworkTypology() error = %v", err)
+		}
+		c := NewController(*topology, d)
+		c.SetLogDir(t.TempDir())
+		c.Initialize([]NodeConfig{
+			{ID: 0, Message: NodeMessage{Sent: true}},
+			{ID: 1, Message: NodeMessage{Sent: true}},
+			{ID: 2, Message: NodeMessage{Sent: true}},
+		})
+		c.SetSynchronous(true)
+		c.Start(40)
+
+		states := make([]NodeState, 0)
+		var counters MessageCounters
+		for _, id := range []NodeID{0, 1, 2} {
+			n, _ := c.node(id)
+			states = append(states, n.Snapshot())
+			if id == 0 {
+				counters = n.Counters()
+			}
+		}
+		return states, counters
+	}
+
+	wantStates, wantCounters := run(time.Millisecond)
+	if wantCounters.HelloSent == 0 {
+		t.Fatalf("node 0 sent no HELLOs")
+	}
+	for _, d := range []time.Duration{10 * time.Millisecond, time.Second, time.Hour} {
+		t.Run(d.String(), func(t *testing.T) {
+			states, counters := run(d)
+			if !reflect.DeepEqual(states, wantStates) {
+				t.Errorf("states = %+v, want %+v", states, wantStates)
+			}
+			if counters != wantCounters {
+				t.Errorf("node 0 counters = %+v, want %+v", counters, wantCounters)
+			}
+		})
+	}
+}
+
+func TestNode_sendTCANSN(t *testing.T) {
+	tests := []struct {
+		name        string
+		incremental bool
+		want        []int
+	}{
+		{name: "full", want: []int{0, 0, 1, 1, 2}},
+		// Every delta takes a new ANSN, while the fourth TC, a full refresh, increments it only on a change.
+		{name: "incremental", incremental: true, want: []int{0, 1, 2, 2, 3}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &recordingTransmitter{}
+			n := newTestNode(0, out)
+			n.SetIncrementalTC(tt.incremental, 2)
+
+			n.msSet[1] = 1
+			n.sendTC()
+			n.sendTC()
+			n.msSet[2] = 2
+			n.sendTC()
+			n.sendTC()
+			delete(n.msSet, 1)
+			n.sendTC()
+
+			var got []int
+			for _, msg := range out.sent {
+				got = append(got, msg.(*TCMessage).Sequence)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sendTC() sequences = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_updateOneHopNeighborsLogsTransitions(t *testing.T) {
+	var buf bytes.Buffer
+	transitions := log.New(&buf, "", 0)
+
+	neighbors := map[NodeID]oneHopNeighborEntry{
+		NodeID(1): {neighborID: 1, state: mpr, holdUntil: 15},
+	}
+
+	// Still advertises this node, so an mpr remains symmetric.
+	updateOneHopNeighbors(&HelloMessage{Source: 1, Bidirectional: []NodeID{0}}, neighbors, 20, 0, transitions)
+	if buf.Len() != 0 {
+		t.Errorf("logged %q, want nothing", buf.String())
+	}
+
+	// No longer advertises this node.
+	updateOneHopNeighbors(&HelloMessage{Source: 1}, neighbors, 25, 0, transitions)
+	if want := "node 0: neighbor 1: relationship changed: BIDIR -> UNIDIR"; !strings.Contains(buf.String(), want) {
+		t.Errorf("logged %q, want %q", buf.String(), want)
+	}
+}
+
+func TestNode_SetNeighborTransitionLog(t *testing.T) {
+	var buf bytes.Buffer
+	logged := newTestNode(0, &recordingTransmitter{})
+	logged.SetNeighborTransitionLog(log.New(&buf, "", 0))
+	// Another Node's messages are not logged, as each Node has its own log.
+	other := newTestNode(2, &recordingTransmitter{})
+
+	for _, n := range []*Node{logged, other} {
+		n.handleHello(&HelloMessage{Source: 1, Sequence: 0})
+		n.handleHello(&HelloMessage{Source: 1, Sequence: 1, Bidirectional: []NodeID{0}})
+	}
+	if got, want := buf.String(), "node 0: neighbor 1: relationship changed: UNIDIR -> BIDIR\n"; got != want {
+		t.Errorf("logged %q, want %q", got, want)
+	}
+}
+
+func TestNode_tickOrdering(t *testing.T) {
+	out := &recordingTransmitter{}
+	n := newTestNode(0, out)
+
+	// Tick 0: a neighbor is discovered in phase 1, so the HELLO sent in phase 2 advertises it.
+	n.tick([]interface{}{&HelloMessage{Source: 1, Sequence: 0}})
+	if got, want := out.sent[0].(*HelloMessage).String(), "* 0 HELLO UNIDIR 1 BIDIR  MPR "; got != want {
+		t.Errorf("tick 0 sent %q, want %q", got, want)
+	}
+
+	// A route learned in phase 1 is available by the end of the tick.
+	n.tick([]interface{}{&HelloMessage{Source: 1, Bidirectional: []NodeID{0}, Sequence: 1}})
+	if _, in := n.routingTable[1]; !in {
+		t.Errorf("tick 1 routingTable = %v, want route to 1", n.routingTable)
+	}
+	if n.currentTick != 2 {
+		t.Errorf("currentTick = %d, want 2", n.currentTick)
+	}
+}
+
+func TestNode_receiveAll(t *testing.T) {
+	out := &recordingTransmitter{}
+	n := newTestNode(0, out)
+	in := make(chan interface{}, 3)
+	n.input = in
+	for _, src := range []NodeID{1, 2, 3} {
+		in <- &HelloMessage{Source: src, Sequence: 0}
+	}
+
+	msgs, _ := n.receiveAll()
+	if len(msgs) != 3 {
+		t.Fatalf("receiveAll() returned %d messages, want 3", len(msgs))
+	}
+	if len(in) != 0 {
+		t.Errorf("receiveAll() left %d messages queued, want 0", len(in))
+	}
+
+	// All three arrivals are processed within the tick they arrive.
+	n.tick(msgs)
+	if got, want := out.sent[0].(*HelloMessage).String(), "* 0 HELLO UNIDIR 1 2 3 BIDIR  MPR "; got != want {
+		t.Errorf("tick 0 sent %q, want %q", got, want)
+	}
+	if got, _ := n.receiveAll(); len(got) != 0 {
+		t.Errorf("receiveAll() on empty input returned %d messages, want 0", len(got))
+	}
+}
+
+func TestNode_handleTCSelfEntry(t *testing.T) {
+	n := newTestNode(0, &recordingTransmitter{})
+	n.oneHopNeighbors[1] = oneHopNeighborEntry{neighborID: 1, state: bidirectional, holdUntil: 15}
+
+	n.handleTC(&TCMessage{Source: 2, FromNeighbor: 1, Sequence: 0, MultipointRelaySet: []NodeID{0, 3}})
+	for _, entry := range n.TopologyEntries() {
+		if entry.Destination == n.id {
+			t.Errorf("handleTC() created self entry %v", entry)
+		}
+	}
+
+	n.calculateRoutingTable()
+	if route, in := n.routingTable[n.id]; in {
+		t.Errorf("calculateRoutingTable() created self route %v", route)
+	}
+}
+
+// lockstepNetwork runs Nodes tick by tick without goroutines, delivering each message sent during a tick to the
+// sender's neighbors at the start of the next tick.
+type lockstepNetwork struct {
+	nodes map[NodeID]*Node
+
+	// links maps each Node to the neighbors which receive its messages.
+	links map[NodeID][]NodeID
+
+	outputs map[NodeID]*recordingTransmitter
+	logs    map[NodeID]*bufferCloser
+}
+
+// newLockstepNetwork creates a Node for every key in links.
+func newLockstepNetwork(links map[NodeID][]NodeID) *lockstepNetwork {
+	l := &lockstepNetwork{
+		nodes:   make(map[NodeID]*Node),
+		links:   links,
+		outputs: make(map[NodeID]*recordingTransmitter),
+		logs:    make(map[NodeID]*bufferCloser),
+	}
+	for id := range links {
+		out := &recordingTransmitter{}
+		buf := &bufferCloser{}
+		l.outputs[id] = out
+		l.logs[id] = buf
+		l.nodes[id] = newNode(make(chan interface{}), out, id, NodeMessage{Sent: true}, time.Millisecond, buf, buf, buf)
+		l.nodes[id].SetStrict(true)
+	}
+	return l
+}
+
+// run runs every Node, in NodeID order, for the given number of ticks.
+func (l *lockstepNetwork) run(ticks int) {
+	inboxes := make(map[NodeID][]interface{})
+	for i := 0; i < ticks; i++ {
+		for _, id := range sortedNodeIDs(l.nodes) {
+			l.nodes[id].tick(inboxes[id])
+		}
+		inboxes = make(map[NodeID][]interface{})
+		for _, id := range sortedNodeIDs(l.outputs) {
+			out := l.outputs[id]
+			for _, msg := range out.sent {
+				for _, neighbor := range l.links[id] {
+					inboxes[neighbor] = append(inboxes[neighbor], msg)
+				}
+			}
+			out.sent = nil
+		}
+	}
+}
+
+// log concatenates every Node's logs in NodeID order.
+func (l *lockstepNetwork) log() string {
+	var b strings.Builder
+	for _, id := range sortedNodeIDs(l.logs) {
+		b.WriteString(l.logs[id].String())
+	}
+	return b.String()
+}
+
+func TestNode_deterministicRuns(t *testing.T) {
+	// Node 3 is reachable from 0 via either 1 or 2, so MPR selection and routing must break a tie.
+	links := map[NodeID][]NodeID{
+		0: {1, 2},
+		1: {0, 3},
+		2: {0, 3},
+		3: {1, 2, 4},
+		4: {3},
+	}
+
+	first := newLockstepNetwork(links)
+	first.run(60)
+	for i := 0; i < 5; i++ {
+		again := newLockstepNetwork(links)
+		again.run(60)
+		if got, want := again.log(), first.log(); got != want {
+			t.Fatalf("run %d logs differ from the first run", i+1)
+		}
+	}
+
+	n := first.nodes[0]
+	if got, want := n.Snapshot().MPRs, []NodeID{1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MPRs = %v, want %v", got, want)
+	}
+	if got, want := n.routingTable[3].nextHop, NodeID(1); got != want {
+		t.Errorf("next hop to 3 = %d, want %d", got, want)
+	}
+	if got, want := n.routingTable[4].nextHop, NodeID(1); got != want {
+		t.Errorf("next hop to 4 = %d, want %d", got, want)
+	}
+}
+
+func TestNode_handleTC_listsSelf(t *testing.T) {
+	tests := []struct {
+		name      string
+		tc        *TCMessage
+		wantCount int
+		wantLog   bool
+	}{
+		{name: "not listed", tc: &TCMessage{Source: 3, FromNeighbor: 1, Sequence: 1, MultipointRelaySet: []NodeID{4}}},
+		{name: "listed by mpr", tc: &TCMessage{Source: 1, FromNeighbor: 1, Sequence: 1, MultipointRelaySet: []NodeID{0, 4}}},
+		{name: "listed by distant node", tc: &TCMessage{Source: 3, FromNeighbor: 1, Sequence: 1, MultipointRelaySet: []NodeID{0, 4}}, wantCount: 1, wantLog: true},
+		{name: "added by distant node", tc: &TCMessage{Source: 3, FromNeighbor: 1, Sequence: 1, Incremental: true, Added: []NodeID{0}}, wantCount: 1, wantLog: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			log.SetOutput(&buf)
+			defer log.SetOutput(os.Stderr)
+
+			n := newTestNode(0, &recordingTransmitter{})
+			n.SetWarnListedInTC(true)
+			n.oneHopNeighbors[1] = oneHopNeighborEntry{neighborID: 1, state: mpr, holdUntil: 15}
+			n.handleTC(tt.tc)
+
+			if got := n.Counters().TCListingSelf; got != tt.wantCount {
+				t.Errorf("TCListingSelf = %d, want %d", got, tt.wantCount)
+			}
+			if got := strings.Contains(buf.String(), "lists this node as an MPR selector"); got != tt.wantLog {
+				t.Errorf("logged %q, want warning %v", buf.String(), tt.wantLog)
+			}
+			if _, in := n.topologyTable[tt.tc.Source][0]; in {
+				t.Errorf("topologyTable[%d] contains this node", tt.tc.Source)
+			}
+		})
+	}
+}
+
+func TestNode_handleTCHoldTime(t *testing.T) {
+	var buf bytes.Buffer
+	log.SetOutput(&buf)
+	defer log.SetOutput(os.Stderr)
+
+	tc := &TCMessage{Source: 2, FromNeighbor: 1, Sequence: 1, MultipointRelaySet: []NodeID{3}}
+
+	n := newTestNode(0, &recordingTransmitter{})
+	n.handleTC(tc)
+	if buf.Len() != 0 {
+		t.Errorf("logged %q, want nothing", buf.String())
+	}
+
+	n = newTestNode(0, &recordingTransmitter{})
+	n.topologyHoldTime = 0
+	n.handleTC(tc)
+	if want := "node 0: WARNING: topology hold time is not positive"; !strings.Contains(buf.String(), want) {
+		t.Errorf("logged %q, want %q", buf.String(), want)
+	}
+
+	n = newTestNode(0, &recordingTransmitter{})
+	n.topologyHoldTime = 0
+	n.SetStrict(true)
+	defer func() {
+		if recover() == nil {
+			t.Errorf("handleTC() did not panic in strict mode")
+		}
+	}()
+	n.handleTC(tc)
+}
+
+func TestNode_handleTCStrictOLSR(t *testing.T) {
+	tests := []struct {
+		name       string
+		strictOLSR bool
+		state      NeighborState
+		known      bool
+		wantStored bool
+	}{
+		{name: "bidirectional sender", strictOLSR: true, state: bidirectional, known: true, wantStored: true},
+		{name: "mpr sender", strictOLSR: true, state: mpr, known: true, wantStored: true},
+		{name: "unidirectional sender", strictOLSR: true, state: unidirectional, known: true, wantStored: false},
+		{name: "unknown sender", strictOLSR: true, wantStored: false},
+		{name: "unidirectional sender, not strict", strictOLSR: false, state: unidirectional, known: true, wantStored: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := newTestNode(0, &recordingTransmitter{})
+			n.SetStrictOLSR(tt.strictOLSR)
+			if tt.known {
+				n.oneHopNeighbors[1] = oneHopNeighborEntry{neighborID: 1, state: tt.state, holdUntil: 15}
+			}
+			n.handleTC(&TCMessage{Source: 2, FromNeighbor: 1, Sequence: 1, MultipointRelaySet: []NodeID{3}})
+			if _, got := n.topologyTable[2]; got != tt.wantStored {
+				t.Errorf("topology entry stored = %v, want %v", got, tt.wantStored)
+			}
+		})
+	}
+}
+
+func TestNode_sendDataAfterMPRDemotion(t *testing.T) {
+	out := &recordingTransmitter{}
+	n := newTestNode(0, out)
+
+	// Node 2 is reachable via mpr 1.
+	n.tick([]interface{}{
+		&HelloMessage{Source: 1, Sequence: 0},
+		&HelloMessage{Source: 1, Bidirectional: []NodeID{0, 2}, Sequence: 1},
+	})
+	if got := n.routingTable[2].nextHop; got != 1 {
+		t.Fatalf("next hop to 2 = %d, want 1", got)
+	}
+
+	// Within a single tick, 1 loses its link to 2 and is demoted, then 3 offers a new path to 2.
+	// Data for 2 forwarded in the same tick must not use the stale next hop.
+	out.sent = nil
+	n.tick([]interface{}{
+		&HelloMessage{Source: 1, Bidirectional: []NodeID{0}, Sequence: 2},
+		&DataMessage{Source: 4, Destination: 2, FromNeighbor: 1, Data: "before"},
+		&HelloMessage{Source: 3, Sequence: 0},
+		&HelloMessage{Source: 3, Bidirectional: []NodeID{0, 2}, Sequence: 1},
+		&DataMessage{Source: 4, Destination: 2, FromNeighbor: 1, Data: "after"},
+	})
+	if got := n.oneHopNeighbors[1].state; got != bidirectional {
+		t.Errorf("neighbor 1 state = %s, want %s", got, bidirectional)
+	}
+
+	var data []*DataMessage
+	for _, msg := range out.sent {
+		if d, ok := msg.(*DataMessage); ok {
+			data = append(data, d)
+		}
+	}
+	if len(data) != 1 {
+		t.Fatalf("sent %d data messages, want 1", len(data))
+	}
+	if data[0].Data != "after" || data[0].NextHop != 3 {
+		t.Errorf("sent %q via %d, want %q via 3", data[0].Data, data[0].NextHop, "after")
+	}
+	if n.counters.DataDropped != 1 {
+		t.Errorf("DataDropped = %d, want 1", n.counters.DataDropped)
+	}
+}
+
+func TestNode_emptyTCIntervals(t *testing.T) {
+	tests := []struct {
+		name             string
+		emptyTCIntervals int
+		want             []string
+	}{
+		{
+			name:             "stop immediately",
+			emptyTCIntervals: 0,
+			want:             []string{"0:[1]", "10:[1]", "50:[2]"},
+		},
+		{
+			name:             "flush then stop",
+			emptyTCIntervals: 2,
+			want:             []string{"0:[1]", "10:[1]", "20:[]", "30:[]", "50:[2]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &recordingTransmitter{}
+			n := newTestNode(0, out)
+			n.SetEmptyTCIntervals(tt.emptyTCIntervals)
+
+			got := make([]string, 0)
+			for tick := 0; tick < 60; tick++ {
+				switch tick {
+				case 0:
+					n.msSet[1] = 1
+				case 15:
+					delete(n.msSet, 1)
+				case 45:
+					n.msSet[2] = 2
+				}
+				out.sent = nil
+				n.tick(nil)
+				for _, msg := range out.sent {
+					if tc, ok := msg.(*TCMessage); ok {
+						got = append(got, fmt.Sprintf("%d:%v", tick, tc.MultipointRelaySet))
+					}
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TCs sent = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNode_firstTCDeferred(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(n *Node)
+		want  []string
+	}{
+		{
+			name:  "no neighbors",
+			setup: func(n *Node) {},
+			want:  []string{"*main.HelloMessage"},
+		},
+		{
+			// An empty TC would otherwise be due to flush the previously advertised set.
+			name: "previously advertised, no neighbors",
+			setup: func(n *Node) {
+				n.advertisedMSSet = []NodeID{1}
+				n.SetEmptyTCIntervals(2)
+			},
+			want: []string{"*main.HelloMessage"},
+		},
+		{
+			name: "neighbor known",
+			setup: func(n *Node) {
+				n.oneHopNeighbors[1] = oneHopNeighborEntry{neighborID: 1, state: bidirectional, holdUntil: 15}
+				n.msSet[1] = 1
+			},
+			want: []string{"*main.HelloMessage", "*main.TCMessage"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &recordingTransmitter{}
+			n := newTestNode(0, out)
+			tt.setup(n)
+			n.tick(nil)
+
+			got := make([]string, 0)
+			for _, msg := range out.sent {
+				got = append(got, fmt.Sprintf("%T", msg))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tick 0 sent %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNode_msHoldTime(t *testing.T) {
+	tests := []struct {
+		name string
+		// hellos are the ticks at which neighbor 1 sends a HelloMessage selecting the node as an mpr.
+		hellos []int
+		want   []bool
+	}{
+		{name: "aged out", hellos: []int{0}, want: []bool{true, true, true, true, false, false, false, false}},
+		{name: "refreshed", hellos: []int{0, 3}, want: []bool{true, true, true, true, true, true, true, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := newTestNode(0, &recordingTransmitter{})
+			n.SetMSHoldTime(4)
+
+			got := make([]bool, 0)
+			for tick, seq := 0, 0; tick < len(tt.want); tick++ {
+				msgs := make([]interface{}, 0)
+				for _, at := range tt.hellos {
+					if at == tick {
+						msgs = append(msgs, &HelloMessage{Source: 1, MultipointRelay: []NodeID{0}, Sequence: seq})
+						seq++
+					}
+				}
+				n.tick(msgs)
+				// The neighbor itself is held for longer than the MS entry.
+				if _, in := n.oneHopNeighbors[1]; !in {
+					t.Fatalf("tick %d: neighbor 1 expelled", tick)
+				}
+				_, in := n.msSet[1]
+				got = append(got, in)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("msSet holds 1 after each tick = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_seqNewer(t *testing.T) {
+	tests := []struct {
+		a, b int
+		want bool
+	}{
+		{a: 1, b: 0, want: true},
+		{a: 0, b: 1, want: false},
+		{a: 5, b: 5, want: false},
+		{a: seqMax / 2, b: 0, want: true},
+		{a: seqMax/2 + 1, b: 0, want: false},
+		// Wrapped around from seqMax-1 to 0.
+		{a: 0, b: seqMax - 1, want: true},
+		{a: seqMax - 1, b: 0, want: false},
+		{a: 3, b: seqMax - 10, want: true},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%d>%d", tt.a, tt.b), func(t *testing.T) {
+			if got := seqNewer(tt.a, tt.b); got != tt.want {
+				t.Errorf("seqNewer(%d, %d) = %v, want %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_updateTopologyTableSequences(t *testing.T) {
+	var buf bytes.Buffer
+	staleTCs := log.New(&buf, "", 0)
+
+	tc := func(seq int, mprSet ...NodeID) *TCMessage {
+		return &TCMessage{Source: 2, FromNeighbor: 1, Sequence: seq, MultipointRelaySet: mprSet}
+	}
+	seqs := func(table map[NodeID]map[NodeID]topologyEntry) map[NodeID]int {
+		got := make(map[NodeID]int)
+		for dst, entry := range table[2] {
+			got[dst] = entry.seq
+		}
+		return got
+	}
+
+	// The first TC records whatever sequence number it carries.
+	sequences := make(map[NodeID]tcSequence)
+	table := updateTopologyTable(tc(seqMax-2, 3), make(map[NodeID]map[NodeID]topologyEntry), sequences, 30, 0, staleTCs)
+	if got, want := seqs(table), map[NodeID]int{3: seqMax - 2}; !reflect.DeepEqual(got, want) {
+		t.Errorf("initial entries = %v, want %v", got, want)
+	}
+
+	// A newer TC, despite wrapping around to a lower sequence number, replaces the entries.
+	table = updateTopologyTable(tc(1, 4), table, sequences, 30, 0, staleTCs)
+	if got, want := seqs(table), map[NodeID]int{4: 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("entries after wraparound = %v, want %v", got, want)
+	}
+	if buf.Len() != 0 {
+		t.Errorf("logged %q, want nothing", buf.String())
+	}
+
+	// A stale TC is discarded, even when advertising a destination with no existing entry.
+	table = updateTopologyTable(tc(seqMax-1, 5), table, sequences, 30, 0, staleTCs)
+	if got, want := seqs(table), map[NodeID]int{4: 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("entries after stale TC = %v, want %v", got, want)
+	}
+	if want := "node 0: discarded stale TC from 2"; !strings.Contains(buf.String(), want) {
+		t.Errorf("logged %q, want %q", buf.String(), want)
+	}
+
+	// An empty TC leaves no entries, but its sequence number still makes an older TC stale.
+	table = updateTopologyTable(tc(3), table, sequences, 30, 0, staleTCs)
+	table = updateTopologyTable(tc(2, 6), table, sequences, 30, 0, staleTCs)
+	if got := seqs(table); len(got) != 0 {
+		t.Errorf("entries after a stale TC following an empty one = %v, want none", got)
+	}
+
+	// An incremental TC following the empty one applies, although the source has no entries.
+	table = updateTopologyTable(&TCMessage{Source: 2, FromNeighbor: 1, Sequence: 4, Incremental: true, Added: []NodeID{7}}, table, sequences, 30, 0, staleTCs)
+	if got, want := seqs(table), map[NodeID]int{7: 4}; !reflect.DeepEqual(got, want) {
+		t.Errorf("entries after a delta following an empty TC = %v, want %v", got, want)
+	}
+}
+
+func TestNode_SetStaleTCLog(t *testing.T) {
+	var buf bytes.Buffer
+	logged := newTestNode(0, &recordingTransmitter{})
+	logged.SetStaleTCLog(log.New(&buf, "", 0))
+	// Another Node's messages are not logged, as each Node has its own log.
+	other := newTestNode(3, &recordingTransmitter{})
+
+	for _, n := range []*Node{logged, other} {
+		n.handleTC(&TCMessage{Source: 2, FromNeighbor: 1, Sequence: 5, MessageSequence: 0})
+		n.handleTC(&TCMessage{Source: 2, FromNeighbor: 1, Sequence: 4, MessageSequence: 1})
+	}
+	if got, want := buf.String(), "node 0: discarded stale TC from 2: seq 4 is older than 5\n"; got != want {
+		t.Errorf("logged %q, want %q", got, want)
+	}
+}
+
+func TestNode_tcSequencesExpire(t *testing.T) {
+	n := newTestNode(0, &recordingTransmitter{})
+	n.SetTopologyHoldTime(3)
+	n.handleTC(&TCMessage{Source: 2, FromNeighbor: 1, Sequence: 5})
+
+	// Within the hold time, an older TC, such as from a restarted originator, is stale.
+	n.tick(nil)
+	n.handleTC(&TCMessage{Source: 2, FromNeighbor: 1, Sequence: 1, MultipointRelaySet: []NodeID{3}, MessageSequence: 1})
+	if got := len(n.topologyTable[2]); got != 0 {
+		t.Errorf("%d entries from a stale TC, want none", got)
+	}
+
+	// Once the sequence number expires along with the entries, any TC is accepted again.
+	for i := 0; i < 3; i++ {
+		n.tick(nil)
+	}
+	n.handleTC(&TCMessage{Source: 2, FromNeighbor: 1, Sequence: 1, MultipointRelaySet: []NodeID{3}, MessageSequence: 2})
+	if got := len(n.topologyTable[2]); got != 1 {
+		t.Errorf("%d entries after the sequence number expired, want 1", got)
+	}
+}
+
+func TestNode_Trigger(t *testing.T) {
+	sentTypes := func(sent []interface{}) []string {
+		types := make([]string, 0)
+		for _, msg := range sent {
+			types = append(types, fmt.Sprintf("%T", msg))
+		}
+		return types
+	}
+
+	out := &recordingTransmitter{}
+	n := newTestNode(0, out)
+	// Ticks 1 through 4 fall outside the HELLO and TC intervals.
+	n.tick(nil)
+
+	tests := []struct {
+		name    string
+		trigger func()
+		want    []string
+	}{
+		{name: "hello", trigger: n.TriggerHello, want: []string{"*main.HelloMessage"}},
+		{name: "tc", trigger: n.TriggerTC, want: []string{"*main.TCMessage"}},
+		{
+			name: "both, triggered twice",
+			trigger: func() {
+				n.TriggerTC()
+				n.TriggerHello()
+				n.TriggerHello()
+			},
+			want: []string{"*main.HelloMessage", "*main.TCMessage"},
+		},
+		// Triggers only apply to the next tick.
+		{name: "cleared", trigger: func() {}, want: []string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out.sent = nil
+			tt.trigger()
+			n.tick(nil)
+			if got := sentTypes(out.sent); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tick %d sent %v, want %v", n.currentTick-1, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNode_selfAddressedData(t *testing.T) {
+	out := &recordingTransmitter{}
+	received := &bufferCloser{}
+	n := newNode(make(chan interface{}), out, 0, NodeMessage{Message: "to self", Destination: 0, Delay: 0}, time.Millisecond, &bufferCloser{}, &bufferCloser{}, received)
+	var outcomes []dataOutcome
+	n.onDataResolved = func(_ *DataMessage, outcome dataOutcome) {
+		outcomes = append(outcomes, outcome)
+	}
+	n.Originate(0, "originated")
+	n.tick(nil)
+
+	for _, msg := range out.sent {
+		if _, ok := msg.(*DataMessage); ok {
+			t.Errorf("sent %s, want self-addressed data kept off the network", msg)
+		}
+	}
+	if got, want := received.String(), "to self\noriginated\n"; got != want {
+		t.Errorf("received %q, want %q", got, want)
+	}
+	if !n.nodeMsg.Sent {
+		t.Errorf("nodeMsg.Sent = false, want true")
+	}
+	if got, want := outcomes, []dataOutcome{dataDelivered, dataDelivered}; !reflect.DeepEqual(got, want) {
+		t.Errorf("outcomes = %v, want %v", got, want)
+	}
+	if got, want := n.Counters().DataDelivered, 2; got != want {
+		t.Errorf("DataDelivered = %d, want %d", got, want)
+	}
+}
+
+func TestNode_tickDeduplicates(t *testing.T) {
+	out := &recordingTransmitter{}
+	n := newTestNode(0, out)
+	n.msSet[1] = 1
+	n.msSet[4] = 4
+	n.currentTick = 1
+
+	tc := &TCMessage{Source: 2, FromNeighbor: 1, Sequence: 1, MultipointRelaySet: []NodeID{3}}
+	data := &DataMessage{Source: 2, Destination: 0, FromNeighbor: 1, NextHop: 0, Data: "hi"}
+	n.tick([]interface{}{tc, tc, &TCMessage{Source: 2, FromNeighbor: 1, Sequence: 2, MultipointRelaySet: []NodeID{3}, MessageSequence: 1}, data, data})
+
+	if got := n.Counters().TCForwarded; got != 2 {
+		t.Errorf("TCForwarded = %d, want 2", got)
+	}
+	if got := n.Counters().DataDelivered; got != 2 {
+		t.Errorf("DataDelivered = %d, want 2", got)
+	}
+
+	// A TC already received is not forwarded again in a later tick, nor when received from another neighbor.
+	fromOther := *tc
+	fromOther.FromNeighbor = 4
+	n.tick([]interface{}{tc, &fromOther})
+	if got := n.Counters().TCForwarded; got != 2 {
+		t.Errorf("TCForwarded after next tick = %d, want 2", got)
+	}
+
+	// A new TC received from two neighbors in the same tick is forwarded once.
+	next := &TCMessage{Source: 2, FromNeighbor: 1, Sequence: 2, MultipointRelaySet: []NodeID{3}, MessageSequence: 2}
+	nextFromOther := *next
+	nextFromOther.FromNeighbor = 4
+	n.tick([]interface{}{next, &nextFromOther})
+	if got := n.Counters().TCForwarded; got != 3 {
+		t.Errorf("TCForwarded after a TC from two neighbors = %d, want 3", got)
+	}
+}
+
+func TestNode_RunClosedInput(t *testing.T) {
+	in := make(chan interface{}, 1)
+	n := newTestNode(0, &recordingTransmitter{})
+	n.input = in
+	in <- &HelloMessage{Source: 1, Sequence: 0}
+	close(in)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		n.Run(context.Background())
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run() did not return after the input was closed")
+	}
+
+	if got, open := n.receiveAll(); open || len(got) != 0 {
+		t.Errorf("receiveAll() = %v, %v, want no messages and closed", got, open)
+	}
+}
+
+func TestNode_handleHelloSelf(t *testing.T) {
+	n := newTestNode(0, &recordingTransmitter{})
+	n.handleHello(&HelloMessage{Source: 1, Sequence: 0})
+	n.handleHello(&HelloMessage{Source: 1, Bidirectional: []NodeID{0, 2}, Sequence: 1})
+	before := n.Snapshot()
+
+	n.handleHello(&HelloMessage{Source: 0, Bidirectional: []NodeID{1}, MultipointRelay: []NodeID{0}, Sequence: 0})
+	if diff := DiffNodeState(before, n.Snapshot()); len(diff) != 0 {
+		t.Errorf("handleHello() of own HELLO changed state: %v", diff)
+	}
+	if _, in := n.helloSequences[0]; in {
+		t.Errorf("handleHello() of own HELLO recorded its sequence number")
+	}
+}
+
+func TestNode_compactTopology(t *testing.T) {
+	tests := []struct {
+		name            string
+		compactTopology bool
+		want            map[NodeID][]NodeID
+	}{
+		{
+			name:            "full",
+			compactTopology: false,
+			want:            map[NodeID][]NodeID{1: {3, 4}, 2: {3, 4}, 5: {4}},
+		},
+		{
+			name:            "compact",
+			compactTopology: true,
+			want:            map[NodeID][]NodeID{2: {3, 4}, 5: {4}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := newTestNode(0, &recordingTransmitter{})
+			n.SetCompactTopology(tt.compactTopology)
+			for _, id := range []NodeID{1, 2} {
+				n.oneHopNeighbors[id] = oneHopNeighborEntry{neighborID: id, state: bidirectional, holdUntil: 15}
+			}
+			n.calculateRoutingTable()
+
+			n.handleTC(&TCMessage{Source: 1, FromNeighbor: 1, Sequence: 1, MultipointRelaySet: []NodeID{3, 4}})
+			n.handleTC(&TCMessage{Source: 2, FromNeighbor: 2, Sequence: 1, MultipointRelaySet: []NodeID{3, 4}})
+			// Originator 5 is unreachable, so its entries do not displace the others.
+			n.handleTC(&TCMessage{Source: 5, FromNeighbor: 2, Sequence: 1, MultipointRelaySet: []NodeID{4}})
+
+			got := make(map[NodeID][]NodeID)
+			for originator, entries := range n.topologyTable {
+				got[originator] = sortedNodeIDs(entries)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("topologyTable = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// BenchmarkNode_handleTC measures the topology table of a node in a 100-node full mesh, where every other node
+// advertises every node as an MPR selector. The retained-B metric is the heap retained by the node afterwards.
+func BenchmarkNode_handleTC(b *testing.B) {
+	const size = 100
+	var tcs []*TCMessage
+	for src := NodeID(1); src < size; src++ {
+		tc := &TCMessage{Source: src, FromNeighbor: src, Sequence: 1}
+		for dst := NodeID(1); dst < size; dst++ {
+			if dst != src {
+				tc.MultipointRelaySet = append(tc.MultipointRelaySet, dst)
+			}
+		}
+		tcs = append(tcs, tc)
+	}
+	mesh := func(compact bool) *Node {
+		n := newTestNode(0, &recordingTransmitter{})
+		n.SetCompactTopology(compact)
+		for id := NodeID(1); id < size; id++ {
+			n.oneHopNeighbors[id] = oneHopNeighborEntry{neighborID: id, state: bidirectional, holdUntil: 15}
+		}
+		n.calculateRoutingTable()
+		for _, tc := range tcs {
+			n.handleTC(tc)
+		}
+		return n
+	}
+	for _, compact := range []bool{false, true} {
+		b.Run(fmt.Sprintf("compact=%v", compact), func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				mesh(compact)
+			}
+			b.StopTimer()
+
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			n := mesh(compact)
+			runtime.GC()
+			runtime.ReadMemStats(&after)
+			b.ReportMetric(float64(after.HeapAlloc-before.HeapAlloc), "retained-B")
+			runtime.KeepAlive(n)
+		})
+	}
+}
+
+func TestNode_willingnessTCInterval(t *testing.T) {
+	tests := []struct {
+		name        string
+		willingness Willingness
+		want        []int
+	}{
+		{name: "never", willingness: WillNever, want: []int{0, 40}},
+		{name: "low", willingness: WillLow, want: []int{0, 20, 40, 60}},
+		{name: "default", willingness: WillDefault, want: []int{0, 10, 20, 30, 40, 50, 60, 70}},
+		{name: "high", willingness: WillHigh, want: []int{0, 10, 20, 30, 40, 50, 60, 70}},
+		{name: "always", willingness: WillAlways, want: []int{0, 5, 10, 15, 20, 25, 30, 35, 40, 45, 50, 55, 60, 65, 70, 75}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &recordingTransmitter{}
+			n := newTestNode(0, out)
+			n.SetWillingness(tt.willingness)
+			n.msSet[1] = 1
+
+			got := make([]int, 0)
+			for tick := 0; tick < 80; tick++ {
+				out.sent = nil
+				n.tick(nil)
+				for _, msg := range out.sent {
+					if _, ok := msg.(*TCMessage); ok {
+						got = append(got, tick)
+					}
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TC ticks = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_applyTCDelta(t *testing.T) {
+	base := func() map[NodeID]map[NodeID]topologyEntry {
+		return map[NodeID]map[NodeID]topologyEntry{
+			2: {
+				3: {dst: 3, originator: 2, holdUntil: 30, seq: 4},
+				4: {dst: 4, originator: 2, holdUntil: 30, seq: 4},
+			},
+		}
+	}
+	tests := []struct {
+		name string
+		msg  *TCMessage
+		want map[NodeID]map[NodeID]topologyEntry
+	}{
+		{
+			name: "next sequence applied",
+			msg:  &TCMessage{Source: 2, FromNeighbor: 2, Sequence: 5, Incremental: true, Added: []NodeID{5, 0}, Removed: []NodeID{3}},
+			want: map[NodeID]map[NodeID]topologyEntry{
+				2: {
+					4: {dst: 4, originator: 2, holdUntil: 40, seq: 5},
+					5: {dst: 5, originator: 2, holdUntil: 40, seq: 5},
+				},
+			},
+		},
+		{
+			name: "no-change delta refreshes",
+			msg:  &TCMessage{Source: 2, FromNeighbor: 2, Sequence: 5, Incremental: true},
+			want: map[NodeID]map[NodeID]topologyEntry{
+				2: {
+					3: {dst: 3, originator: 2, holdUntil: 40, seq: 5},
+					4: {dst: 4, originator: 2, holdUntil: 40, seq: 5},
+				},
+			},
+		},
+		{
+			name: "same sequence ignored",
+			msg:  &TCMessage{Source: 2, FromNeighbor: 2, Sequence: 4, Incremental: true, Added: []NodeID{5}},
+			want: base(),
+		},
+		{
+			name: "missed sequence ignored",
+			msg:  &TCMessage{Source: 2, FromNeighbor: 2, Sequence: 6, Incremental: true, Added: []NodeID{5}},
+			want: base(),
+		},
+		{
+			name: "stale sequence ignored",
+			msg:  &TCMessage{Source: 2, FromNeighbor: 2, Sequence: 3, Incremental: true, Removed: []NodeID{3}},
+			want: base(),
+		},
+		{
+			name: "unknown source ignored",
+			msg:  &TCMessage{Source: 6, FromNeighbor: 6, Sequence: 1, Incremental: true, Added: []NodeID{5}},
+			want: base(),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table := base()
+			if got := updateTopologyTable(tt.msg, table, tcSequencesOf(table), 40, 0, nil); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("updateTopologyTable() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNode_incrementalTC(t *testing.T) {
+	changing := [][]NodeID{{1}, {1, 2}, {2}, {2, 3}, {3}}
+	tests := []struct {
+		name string
+		// msSets is the sender's MS set for each TCMessage.
+		msSets [][]NodeID
+		// drop is the index of a TCMessage the receiver misses, or -1.
+		drop int
+		// want is the receiver's view of the sender's MS set after each TCMessage.
+		want []string
+	}{
+		{
+			name:   "every delta received",
+			msSets: changing,
+			drop:   -1,
+			want:   []string{"[1]", "[1 2]", "[2]", "[2 3]", "[3]"},
+		},
+		{
+			name:   "missed delta recovered by full refresh",
+			msSets: changing,
+			drop:   1,
+			want:   []string{"[1]", "[1]", "[1]", "[1]", "[3]"},
+		},
+		{
+			// The delta following the missed one advertises no change, but still reveals the gap.
+			name:   "missed delta followed by an unchanged one",
+			msSets: [][]NodeID{{1}, {1, 2}, {1, 2}, {2}, {2}},
+			drop:   1,
+			want:   []string{"[1]", "[1]", "[1]", "[1]", "[2]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &recordingTransmitter{}
+			sender := newTestNode(0, out)
+			sender.SetIncrementalTC(true, 3)
+			receiver := newTestNode(9, &recordingTransmitter{})
+			receiver.oneHopNeighbors[0] = oneHopNeighborEntry{neighborID: 0, state: bidirectional, holdUntil: 100}
+
+			var kinds []bool
+			got := make([]string, 0)
+			for i, msSet := range tt.msSets {
+				sender.msSet = make(map[NodeID]NodeID)
+				for _, id := range msSet {
+					sender.msSet[id] = id
+				}
+				out.sent = nil
+				sender.sendTC()
+				tc := out.sent[0].(*TCMessage)
+				kinds = append(kinds, tc.Incremental)
+				if i != tt.drop {
+					receiver.handleTC(tc)
+				}
+				got = append(got, fmt.Sprint(sortedNodeIDs(receiver.topologyTable[0])))
+			}
+			if want := []bool{false, true, true, true, false}; !reflect.DeepEqual(kinds, want) {
+				t.Errorf("incremental TCs = %v, want %v", kinds, want)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("receiver MS sets = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNode_routeTieBreak(t *testing.T) {
+	// Node 3 is reachable from 0 via either 1 or 2, and 4 is one hop beyond 3.
+	links := map[NodeID][]NodeID{
+		0: {1, 2},
+		1: {0, 3},
+		2: {0, 3},
+		3: {1, 2, 4},
+		4: {3},
+	}
+	tests := []struct {
+		name         string
+		ecmp         bool
+		wantRoutes   []RoutingEntry
+		wantNextHops []NodeID
+	}{
+		{
+			name: "lowest next hop",
+			wantRoutes: []RoutingEntry{
+				{Destination: 3, NextHop: 1, Distance: 2},
+				{Destination: 4, NextHop: 1, Distance: 3},
+			},
+			wantNextHops: []NodeID{1, 1, 1, 1},
+		},
+		{
+			name: "equal-cost multipath",
+			ecmp: true,
+			wantRoutes: []RoutingEntry{
+				{Destination: 3, NextHop: 1, Distance: 2},
+				{Destination: 3, NextHop: 2, Distance: 2},
+				{Destination: 4, NextHop: 1, Distance: 3},
+				{Destination: 4, NextHop: 2, Distance: 3},
+			},
+			wantNextHops: []NodeID{1, 2, 1, 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLockstepNetwork(links)
+			n := l.nodes[0]
+			n.SetEqualCostMultipath(tt.ecmp)
+			l.run(40)
+
+			routes := make([]RoutingEntry, 0)
+			for _, route := range n.Snapshot().RoutingTable {
+				if route.Destination >= 3 {
+					routes = append(routes, route)
+				}
+			}
+			if !reflect.DeepEqual(routes, tt.wantRoutes) {
+				t.Errorf("routes = %v, want %v", routes, tt.wantRoutes)
+			}
+
+			out := l.outputs[0]
+			out.sent = nil
+			nextHops := make([]NodeID, 0)
+			for i := 0; i < len(tt.wantNextHops); i++ {
+				n.Originate(4, "hello 4")
+				n.tick(nil)
+			}
+			for _, msg := range out.sent {
+				if data, ok := msg.(*DataMessage); ok {
+					nextHops = append(nextHops, data.NextHop)
+				}
+			}
+			if !reflect.DeepEqual(nextHops, tt.wantNextHops) {
+				t.Errorf("next hops = %v, want %v", nextHops, tt.wantNextHops)
+			}
+		})
+	}
+}
+
+func TestNode_handleHello_unknownCategories(t *testing.T) {
+	n := newTestNode(0, &recordingTransmitter{})
+	for seq := 0; seq < 2; seq++ {
+		msg, err := ParseMessage("* 1 HELLO UNIDIR  BIDIR 0 MPR  LOST 5 SYM_V2 6")
+		if err != nil {
+			t.Fatalf("ParseMessage() error = %v", err)
+		}
+		hello := msg.(*HelloMessage)
+		hello.Sequence = seq
+		n.tick([]interface{}{hello})
+	}
+
+	s := n.Snapshot()
+	if want := map[NodeID]NeighborState{1: bidirectional}; !reflect.DeepEqual(s.OneHopNeighbors, want) {
+		t.Errorf("OneHopNeighbors = %v, want %v", s.OneHopNeighbors, want)
+	}
+	// Neighbors in unknown categories are not two-hop neighbors.
+	if got := s.TwoHopNeighbors[1]; len(got) != 0 {
+		t.Errorf("TwoHopNeighbors[1] = %v, want none", got)
+	}
+}
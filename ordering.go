@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/binary"
+	"hash/fnv"
+	"sort"
+)
+
+// deliveryOrder orders the messages a Node receives within a tick by a seeded, pseudo-random sub-tick ordering of their
+// senders, rather than by the order they happened to arrive in. Messages from the same sender keep the order they
+// arrived in, as each link delivers in order. The ordering value of a sender only depends on the seed, the receiving
+// Node, the tick, and the sender, so for a fixed seed the same senders are always processed in the same order.
+type deliveryOrder struct {
+	seed int64
+}
+
+// messageSender determines the neighbor a received message was sent by, false for messages of unknown type.
+func messageSender(msg interface{}) (NodeID, bool) {
+	switch m := msg.(type) {
+	case *HelloMessage:
+		return m.Source, true
+	case *TCMessage:
+		return m.FromNeighbor, true
+	case *MIDMessage:
+		return m.FromNeighbor, true
+	case *DataMessage:
+		return m.FromNeighbor, true
+	default:
+		return 0, false
+	}
+}
+
+// subTick determines the ordering value of a sender of messages received by the Node during the tick.
+func (o *deliveryOrder) subTick(id NodeID, tick int, sender NodeID) uint64 {
+	h := fnv.New64a()
+	var b [8]byte
+	for _, v := range []uint64{uint64(o.seed), uint64(id), uint64(tick), uint64(sender)} {
+		binary.LittleEndian.PutUint64(b[:], v)
+		_, _ = h.Write(b[:])
+	}
+	return h.Sum64()
+}
+
+// sort orders the messages received by the Node during the tick by their senders' ordering values. Messages from the
+// same sender, or with equal ordering values, keep the order they arrived in. Messages of unknown type are ordered
+// after all others.
+func (o *deliveryOrder) sort(id NodeID, tick int, msgs []interface{}) {
+	type ordered struct {
+		msg   interface{}
+		known bool
+		value uint64
+	}
+	values := make([]ordered, len(msgs))
+	for i, msg := range msgs {
+		sender, ok := messageSender(msg)
+		values[i] = ordered{msg: msg, known: ok}
+		if ok {
+			values[i].value = o.subTick(id, tick, sender)
+		}
+	}
+	sort.SliceStable(values, func(i, j int) bool {
+		if values[i].known != values[j].known {
+			return values[i].known
+		}
+		return values[i].value < values[j].value
+	})
+	for i, v := range values {
+		msgs[i] = v.msg
+	}
+}
+
+// SetDeliveryOrderSeed makes each node process the messages it receives within a tick in a pseudo-random order of
+// their senders determined by the seed, rather than in the order they arrived. Messages from the same sender are still
+// processed in the order they arrived. Must be called before Initialize.
+func (c *Controller) SetDeliveryOrderSeed(seed int64) {
+	c.deliveryOrder = &deliveryOrder{seed: seed}
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func Test_deliveryOrder(t *testing.T) {
+	msgs := []interface{}{
+		&HelloMessage{Source: 1, Sequence: 1},
+		&HelloMessage{Source: 2, Sequence: 1},
+		&TCMessage{Source: 3, FromNeighbor: 1, MultipointRelaySet: []NodeID{4}},
+		&TCMessage{Source: 3, FromNeighbor: 2, MultipointRelaySet: []NodeID{4}},
+		&DataMessage{Source: 5, Destination: 0, NextHop: 0, FromNeighbor: 1, Data: "a"},
+		&DataMessage{Source: 5, Destination: 0, NextHop: 0, FromNeighbor: 6, Data: "b"},
+		&HelloMessage{Source: 6, Sequence: 4},
+		&HelloMessage{Source: 7, Sequence: 2},
+	}
+	order := func(seed int64, arrival []int) []string {
+		received := make([]interface{}, 0, len(arrival))
+		for _, i := range arrival {
+			received = append(received, msgs[i])
+		}
+		(&deliveryOrder{seed: seed}).sort(0, 10, received)
+		got := make([]string, 0, len(received))
+		for _, msg := range received {
+			got = append(got, fmt.Sprint(msg))
+		}
+		return got
+	}
+
+	// Each arrival order interleaves the senders differently, but keeps the order of each sender's messages.
+	want := order(1, []int{0, 1, 2, 3, 4, 5, 6, 7})
+	for _, arrival := range [][]int{
+		{7, 5, 6, 1, 3, 0, 2, 4},
+		{1, 0, 7, 2, 5, 3, 4, 6},
+	} {
+		if got := order(1, arrival); !reflect.DeepEqual(got, want) {
+			t.Errorf("order of arrival %v = %q, want %q", arrival, got, want)
+		}
+	}
+	if got := order(2, []int{0, 1, 2, 3, 4, 5, 6, 7}); reflect.DeepEqual(got, want) {
+		t.Errorf("order with another seed = %q, want it to differ from %q", got, want)
+	}
+}
+
+func Test_deliveryOrder_senderFIFO(t *testing.T) {
+	hello := &HelloMessage{Source: 1, Sequence: 1}
+	tc := &TCMessage{Source: 3, FromNeighbor: 1, Sequence: 1, MultipointRelaySet: []NodeID{4}}
+	data := &DataMessage{Source: 5, Destination: 0, NextHop: 0, FromNeighbor: 1, Data: "a"}
+	other := &HelloMessage{Source: 2, Sequence: 1}
+
+	for _, arrival := range [][]interface{}{
+		{hello, tc, data, other},
+		{data, other, tc, hello},
+	} {
+		for seed := int64(0); seed < 50; seed++ {
+			received := append([]interface{}(nil), arrival...)
+			(&deliveryOrder{seed: seed}).sort(0, 10, received)
+
+			var fromSender []interface{}
+			for _, msg := range received {
+				if msg != other {
+					fromSender = append(fromSender, msg)
+				}
+			}
+			want := make([]interface{}, 0, 3)
+			for _, msg := range arrival {
+				if msg != other {
+					want = append(want, msg)
+				}
+			}
+			if !reflect.DeepEqual(fromSender, want) {
+				t.Fatalf("seed %d: messages from node 1 processed in order %v, want arrival order %v", seed, fromSender, want)
+			}
+		}
+	}
+}
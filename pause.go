@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// pauseGate is shared by the Controller and all nodes, enabling the simulation clock to be paused.
+type pauseGate struct {
+	mu sync.Mutex
+
+	// resumed is closed when the gate is not paused, and replaced with an open channel while paused.
+	resumed chan struct{}
+
+	// pausedAt is when the current pause began.
+	pausedAt time.Time
+
+	// pausedFor is the total duration of all completed pauses.
+	pausedFor time.Duration
+}
+
+// newPauseGate creates an unpaused pauseGate.
+func newPauseGate() *pauseGate {
+	g := &pauseGate{}
+	g.resumed = make(chan struct{})
+	close(g.resumed)
+	return g
+}
+
+// Pause halts the clock until Resume is called. Has no effect if already paused.
+func (g *pauseGate) Pause() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	select {
+	case <-g.resumed:
+		g.resumed = make(chan struct{})
+		g.pausedAt = time.Now()
+	default:
+	}
+}
+
+// Resume restarts the clock. Has no effect if not paused.
+func (g *pauseGate) Resume() {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	select {
+	case <-g.resumed:
+	default:
+		g.pausedFor += time.Since(g.pausedAt)
+		close(g.resumed)
+	}
+}
+
+// wait blocks while the gate is paused. Returns false if the context is done first.
+// A nil pauseGate is never paused.
+func (g *pauseGate) wait(ctx context.Context) bool {
+	if g == nil {
+		return true
+	}
+	g.mu.Lock()
+	resumed := g.resumed
+	g.mu.Unlock()
+
+	select {
+	case <-resumed:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// elapsed is the time since the start, excluding any time spent paused.
+func (g *pauseGate) elapsed(start time.Time) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	paused := g.pausedFor
+	select {
+	case <-g.resumed:
+	default:
+		paused += time.Since(g.pausedAt)
+	}
+	return time.Since(start) - paused
+}
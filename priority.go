@@ -0,0 +1,58 @@
+package main
+
+import (
+	"log"
+	"sort"
+	"sync/atomic"
+)
+
+// SetLinkCapacity limits each directed link to carrying capacity DataMessage(s) per tick, as a simple bandwidth
+// model. When more are sent across a link during a tick, the link is congested: it carries those of the highest
+// Priority, highest first, and drops the rest, so lower-priority messages are dropped first. Among messages of equal
+// Priority, the earliest sent are carried. Dropped messages are resolved as dropped. Control messages are not limited.
+// Only a synchronous simulation, whose ticks are lock-step, models bandwidth, so this has no effect otherwise. Zero,
+// the default, leaves links unlimited. Must be called before Start.
+func (c *Controller) SetLinkCapacity(capacity int) {
+	c.linkCapacity = capacity
+}
+
+// CongestionDrops is the number of DataMessage(s) dropped by congested links. See SetLinkCapacity. Safe to call
+// concurrently with Start.
+func (c *Controller) CongestionDrops() int {
+	return int(atomic.LoadInt64(&c.congestionDrops))
+}
+
+// congest determines the DataMessage(s) sent across a link during a tick which the link carries, in order of
+// Priority, resolving the rest as dropped.
+func (c *Controller) congest(from, to NodeID, tick int, msgs []*DataMessage) []*DataMessage {
+	sorted := append([]*DataMessage(nil), msgs...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Priority > sorted[j].Priority
+	})
+	if len(sorted) <= c.linkCapacity {
+		return sorted
+	}
+
+	for _, m := range sorted[c.linkCapacity:] {
+		atomic.AddInt64(&c.congestionDrops, 1)
+		log.Printf("controller: link %s -> %s congested at tick %d, dropped:\t%s", from, to, tick, m)
+		c.dataResolved(m, dataDropped)
+	}
+	return sorted[:c.linkCapacity]
+}
+
+// OriginatePriority makes the Node send a DataMessage of the given Priority to the destination during its next tick,
+// as Originate does. See Controller.SetLinkCapacity.
+func (n *Node) OriginatePriority(dst NodeID, data string, priority int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.pendingData = append(n.pendingData, &DataMessage{
+		Source:       n.id,
+		Destination:  dst,
+		NextHop:      0,
+		FromNeighbor: 0,
+		Data:         data,
+		Priority:     priority,
+	})
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestController_SetLinkCapacity(t *testing.T) {
+	tests := []struct {
+		name     string
+		capacity int
+		// want is the data received by node 1, in order.
+		want        string
+		wantDropped int
+	}{
+		{name: "unlimited", capacity: 0, want: "low 1\nlow 2\nhigh\n"},
+		{name: "congested", capacity: 1, want: "high\n", wantDropped: 2},
+		{name: "partly congested", capacity: 2, want: "high\nlow 1\n", wantDropped: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topology, err := NewNetworkTypology(strings.NewReader("0 UP 0 1\n0 UP 1 0\n"))
+			if err != nil {
+				t.Fatalf("NewNetworkTypology() error = %v", err)
+			}
+			logDir := t.TempDir()
+			c := NewController(*topology, time.Hour)
+			c.SetLogDir(logDir)
+			c.Initialize([]NodeConfig{
+				{ID: 0, Message: NodeMessage{Sent: true}},
+				{ID: 1, Message: NodeMessage{Sent: true}},
+			})
+			// The low-priority messages are sent first, in the same tick as the high-priority one.
+			for _, data := range []string{"low 1", "low 2"} {
+				if err := c.ScheduleData(0, 1, data, 20); err != nil {
+					t.Fatalf("ScheduleData() error = %v", err)
+				}
+			}
+			if err := c.ScheduleDataPriority(0, 1, "high", 5, 20); err != nil {
+				t.Fatalf("ScheduleDataPriority() error = %v", err)
+			}
+			c.SetSynchronous(true)
+			c.SetLinkCapacity(tt.capacity)
+			c.Start(30)
+
+			got, err := os.ReadFile(filepath.Join(logDir, "1_received.txt"))
+			if err != nil {
+				t.Fatalf("ReadFile() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("received %q, want %q", got, tt.want)
+			}
+			if got := c.CongestionDrops(); got != tt.wantDropped {
+				t.Errorf("CongestionDrops() = %d, want %d", got, tt.wantDropped)
+			}
+		})
+	}
+}
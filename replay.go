@@ -0,0 +1,388 @@
+package main
+
+import (
+	"bufio"
+	"encoding"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// simulationRecorder writes every message delivered to a Node, and every DataMessage originated on request, along with
+// the Node's tick, so that a simulation can be replayed by ReplaySimulation.
+//
+// The recording is line based:
+//
+//	node {ID} {DST} {DELAY} {SENT} {START_TICK} {STOP_TICK} {QUOTED_MSG}
+//	join {ID} {GROUP}
+//	interface {ID} {ADDR}
+//	deliver {ID} {TICK} {BASE64_MSG} {MSG}
+//	originate {ID} {TICK} {DST} {PRIORITY} {QUOTED_DATA}
+//	trigger {ID} {TICK} {hello | tc}
+//	stop {ID} {TICK}
+//
+// Messages are recorded in their binary encoding, which unlike the String() format includes HELLO sequence numbers;
+// the trailing String() format is informational only.
+type simulationRecorder struct {
+	mu  sync.Mutex
+	w   io.Writer
+	err error
+}
+
+// printf writes a line to the recording. Only the first write error is logged.
+func (r *simulationRecorder) printf(format string, a ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.err != nil {
+		return
+	}
+	if _, err := fmt.Fprintf(r.w, format+"\n", a...); err != nil {
+		r.err = err
+		log.Printf("recorder: unable to write recording: %s", err)
+	}
+}
+
+// recordTick records the messages and requested DataMessage(s) a Node is about to process during a tick, and whether
+// a HelloMessage or TCMessage was triggered for the tick.
+func (r *simulationRecorder) recordTick(id NodeID, tick int, msgs []interface{}, pendingData []*DataMessage, helloTriggered, tcTriggered bool) {
+	for _, msg := range msgs {
+		m, ok := msg.(encoding.BinaryMarshaler)
+		if !ok {
+			continue
+		}
+		b, err := m.MarshalBinary()
+		if err != nil {
+			log.Printf("recorder: unable to encode %s: %s", msg, err)
+			continue
+		}
+		r.printf("deliver %d %d %s %s", id, tick, base64.StdEncoding.EncodeToString(b), msg)
+	}
+	for _, msg := range pendingData {
+		r.printf("originate %d %d %d %d %s", id, tick, msg.Destination, msg.Priority, strconv.Quote(msg.Data))
+	}
+	if helloTriggered {
+		r.printf("trigger %d %d hello", id, tick)
+	}
+	if tcTriggered {
+		r.printf("trigger %d %d tc", id, tick)
+	}
+}
+
+// RecordTo records the simulation to the writer, so that it can be replayed by ReplaySimulation. Writes happen
+// concurrently with the simulation, from every node. Must be called after Initialize and before Start.
+func (c *Controller) RecordTo(w io.Writer) {
+	r := &simulationRecorder{w: w}
+	for _, n := range c.nodes {
+		config := c.configs[n.id]
+		r.printf("node %s %d %d %t %d %d %s", n.id, config.Message.Destination, config.Message.Delay, config.Message.Sent, config.StartTick, config.StopTick, strconv.Quote(config.Message.Message))
+		for _, group := range config.Groups {
+			r.printf("join %d %d", n.id, group)
+		}
+		for _, addr := range config.Interfaces {
+			r.printf("interface %d %d", n.id, addr)
+		}
+		n.recorder = r
+	}
+}
+
+// nopWriteCloser discards all writes.
+type nopWriteCloser struct{}
+
+func (nopWriteCloser) Write(p []byte) (int, error) {
+	return len(p), nil
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// discardTransmitter is a Transmitter which drops every message.
+type discardTransmitter struct{}
+
+func (discardTransmitter) Send(interface{}) {}
+
+// ErrReplay is returned when a recording can not be replayed.
+type ErrReplay struct {
+	line int
+	msg  string
+}
+
+func (e ErrReplay) Error() string {
+	return fmt.Sprintf("replay: line %d: %s", e.line, e.msg)
+}
+
+// replayTick holds what a Node processed during a single recorded tick.
+type replayTick struct {
+	msgs           []interface{}
+	pendingData    []*DataMessage
+	helloTriggered bool
+	tcTriggered    bool
+}
+
+// replayNode holds the recorded history of a single Node.
+type replayNode struct {
+	config NodeConfig
+	ticks  map[int]*replayTick
+
+	// stop is the number of ticks the Node ran for, or -1 if the recording did not include it.
+	stop int
+}
+
+func (n *replayNode) at(tick int) *replayTick {
+	t, in := n.ticks[tick]
+	if !in {
+		t = &replayTick{}
+		n.ticks[tick] = t
+	}
+	return t
+}
+
+// ReplaySimulation reconstructs the nodes of a simulation recorded by Controller.RecordTo, by replaying every recorded
+// message into fresh nodes in the order and at the tick each was originally processed. The returned Controller's
+// nodes hold the state of the original nodes at the end of the recording, and can be inspected with Snapshot or
+// DumpState. The Controller has no topology and can not be started.
+//
+// Nodes are replayed with the default settings, so a simulation which changed settings such as hold times is only
+// reproduced if the same changes are made. Node logs are discarded.
+func ReplaySimulation(r io.Reader) (*Controller, error) {
+	nodes := make(map[NodeID]*replayNode)
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		if err := parseReplayLine(scanner.Text(), nodes); err != nil {
+			return nil, ErrReplay{line: lineNum, msg: err.Error()}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	c := NewController(*NewEmptyTopology(), time.Millisecond)
+	ids := make([]NodeID, 0, len(nodes))
+	configs := make([]NodeConfig, 0, len(nodes))
+	for id, rn := range nodes {
+		ids = append(ids, id)
+		configs = append(configs, rn.config)
+	}
+	sortNodeIDs(ids)
+	if err := c.setInterfaces(configs); err != nil {
+		return nil, err
+	}
+	c.groups = multicastGroups(configs)
+	for _, id := range ids {
+		rn := nodes[id]
+		n := newNode(nil, discardTransmitter{}, id, rn.config.Message, c.tickDuration, nopWriteCloser{}, nopWriteCloser{}, nopWriteCloser{})
+		n.groups = rn.config.Groups
+		n.interfaces = rn.config.Interfaces
+		n.groupMembers = c.groups
+		stop := rn.stop
+		if stop < 0 {
+			stop = 0
+			for tick := range rn.ticks {
+				if tick+1 > stop {
+					stop = tick + 1
+				}
+			}
+		}
+		for tick := 0; tick < stop; tick++ {
+			if t, in := rn.ticks[tick]; in {
+				n.pendingData = t.pendingData
+				n.helloTriggered = t.helloTriggered
+				n.tcTriggered = t.tcTriggered
+				n.tick(t.msgs)
+				continue
+			}
+			n.tick(nil)
+		}
+		c.configs[id] = rn.config
+		c.nodes = append(c.nodes, n)
+	}
+	return c, nil
+}
+
+// parseReplayLine parses a single line of a recording into the nodes' histories.
+func parseReplayLine(line string, nodes map[NodeID]*replayNode) error {
+	kind, rest, _ := strings.Cut(line, " ")
+	switch kind {
+	case "node":
+		fields := strings.SplitN(rest, " ", 7)
+		if len(fields) != 7 {
+			return errors.New("node must be of the form: 'node {ID} {DST} {DELAY} {SENT} {START_TICK} {STOP_TICK} {QUOTED_MSG}'")
+		}
+		ints, err := parseReplayInts([]string{fields[2], fields[4], fields[5]})
+		if err != nil {
+			return err
+		}
+		sent, err := strconv.ParseBool(fields[3])
+		if err != nil {
+			return fmt.Errorf("invalid sent flag: '%s'", fields[3])
+		}
+		id, err := parseNodeID(fields[0])
+		if err != nil {
+			return fmt.Errorf("invalid node ID: '%s'", fields[0])
+		}
+		dst, err := parseNodeID(fields[1])
+		if err != nil {
+			return fmt.Errorf("invalid destination: '%s'", fields[1])
+		}
+		msg, err := strconv.Unquote(fields[6])
+		if err != nil {
+			return fmt.Errorf("invalid message: %s", fields[6])
+		}
+		nodes[id] = &replayNode{
+			config: NodeConfig{
+				ID:        id,
+				Message:   NodeMessage{Message: msg, Delay: ints[0], Destination: dst, Sent: sent},
+				StartTick: ints[1],
+				StopTick:  ints[2],
+			},
+			ticks: make(map[int]*replayTick),
+			stop:  -1,
+		}
+	case "join":
+		fields := strings.Fields(rest)
+		if len(fields) != 2 {
+			return errors.New("join must be of the form: 'join {ID} {GROUP}'")
+		}
+		id, err := parseNodeID(fields[0])
+		if err != nil {
+			return fmt.Errorf("invalid node ID: '%s'", fields[0])
+		}
+		n, in := nodes[id]
+		if !in {
+			return fmt.Errorf("node %s was not recorded", id)
+		}
+		group, err := parseNodeID(fields[1])
+		if err != nil || !IsMulticast(group) {
+			return fmt.Errorf("invalid group: '%s'", fields[1])
+		}
+		n.config.Groups = append(n.config.Groups, group)
+	case "interface":
+		fields := strings.Fields(rest)
+		if len(fields) != 2 {
+			return errors.New("interface must be of the form: 'interface {ID} {ADDR}'")
+		}
+		id, err := parseNodeID(fields[0])
+		if err != nil {
+			return fmt.Errorf("invalid node ID: '%s'", fields[0])
+		}
+		n, in := nodes[id]
+		if !in {
+			return fmt.Errorf("node %s was not recorded", id)
+		}
+		addr, err := parseNodeID(fields[1])
+		if err != nil {
+			return fmt.Errorf("invalid interface address: '%s'", fields[1])
+		}
+		n.config.Interfaces = append(n.config.Interfaces, addr)
+	case "deliver":
+		fields := strings.SplitN(rest, " ", 4)
+		if len(fields) < 3 {
+			return errors.New("deliver must be of the form: 'deliver {ID} {TICK} {BASE64_MSG} {MSG}'")
+		}
+		n, tick, err := replayNodeTick(fields, nodes)
+		if err != nil {
+			return err
+		}
+		b, err := base64.StdEncoding.DecodeString(fields[2])
+		if err != nil {
+			return fmt.Errorf("invalid message encoding: %s", err)
+		}
+		msg, err := unmarshalMessage(b)
+		if err != nil {
+			return err
+		}
+		t := n.at(tick)
+		t.msgs = append(t.msgs, msg)
+	case "originate":
+		fields := strings.SplitN(rest, " ", 5)
+		if len(fields) != 5 {
+			return errors.New("originate must be of the form: 'originate {ID} {TICK} {DST} {PRIORITY} {QUOTED_DATA}'")
+		}
+		n, tick, err := replayNodeTick(fields, nodes)
+		if err != nil {
+			return err
+		}
+		dst, err := parseNodeID(fields[2])
+		if err != nil {
+			return fmt.Errorf("invalid destination: '%s'", fields[2])
+		}
+		priority, err := strconv.Atoi(fields[3])
+		if err != nil {
+			return fmt.Errorf("invalid priority: '%s'", fields[3])
+		}
+		data, err := strconv.Unquote(fields[4])
+		if err != nil {
+			return fmt.Errorf("invalid data: %s", fields[4])
+		}
+		t := n.at(tick)
+		t.pendingData = append(t.pendingData, &DataMessage{Source: n.config.ID, Destination: dst, Data: data, Priority: priority})
+	case "trigger":
+		fields := strings.Fields(rest)
+		if len(fields) != 3 {
+			return errors.New("trigger must be of the form: 'trigger {ID} {TICK} {hello | tc}'")
+		}
+		n, tick, err := replayNodeTick(fields, nodes)
+		if err != nil {
+			return err
+		}
+		switch fields[2] {
+		case "hello":
+			n.at(tick).helloTriggered = true
+		case "tc":
+			n.at(tick).tcTriggered = true
+		default:
+			return fmt.Errorf("unknown trigger: '%s'", fields[2])
+		}
+	case "stop":
+		fields := strings.Fields(rest)
+		if len(fields) != 2 {
+			return errors.New("stop must be of the form: 'stop {ID} {TICK}'")
+		}
+		n, tick, err := replayNodeTick(fields, nodes)
+		if err != nil {
+			return err
+		}
+		n.stop = tick
+	default:
+		return fmt.Errorf("unknown record: '%s'", kind)
+	}
+	return nil
+}
+
+// replayNodeTick parses the node ID and tick at the start of a record, for a node which has already been recorded.
+func replayNodeTick(fields []string, nodes map[NodeID]*replayNode) (*replayNode, int, error) {
+	id, err := parseNodeID(fields[0])
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid node ID: '%s'", fields[0])
+	}
+	n, in := nodes[id]
+	if !in {
+		return nil, 0, fmt.Errorf("node %s was not recorded", id)
+	}
+	tick, err := strconv.Atoi(fields[1])
+	if err != nil || tick < 0 {
+		return nil, 0, fmt.Errorf("invalid tick: '%s'", fields[1])
+	}
+	return n, tick, nil
+}
+
+// parseReplayInts parses non-negative integers.
+func parseReplayInts(fields []string) ([]int, error) {
+	ints := make([]int, 0, len(fields))
+	for _, field := range fields {
+		i, err := strconv.Atoi(field)
+		if err != nil || i < 0 {
+			return nil, fmt.Errorf("invalid integer: '%s'", field)
+		}
+		ints = append(ints, i)
+	}
+	return ints, nil
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestReplaySimulation(t *testing.T) {
+	topology, err := NewNetworkTypology(strings.NewReader(
+		"0 UP 0 1\n0 UP 1 0\n" +
+			"0 UP 0 2\n0 UP 2 0\n" +
+			"0 UP 1 3\n0 UP 3 1\n" +
+			"0 UP 2 3\n0 UP 3 2\n" +
+			"20 DOWN 1 3\n20 DOWN 3 1\n"))
+	if err != nil {
+		t.Fatalf("NewNetworkTypology() error = %v", err)
+	}
+	logDir, err := os.MkdirTemp("", "olsrsim")
+	if err != nil {
+		t.Fatalf("MkdirTemp() error = %v", err)
+	}
+	defer os.RemoveAll(logDir)
+
+	c := NewController(*topology, 5*time.Millisecond)
+	c.SetLogDir(logDir)
+	c.Initialize([]NodeConfig{
+		{ID: 0, Message: NodeMessage{Message: "hello 3", Delay: 15, Destination: 3}},
+		{ID: 1, Message: NodeMessage{Sent: true}},
+		{ID: 2, Message: NodeMessage{Sent: true}},
+		{ID: 3, Message: NodeMessage{Sent: true}, Interfaces: []NodeID{9}},
+	})
+	if err := c.ScheduleData(3, 0, "hello 0, from 3", 25); err != nil {
+		t.Fatalf("ScheduleData() error = %v", err)
+	}
+	if err := c.ScheduleNodeChange(0, 12, func(n *Node) { n.TriggerTC() }); err != nil {
+		t.Fatalf("ScheduleNodeChange() error = %v", err)
+	}
+	if err := c.ScheduleNodeChange(3, 17, func(n *Node) { n.TriggerHello() }); err != nil {
+		t.Fatalf("ScheduleNodeChange() error = %v", err)
+	}
+	var recording bytes.Buffer
+	c.RecordTo(&recording)
+	c.Start(40)
+
+	replayed, err := ReplaySimulation(bytes.NewReader(recording.Bytes()))
+	if err != nil {
+		t.Fatalf("ReplaySimulation() error = %v", err)
+	}
+	var want, got bytes.Buffer
+	if err := c.DumpState(&want); err != nil {
+		t.Fatalf("DumpState() error = %v", err)
+	}
+	if err := replayed.DumpState(&got); err != nil {
+		t.Fatalf("DumpState() error = %v", err)
+	}
+	if got.String() != want.String() {
+		t.Errorf("replayed state:\n%s\nwant:\n%s", got.String(), want.String())
+	}
+	for _, id := range []NodeID{0, 3} {
+		original, _ := c.node(id)
+		n, _ := replayed.node(id)
+		if got, want := n.Counters(), original.Counters(); got != want {
+			t.Errorf("node %d replayed counters = %+v, want %+v", id, got, want)
+		}
+	}
+}
+
+func TestReplaySimulation_errors(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+	}{
+		{name: "unknown record", in: "ping 0 1\n"},
+		{name: "unrecorded node", in: "stop 0 1\n"},
+		{name: "bad node", in: "node 0 1 x false 0 0 \"\"\n"},
+		{name: "bad group", in: "node 0 1 0 false 0 0 \"\"\njoin 0 5\n"},
+		{name: "bad message", in: "node 0 1 0 false 0 0 \"\"\ndeliver 0 1 AAAA\n"},
+		{name: "bad interface", in: "node 0 1 0 false 0 0 \"\"\ninterface 0 x\n"},
+		{name: "bad trigger", in: "node 0 1 0 false 0 0 \"\"\ntrigger 0 1 mid\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ReplaySimulation(strings.NewReader(tt.in))
+			var replayErr ErrReplay
+			if !errors.As(err, &replayErr) {
+				t.Errorf("ReplaySimulation() error = %v, want ErrReplay", err)
+			}
+		})
+	}
+}
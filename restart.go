@@ -0,0 +1,45 @@
+package main
+
+// Restart restarts the Node, as when it is power-cycled between ticks. Unless preserveState is set, as for a node which
+// keeps its protocol state in non-volatile storage, the Node forgets its neighbor, topology, and routing tables, and
+// its HELLO, TC, and MID sequence numbers start again from zero. Other nodes drop the restarted Node's HelloMessage(s)
+// and TCMessage(s) as stale until their entries for it expire, just as in a real network. Its MessageCounters and
+// routing history are always preserved, as they describe the whole simulation rather than the Node's protocol state.
+// Its parameters, set through its setters, are preserved too.
+func (n *Node) Restart(preserveState bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if preserveState {
+		return
+	}
+	n.helloSequenceNum = 0
+	n.tcSequenceNum = 0
+	n.tcMessageSequenceNum = 0
+	n.midSequenceNum = 0
+	n.emptyTCsSent = 0
+	n.advertisedMSSet = nil
+	n.incrementalTCsSent = 0
+	n.helloTriggered = false
+	n.tcTriggered = false
+
+	n.oneHopNeighbors = make(map[NodeID]oneHopNeighborEntry)
+	n.twoHopNeighbors = make(map[NodeID]map[NodeID]NodeID)
+	n.mprs = make(map[NodeID]NodeID)
+	n.uncoveredTwoHops = nil
+	n.msSet = make(map[NodeID]NodeID)
+	n.msHoldUntil = make(map[NodeID]int)
+	n.helloSequences = make(map[NodeID]int)
+	n.helloHistories = make(map[NodeID]helloHistory)
+	n.topologyTable = make(map[NodeID]map[NodeID]topologyEntry)
+	n.addedTopology = nil
+	n.tcSequences = make(map[NodeID]tcSequence)
+	n.tcDuplicates = make(map[tcMessageID]int)
+	n.midSequences = make(map[NodeID]int)
+	n.interfaceAssociations = make(map[NodeID]interfaceAssociation)
+	n.tcForwardedFor = make(map[tcMessageID]int)
+	n.tcForwardQueue = nil
+	n.ecmpNext = make(map[NodeID]int)
+	n.routingTable = make(map[NodeID]routingEntry)
+	n.routesChanged = true
+}
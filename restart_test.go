@@ -0,0 +1,66 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestNode_Restart(t *testing.T) {
+	tests := []struct {
+		name          string
+		preserveState bool
+		wantNeighbors int
+		wantTopology  int
+		wantHelloSeq  int
+	}{
+		{name: "reset", preserveState: false, wantNeighbors: 0, wantTopology: 0, wantHelloSeq: 0},
+		{name: "preserved", preserveState: true, wantNeighbors: 1, wantTopology: 1, wantHelloSeq: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			out := &recordingTransmitter{}
+			n := newTestNode(0, out)
+			n.handleHello(&HelloMessage{Source: 1, Sequence: 1, Bidirectional: []NodeID{0}})
+			n.handleTC(&TCMessage{Source: 2, FromNeighbor: 1, Sequence: 1, MultipointRelaySet: []NodeID{1}})
+			n.tick(nil)
+			counters := n.Counters()
+			if counters.HelloSent == 0 {
+				t.Fatalf("no HELLO sent before the restart")
+			}
+
+			n.Restart(tt.preserveState)
+			if got := len(n.Snapshot().OneHopNeighbors); got != tt.wantNeighbors {
+				t.Errorf("%d one-hop neighbors, want %d", got, tt.wantNeighbors)
+			}
+			if got := len(n.Snapshot().TopologyTable); got != tt.wantTopology {
+				t.Errorf("%d topology entries, want %d", got, tt.wantTopology)
+			}
+			if got := n.helloSequenceNum; got != tt.wantHelloSeq {
+				t.Errorf("helloSequenceNum = %d, want %d", got, tt.wantHelloSeq)
+			}
+			if got := n.Counters(); got != counters {
+				t.Errorf("Counters() = %+v, want preserved %+v", got, counters)
+			}
+		})
+	}
+}
+
+func TestNode_Restart_neighborRecovers(t *testing.T) {
+	n := newTestNode(0, &recordingTransmitter{})
+	n.SetNeighborHoldTime(3)
+	n.handleHello(&HelloMessage{Source: 1, Sequence: 5})
+
+	// Neighbor 1 restarts, so its sequence numbers start again, and its HELLOs are stale until its entry expires.
+	n.tick(nil)
+	n.handleHello(&HelloMessage{Source: 1, Sequence: 1, Bidirectional: []NodeID{0}})
+	if got, in := n.Snapshot().OneHopNeighbors[1]; !in || got != unidirectional {
+		t.Errorf("neighbor 1 = %v after a stale HELLO, want %v", got, unidirectional)
+	}
+	for i := 0; i < 3; i++ {
+		n.tick(nil)
+	}
+	n.handleHello(&HelloMessage{Source: 1, Sequence: 2})
+	n.handleHello(&HelloMessage{Source: 1, Sequence: 3, Bidirectional: []NodeID{0}})
+	if got, in := n.Snapshot().OneHopNeighbors[1]; !in || got != bidirectional {
+		t.Errorf("neighbor 1 = %v, %v once its entry expired, want %v", got, in, bidirectional)
+	}
+}
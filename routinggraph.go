@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+)
+
+// routingGraph is a directed multigraph in the node-link JSON format read by networkx's node_link_graph.
+type routingGraph struct {
+	Directed   bool               `json:"directed"`
+	Multigraph bool               `json:"multigraph"`
+	Graph      routingGraphAttrs  `json:"graph"`
+	Nodes      []routingGraphNode `json:"nodes"`
+	Links      []routingGraphLink `json:"links"`
+}
+
+type routingGraphAttrs struct {
+	Tick int `json:"tick"`
+}
+
+type routingGraphNode struct {
+	ID NodeID `json:"id"`
+
+	// Label is the NodeID as displayed in logs, which differs from the ID for dotted-quad addresses.
+	Label string `json:"label"`
+}
+
+// routingGraphLink is the next hop of a route, from the node holding the route. Routes from the same node through the
+// same next hop are parallel links, keyed by destination.
+type routingGraphLink struct {
+	Source      NodeID `json:"source"`
+	Target      NodeID `json:"target"`
+	Key         NodeID `json:"key"`
+	Destination NodeID `json:"destination"`
+	Distance    int    `json:"distance"`
+}
+
+// ExportRoutingGraph writes the nodes' current routing tables as a JSON node-link graph, for analysis with tools such
+// as networkx. Each route is a link from the node holding it to its next hop, annotated with the route's destination
+// and distance. Nodes are sorted by NodeID, and links by source then destination, so the output is deterministic.
+func (c *Controller) ExportRoutingGraph(w io.Writer) error {
+	g := routingGraph{
+		Directed:   true,
+		Multigraph: true,
+		Graph:      routingGraphAttrs{Tick: c.CurrentTick()},
+		Nodes:      make([]routingGraphNode, 0, len(c.nodes)),
+		Links:      make([]routingGraphLink, 0),
+	}
+	routes := make(map[NodeID][]RoutingEntry, len(c.nodes))
+	for _, n := range c.nodes {
+		n.mu.RLock()
+		routes[n.id] = n.routingEntries()
+		n.mu.RUnlock()
+	}
+	// Next hops and destinations which are not simulated nodes are still included as nodes of the graph.
+	nodes := make(map[NodeID]struct{}, len(routes))
+	for id, entries := range routes {
+		nodes[id] = struct{}{}
+		for _, entry := range entries {
+			nodes[entry.NextHop] = struct{}{}
+			nodes[entry.Destination] = struct{}{}
+		}
+	}
+	for _, id := range sortedNodeIDs(nodes) {
+		g.Nodes = append(g.Nodes, routingGraphNode{ID: id, Label: id.String()})
+	}
+	for _, id := range sortedNodeIDs(routes) {
+		for _, entry := range routes[id] {
+			g.Links = append(g.Links, routingGraphLink{
+				Source:      id,
+				Target:      entry.NextHop,
+				Key:         entry.Destination,
+				Destination: entry.Destination,
+				Distance:    entry.Distance,
+			})
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(g)
+}
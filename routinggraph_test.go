@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestController_ExportRoutingGraph(t *testing.T) {
+	const addr NodeID = 0x0A000001
+	// A line of nodes 1 - 2 - 10.0.0.1.
+	l := newLockstepNetwork(map[NodeID][]NodeID{
+		1:    {2},
+		2:    {1, addr},
+		addr: {2},
+	})
+	l.run(30)
+	c := NewController(NetworkTypology{}, time.Hour)
+	for _, id := range []NodeID{addr, 2, 1} {
+		c.nodes = append(c.nodes, l.nodes[id])
+	}
+
+	var buf bytes.Buffer
+	if err := c.ExportRoutingGraph(&buf); err != nil {
+		t.Fatalf("ExportRoutingGraph() error = %v", err)
+	}
+	var got routingGraph
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("json.Unmarshal() error = %v", err)
+	}
+	want := routingGraph{
+		Directed:   true,
+		Multigraph: true,
+		Nodes: []routingGraphNode{
+			{ID: 1, Label: "1"},
+			{ID: 2, Label: "2"},
+			{ID: addr, Label: "10.0.0.1"},
+		},
+		Links: []routingGraphLink{
+			{Source: 1, Target: 2, Key: 2, Destination: 2, Distance: 1},
+			{Source: 1, Target: 2, Key: addr, Destination: addr, Distance: 2},
+			{Source: 2, Target: 1, Key: 1, Destination: 1, Distance: 1},
+			{Source: 2, Target: addr, Key: addr, Destination: addr, Distance: 1},
+			{Source: addr, Target: 2, Key: 1, Destination: 1, Distance: 2},
+			{Source: addr, Target: 2, Key: 2, Destination: 2, Distance: 1},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ExportRoutingGraph() = %+v, want %+v", got, want)
+	}
+
+	// The output is deterministic.
+	var again bytes.Buffer
+	if err := c.ExportRoutingGraph(&again); err != nil {
+		t.Fatalf("ExportRoutingGraph() error = %v", err)
+	}
+	if again.String() != buf.String() {
+		t.Errorf("ExportRoutingGraph() = %s, want %s", again.String(), buf.String())
+	}
+}
@@ -0,0 +1,43 @@
+package main
+
+// OneHopNeighborEntry is a one-hop neighbor to prime a Node with. See SeedNeighbors.
+type OneHopNeighborEntry struct {
+	ID NodeID
+
+	// State is the Node's perceived state of the link. An mpr is seeded as a bidirectional neighbor, as the Node
+	// selects its own MPRs.
+	State NeighborState
+}
+
+// SeedNeighbors is a test and bootstrap helper which primes the Node's neighbor tables directly, bypassing neighbor
+// discovery, then selects its MPRs as a received HelloMessage would. twoHop maps one-hop neighbors to the two-hop
+// neighbors reachable through them, replacing any already known. Seeded entries are held for the neighbor hold time
+// from the current tick, and expire like discovered ones unless refreshed by HelloMessage(s).
+func (n *Node) SeedNeighbors(oneHop []OneHopNeighborEntry, twoHop map[NodeID][]NodeID) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	holdUntil := n.currentTick + n.neighborHoldTime
+	for _, entry := range oneHop {
+		if entry.ID == n.id {
+			continue
+		}
+		state := entry.State
+		if state == mpr {
+			state = bidirectional
+		}
+		n.oneHopNeighbors[entry.ID] = oneHopNeighborEntry{neighborID: entry.ID, state: state, holdUntil: holdUntil}
+	}
+	for via, ids := range twoHop {
+		twoHops := make(map[NodeID]NodeID)
+		for _, id := range ids {
+			if id != n.id {
+				twoHops[id] = id
+			}
+		}
+		n.twoHopNeighbors[via] = twoHops
+	}
+
+	n.selectMPRs()
+	n.routesChanged = true
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNode_SeedNeighbors(t *testing.T) {
+	tests := []struct {
+		name     string
+		oneHop   []OneHopNeighborEntry
+		twoHop   map[NodeID][]NodeID
+		wantOne  map[NodeID]NeighborState
+		wantMPRs []NodeID
+	}{
+		{
+			name:     "one-hop only",
+			oneHop:   []OneHopNeighborEntry{{ID: 1, State: bidirectional}, {ID: 2, State: unidirectional}},
+			wantOne:  map[NodeID]NeighborState{1: bidirectional, 2: unidirectional},
+			wantMPRs: []NodeID{},
+		},
+		{
+			name:     "mpr recomputed",
+			oneHop:   []OneHopNeighborEntry{{ID: 1, State: bidirectional}, {ID: 2, State: mpr}, {ID: 3, State: unidirectional}},
+			twoHop:   map[NodeID][]NodeID{1: {4, 5}, 2: {5, 0}, 3: {6}},
+			wantOne:  map[NodeID]NeighborState{1: mpr, 2: bidirectional, 3: unidirectional},
+			wantMPRs: []NodeID{1},
+		},
+		{
+			name:     "own id ignored",
+			oneHop:   []OneHopNeighborEntry{{ID: 0, State: bidirectional}, {ID: 1, State: bidirectional}},
+			twoHop:   map[NodeID][]NodeID{1: {0, 2}},
+			wantOne:  map[NodeID]NeighborState{1: mpr},
+			wantMPRs: []NodeID{1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := newTestNode(0, &recordingTransmitter{})
+			n.SeedNeighbors(tt.oneHop, tt.twoHop)
+
+			s := n.Snapshot()
+			if !reflect.DeepEqual(s.OneHopNeighbors, tt.wantOne) {
+				t.Errorf("OneHopNeighbors = %v, want %v", s.OneHopNeighbors, tt.wantOne)
+			}
+			if !reflect.DeepEqual(s.MPRs, tt.wantMPRs) {
+				t.Errorf("MPRs = %v, want %v", s.MPRs, tt.wantMPRs)
+			}
+		})
+	}
+}
+
+func TestNode_SeedNeighbors_routes(t *testing.T) {
+	n := newTestNode(0, &recordingTransmitter{})
+	n.SeedNeighbors([]OneHopNeighborEntry{{ID: 1, State: bidirectional}}, map[NodeID][]NodeID{1: {2}})
+	n.tick(nil)
+
+	want := []RoutingEntry{{Destination: 1, NextHop: 1, Distance: 1}, {Destination: 2, NextHop: 1, Distance: 2}}
+	if got := n.Snapshot().RoutingTable; !reflect.DeepEqual(got, want) {
+		t.Errorf("RoutingTable = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,485 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// TopologyEntry is a read-only view of an entry within a Node's topology table.
+type TopologyEntry struct {
+	// Destination is the mpr selector advertised in a TCMessage.
+	Destination NodeID
+
+	// Originator is the originator of the TCMessage (last-hop node to the destination).
+	Originator NodeID
+
+	// Sequence is the sequence number of the TCMessage which created the entry.
+	Sequence int
+
+	// HoldUntil is the tick at which the entry will be expelled.
+	HoldUntil int
+
+	// ExpiresIn is the number of ticks until the entry will be expelled.
+	ExpiresIn int
+}
+
+// RoutingEntry is a read-only view of an entry within a Node's routing table.
+type RoutingEntry struct {
+	// Destination is the node the route leads to.
+	Destination NodeID
+
+	// NextHop is where to send a message to in order to reach the destination.
+	NextHop NodeID
+
+	// Distance is the number of hops needed to reach the destination.
+	Distance int
+}
+
+// NodeState is a copy of a Node's state at a moment in time.
+type NodeState struct {
+	ID   NodeID
+	Tick int
+
+	// OneHopNeighbors maps each one-hop neighbor to the Node's perceived state of the link.
+	OneHopNeighbors map[NodeID]NeighborState
+
+	// TwoHopNeighbors maps each one-hop neighbor to the sorted two-hop neighbors reachable through it.
+	TwoHopNeighbors map[NodeID][]NodeID
+
+	// MPRs is the sorted set of neighbors selected as multipoint relays.
+	MPRs []NodeID
+
+	// MPRSelectors is the sorted set of neighbors which selected the Node as a multipoint relay.
+	MPRSelectors []NodeID
+
+	// TopologyTable is sorted by originator, then destination.
+	TopologyTable []TopologyEntry
+
+	// RoutingTable is sorted by destination.
+	RoutingTable []RoutingEntry
+}
+
+// Snapshot copies the Node's current state.
+func (n *Node) Snapshot() NodeState {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	s := NodeState{
+		ID:              n.id,
+		Tick:            n.currentTick,
+		OneHopNeighbors: make(map[NodeID]NeighborState),
+		TwoHopNeighbors: make(map[NodeID][]NodeID),
+		MPRs:            make([]NodeID, 0),
+		MPRSelectors:    sortedNodeIDs(n.msSet),
+		RoutingTable:    make([]RoutingEntry, 0),
+	}
+	for _, id := range sortedNodeIDs(n.oneHopNeighbors) {
+		state := n.oneHopNeighbors[id].state
+		s.OneHopNeighbors[id] = state
+		if state == mpr {
+			s.MPRs = append(s.MPRs, id)
+		}
+	}
+	for id, twoHops := range n.twoHopNeighbors {
+		s.TwoHopNeighbors[id] = sortedNodeIDs(twoHops)
+	}
+	s.TopologyTable = n.topologyEntries()
+	s.RoutingTable = n.routingEntries()
+	return s
+}
+
+// routingEntries flattens the routing table, sorted by destination. With equalCostMultipath, each destination has an
+// entry for each of its next hops, sorted by next hop.
+func (n *Node) routingEntries() []RoutingEntry {
+	flattened := make([]RoutingEntry, 0, len(n.routingTable))
+	for _, dst := range sortedNodeIDs(n.routingTable) {
+		entry := n.routingTable[dst]
+		nextHops := []NodeID{entry.nextHop}
+		if n.routeTieBreak == equalCostMultipath && len(entry.nextHops) > 0 {
+			nextHops = entry.nextHops
+		}
+		for _, nextHop := range nextHops {
+			flattened = append(flattened, RoutingEntry{
+				Destination: entry.dst,
+				NextHop:     nextHop,
+				Distance:    entry.distance,
+			})
+		}
+	}
+	return flattened
+}
+
+// routingHistoryEntry is the Node's routing table as of a tick.
+type routingHistoryEntry struct {
+	tick   int
+	routes []RoutingEntry
+}
+
+// SetRoutingHistoryDepth sets the number of routing table changes the Node retains for RoutingTableAt. Zero, the
+// default, disables the history. Reducing the depth discards the oldest entries beyond it.
+func (n *Node) SetRoutingHistoryDepth(depth int) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.routingHistoryDepth = depth
+	if depth <= 0 {
+		n.routingHistory = nil
+	} else if len(n.routingHistory) > depth {
+		n.routingHistory = n.routingHistory[len(n.routingHistory)-depth:]
+	}
+}
+
+// recordRoutingTable adds the current routing table to the routing history if it differs from the most recent entry.
+// The oldest entry is discarded once the history holds routingHistoryDepth entries.
+func (n *Node) recordRoutingTable() {
+	if n.routingHistoryDepth <= 0 {
+		return
+	}
+	routes := n.routingEntries()
+	if len(n.routingHistory) > 0 && equalRoutingEntries(n.routingHistory[len(n.routingHistory)-1].routes, routes) {
+		return
+	}
+	if len(n.routingHistory) >= n.routingHistoryDepth {
+		n.routingHistory = n.routingHistory[len(n.routingHistory)-n.routingHistoryDepth+1:]
+	}
+	n.routingHistory = append(n.routingHistory, routingHistoryEntry{tick: n.currentTick, routes: routes})
+}
+
+// equalRoutingEntries determines whether both routing tables contain the same entries in the same order.
+func equalRoutingEntries(a, b []RoutingEntry) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// RoutingTableAt returns the Node's routing table as of the end of the given tick, sorted by destination.
+// Returns nil if the tick predates the retained routing history.
+func (n *Node) RoutingTableAt(tick int) []RoutingEntry {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	for i := len(n.routingHistory) - 1; i >= 0; i-- {
+		if n.routingHistory[i].tick <= tick {
+			return append([]RoutingEntry(nil), n.routingHistory[i].routes...)
+		}
+	}
+	return nil
+}
+
+// TopologyEntries returns a copy of every entry within the Node's topology table, sorted by originator then
+// destination.
+func (n *Node) TopologyEntries() []TopologyEntry {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	return n.topologyEntries()
+}
+
+// topologyEntries flattens the topology table, sorted by originator then destination.
+func (n *Node) topologyEntries() []TopologyEntry {
+	flattened := make([]TopologyEntry, 0)
+	for _, originator := range sortedNodeIDs(n.topologyTable) {
+		entries := n.topologyTable[originator]
+		for _, dst := range sortedNodeIDs(entries) {
+			entry := entries[dst]
+			flattened = append(flattened, TopologyEntry{
+				Destination: entry.dst,
+				Originator:  entry.originator,
+				Sequence:    entry.seq,
+				HoldUntil:   entry.holdUntil,
+				ExpiresIn:   entry.holdUntil - n.currentTick,
+			})
+		}
+	}
+	return flattened
+}
+
+// DiffNodeState describes the differences from state a to state b, one difference per line.
+// Differences are ordered by table, then by NodeID.
+func DiffNodeState(a, b NodeState) []string {
+	diffs := make([]string, 0)
+
+	// One-hop neighbors.
+	for _, id := range mergedNodeIDs(a.OneHopNeighbors, b.OneHopNeighbors) {
+		before, inA := a.OneHopNeighbors[id]
+		after, inB := b.OneHopNeighbors[id]
+		switch {
+		case !inA:
+			diffs = append(diffs, fmt.Sprintf("one-hop neighbor %s: added %s", id, after))
+		case !inB:
+			diffs = append(diffs, fmt.Sprintf("one-hop neighbor %s: removed %s", id, before))
+		case before != after:
+			diffs = append(diffs, fmt.Sprintf("one-hop neighbor %s: %s -> %s", id, before, after))
+		}
+	}
+
+	// Two-hop neighbors.
+	for _, via := range mergedNodeIDs(a.TwoHopNeighbors, b.TwoHopNeighbors) {
+		before := nodeIDSet(a.TwoHopNeighbors[via])
+		after := nodeIDSet(b.TwoHopNeighbors[via])
+		for _, id := range mergedNodeIDs(before, after) {
+			_, inA := before[id]
+			_, inB := after[id]
+			switch {
+			case !inA:
+				diffs = append(diffs, fmt.Sprintf("two-hop neighbor %s via %s: added", id, via))
+			case !inB:
+				diffs = append(diffs, fmt.Sprintf("two-hop neighbor %s via %s: removed", id, via))
+			}
+		}
+	}
+
+	// MPR and MPR selector sets.
+	diffs = append(diffs, diffNodeIDSets("mpr", a.MPRs, b.MPRs)...)
+	diffs = append(diffs, diffNodeIDSets("mpr selector", a.MPRSelectors, b.MPRSelectors)...)
+
+	// Topology table, keyed by originator then destination.
+	type topologyKey struct {
+		originator NodeID
+		dst        NodeID
+	}
+	topologyA := make(map[topologyKey]TopologyEntry)
+	for _, entry := range a.TopologyTable {
+		topologyA[topologyKey{entry.Originator, entry.Destination}] = entry
+	}
+	topologyB := make(map[topologyKey]TopologyEntry)
+	for _, entry := range b.TopologyTable {
+		topologyB[topologyKey{entry.Originator, entry.Destination}] = entry
+	}
+	for _, entry := range a.TopologyTable {
+		key := topologyKey{entry.Originator, entry.Destination}
+		after, in := topologyB[key]
+		if !in {
+			diffs = append(diffs, fmt.Sprintf("topology entry %s via %s: removed", key.dst, key.originator))
+			continue
+		}
+		if after.Sequence != entry.Sequence {
+			diffs = append(diffs, fmt.Sprintf("topology entry %s via %s: seq %d -> %d", key.dst, key.originator, entry.Sequence, after.Sequence))
+		}
+	}
+	for _, entry := range b.TopologyTable {
+		key := topologyKey{entry.Originator, entry.Destination}
+		if _, in := topologyA[key]; !in {
+			diffs = append(diffs, fmt.Sprintf("topology entry %s via %s: added seq %d", key.dst, key.originator, entry.Sequence))
+		}
+	}
+
+	// Routing table. Only the lowest next hop of an equal-cost multipath route is compared.
+	routesA := make(map[NodeID]RoutingEntry)
+	for _, entry := range a.RoutingTable {
+		if _, in := routesA[entry.Destination]; !in {
+			routesA[entry.Destination] = entry
+		}
+	}
+	routesB := make(map[NodeID]RoutingEntry)
+	for _, entry := range b.RoutingTable {
+		if _, in := routesB[entry.Destination]; !in {
+			routesB[entry.Destination] = entry
+		}
+	}
+	for _, dst := range mergedNodeIDs(routesA, routesB) {
+		before, inA := routesA[dst]
+		after, inB := routesB[dst]
+		switch {
+		case !inA:
+			diffs = append(diffs, fmt.Sprintf("route to %s: added next hop %s distance %d", dst, after.NextHop, after.Distance))
+		case !inB:
+			diffs = append(diffs, fmt.Sprintf("route to %s: removed next hop %s distance %d", dst, before.NextHop, before.Distance))
+		case before != after:
+			diffs = append(diffs, fmt.Sprintf("route to %s: next hop %s -> %s, distance %d -> %d", dst, before.NextHop, after.NextHop, before.Distance, after.Distance))
+		}
+	}
+
+	return diffs
+}
+
+// diffNodeIDSets describes the NodeID(s) added to and removed from a set.
+func diffNodeIDSets(name string, a, b []NodeID) []string {
+	diffs := make([]string, 0)
+	before := nodeIDSet(a)
+	after := nodeIDSet(b)
+	for _, id := range mergedNodeIDs(before, after) {
+		_, inA := before[id]
+		_, inB := after[id]
+		switch {
+		case !inA:
+			diffs = append(diffs, fmt.Sprintf("%s %s: added", name, id))
+		case !inB:
+			diffs = append(diffs, fmt.Sprintf("%s %s: removed", name, id))
+		}
+	}
+	return diffs
+}
+
+// nodeIDSet creates a set from the NodeID(s).
+func nodeIDSet(ids []NodeID) map[NodeID]NodeID {
+	set := make(map[NodeID]NodeID)
+	for _, id := range ids {
+		set[id] = id
+	}
+	return set
+}
+
+// mergedNodeIDs returns the keys present in either map in increasing order.
+func mergedNodeIDs[V any](a, b map[NodeID]V) []NodeID {
+	merged := make(map[NodeID]struct{})
+	for id := range a {
+		merged[id] = struct{}{}
+	}
+	for id := range b {
+		merged[id] = struct{}{}
+	}
+	return sortedNodeIDs(merged)
+}
+
+// NodeSummary is a condensed view of a Node's state, suitable for rendering on a dashboard.
+type NodeSummary struct {
+	ID   NodeID
+	Tick int
+
+	// Neighbors is the number of one-hop neighbors, of any state.
+	Neighbors int
+
+	// SymmetricNeighbors is the number of bidirectional and mpr one-hop neighbors.
+	SymmetricNeighbors int
+
+	// MPRs is the sorted set of neighbors selected as multipoint relays.
+	MPRs []NodeID
+
+	// MPRSelectors is the sorted set of neighbors which selected the Node as a multipoint relay.
+	MPRSelectors []NodeID
+
+	// RoutingTableSize is the number of reachable destinations.
+	RoutingTableSize int
+}
+
+// NetworkSnapshot is a copy of every Node's state at a moment in time.
+type NetworkSnapshot struct {
+	// Tick is the Controller's current tick.
+	Tick int
+
+	// Nodes is sorted by NodeID.
+	Nodes []NodeSummary
+}
+
+// Snapshot summarizes every Node's state. Each Node is summarized under its own lock, so it is safe to call while the
+// simulation is running.
+func (c *Controller) Snapshot() NetworkSnapshot {
+	nodes := make([]*Node, len(c.nodes))
+	copy(nodes, c.nodes)
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].id < nodes[j].id
+	})
+
+	s := NetworkSnapshot{
+		Tick:  c.CurrentTick(),
+		Nodes: make([]NodeSummary, 0, len(nodes)),
+	}
+	for _, n := range nodes {
+		state := n.Snapshot()
+		summary := NodeSummary{
+			ID:               state.ID,
+			Tick:             state.Tick,
+			Neighbors:        len(state.OneHopNeighbors),
+			MPRs:             state.MPRs,
+			MPRSelectors:     state.MPRSelectors,
+			RoutingTableSize: len(state.RoutingTable),
+		}
+		for _, neighborState := range state.OneHopNeighbors {
+			if neighborState != unidirectional {
+				summary.SymmetricNeighbors++
+			}
+		}
+		s.Nodes = append(s.Nodes, summary)
+	}
+	return s
+}
+
+// DumpState writes every Node's state in a stable, fully sorted textual form, suitable for comparing against a golden
+// file. Nodes are written in NodeID order.
+func (c *Controller) DumpState(w io.Writer) error {
+	nodes := make([]*Node, len(c.nodes))
+	copy(nodes, c.nodes)
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].id < nodes[j].id
+	})
+
+	bw := bufio.NewWriter(w)
+	for _, n := range nodes {
+		s := n.Snapshot()
+		fmt.Fprintf(bw, "node %s tick %d\n", s.ID, s.Tick)
+		for _, id := range sortedNodeIDs(s.OneHopNeighbors) {
+			fmt.Fprintf(bw, "\tneighbor %s %s\n", id, s.OneHopNeighbors[id])
+		}
+		for _, via := range sortedNodeIDs(s.TwoHopNeighbors) {
+			for _, id := range s.TwoHopNeighbors[via] {
+				fmt.Fprintf(bw, "\ttwo-hop neighbor %s via %s\n", id, via)
+			}
+		}
+		for _, id := range s.MPRSelectors {
+			fmt.Fprintf(bw, "\tmpr selector %s\n", id)
+		}
+		for _, entry := range s.TopologyTable {
+			fmt.Fprintf(bw, "\ttopology %s via %s seq %d hold until %d\n", entry.Destination, entry.Originator, entry.Sequence, entry.HoldUntil)
+		}
+		for _, entry := range s.RoutingTable {
+			fmt.Fprintf(bw, "\troute %s via %s distance %d\n", entry.Destination, entry.NextHop, entry.Distance)
+		}
+	}
+	return bw.Flush()
+}
+
+// NeighborDiff compares a Node's one-hop neighbors with the links it actually has.
+type NeighborDiff struct {
+	// Missing are the sorted nodes the Node can hear, but has not discovered as neighbors.
+	Missing []NodeID
+
+	// Spurious are the sorted nodes the Node holds as neighbors, but can no longer hear.
+	Spurious []NodeID
+}
+
+// NeighborAccuracy compares each online Node's one-hop neighbors against the online nodes with a link to it in the
+// topology at the current tick. Neighbor discovery necessarily lags the topology, by at least a HELLO interval when a
+// link comes up and up to the neighbor hold time when one goes down, so some difference is expected while the topology
+// changes; a difference which persists indicates a bug.
+func (c *Controller) NeighborAccuracy() map[NodeID]NeighborDiff {
+	tick := c.CurrentTick()
+	online := make([]NodeID, 0, len(c.nodes))
+	for _, n := range c.nodes {
+		if c.isOnline(n.id, tick) {
+			online = append(online, n.id)
+		}
+	}
+	sortNodeIDs(online)
+
+	accuracy := make(map[NodeID]NeighborDiff, len(online))
+	for _, id := range online {
+		n, _ := c.node(id)
+		discovered := n.Snapshot().OneHopNeighbors
+		diff := NeighborDiff{Missing: make([]NodeID, 0), Spurious: make([]NodeID, 0)}
+		expected := make(map[NodeID]struct{})
+		for _, from := range online {
+			if from != id && c.linkUp(QueryMsg{FromNode: from, ToNode: id, AtTime: tick}) {
+				expected[from] = struct{}{}
+				if _, in := discovered[from]; !in {
+					diff.Missing = append(diff.Missing, from)
+				}
+			}
+		}
+		for _, neighbor := range sortedNodeIDs(discovered) {
+			if _, in := expected[neighbor]; !in {
+				diff.Spurious = append(diff.Spurious, neighbor)
+			}
+		}
+		accuracy[id] = diff
+	}
+	return accuracy
+}
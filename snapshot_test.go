@@ -0,0 +1,353 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNode_Snapshot(t *testing.T) {
+	n := newTestNode(0, &recordingTransmitter{})
+	n.handleHello(&HelloMessage{Source: 1, Bidirectional: []NodeID{0, 2}, MultipointRelay: nil, Sequence: 0})
+	n.handleHello(&HelloMessage{Source: 1, Bidirectional: []NodeID{0, 2}, MultipointRelay: nil, Sequence: 1})
+	n.handleTC(&TCMessage{Source: 2, FromNeighbor: 1, Sequence: 4, MultipointRelaySet: []NodeID{3}})
+	n.calculateRoutingTable()
+
+	want := NodeState{
+		ID:              0,
+		Tick:            0,
+		OneHopNeighbors: map[NodeID]NeighborState{1: mpr},
+		TwoHopNeighbors: map[NodeID][]NodeID{1: {2}},
+		MPRs:            []NodeID{1},
+		MPRSelectors:    []NodeID{},
+		TopologyTable: []TopologyEntry{
+			{Destination: 3, Originator: 2, Sequence: 4, HoldUntil: 30, ExpiresIn: 30},
+		},
+		RoutingTable: []RoutingEntry{
+			{Destination: 1, NextHop: 1, Distance: 1},
+			{Destination: 2, NextHop: 1, Distance: 2},
+			{Destination: 3, NextHop: 1, Distance: 3},
+		},
+	}
+	if got := n.Snapshot(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Snapshot() = %v, want %v", got, want)
+	}
+}
+
+func TestDiffNodeState(t *testing.T) {
+	type args struct {
+		a NodeState
+		b NodeState
+	}
+	tests := []struct {
+		name string
+		args args
+		want []string
+	}{
+		{
+			name: "identical",
+			args: args{
+				a: NodeState{
+					OneHopNeighbors: map[NodeID]NeighborState{1: bidirectional},
+					RoutingTable:    []RoutingEntry{{Destination: 1, NextHop: 1, Distance: 1}},
+				},
+				b: NodeState{
+					OneHopNeighbors: map[NodeID]NeighborState{1: bidirectional},
+					RoutingTable:    []RoutingEntry{{Destination: 1, NextHop: 1, Distance: 1}},
+				},
+			},
+			want: []string{},
+		},
+		{
+			name: "all tables",
+			args: args{
+				a: NodeState{
+					OneHopNeighbors: map[NodeID]NeighborState{1: unidirectional, 2: bidirectional},
+					TwoHopNeighbors: map[NodeID][]NodeID{2: {4}},
+					MPRs:            []NodeID{},
+					MPRSelectors:    []NodeID{2},
+					TopologyTable: []TopologyEntry{
+						{Destination: 5, Originator: 4, Sequence: 1},
+						{Destination: 6, Originator: 4, Sequence: 1},
+					},
+					RoutingTable: []RoutingEntry{
+						{Destination: 2, NextHop: 2, Distance: 1},
+						{Destination: 5, NextHop: 2, Distance: 3},
+					},
+				},
+				b: NodeState{
+					OneHopNeighbors: map[NodeID]NeighborState{1: bidirectional, 3: mpr},
+					TwoHopNeighbors: map[NodeID][]NodeID{3: {4}},
+					MPRs:            []NodeID{3},
+					MPRSelectors:    []NodeID{},
+					TopologyTable: []TopologyEntry{
+						{Destination: 5, Originator: 4, Sequence: 2},
+						{Destination: 7, Originator: 4, Sequence: 2},
+					},
+					RoutingTable: []RoutingEntry{
+						{Destination: 3, NextHop: 3, Distance: 1},
+						{Destination: 5, NextHop: 3, Distance: 3},
+					},
+				},
+			},
+			want: []string{
+				"one-hop neighbor 1: UNIDIR -> BIDIR",
+				"one-hop neighbor 2: removed BIDIR",
+				"one-hop neighbor 3: added MPR",
+				"two-hop neighbor 4 via 2: removed",
+				"two-hop neighbor 4 via 3: added",
+				"mpr 3: added",
+				"mpr selector 2: removed",
+				"topology entry 5 via 4: seq 1 -> 2",
+				"topology entry 6 via 4: removed",
+				"topology entry 7 via 4: added seq 2",
+				"route to 2: removed next hop 2 distance 1",
+				"route to 3: added next hop 3 distance 1",
+				"route to 5: next hop 2 -> 3, distance 3 -> 3",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DiffNodeState(tt.args.a, tt.args.b); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DiffNodeState() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNode_TopologyEntries(t *testing.T) {
+	n := newTestNode(0, &recordingTransmitter{})
+	n.handleTC(&TCMessage{Source: 3, FromNeighbor: 1, Sequence: 1, MultipointRelaySet: []NodeID{5, 4}})
+	n.currentTick = 10
+	n.handleTC(&TCMessage{Source: 2, FromNeighbor: 1, Sequence: 7, MultipointRelaySet: []NodeID{0, 6}})
+	n.currentTick = 25
+
+	want := []TopologyEntry{
+		{Destination: 6, Originator: 2, Sequence: 7, HoldUntil: 40, ExpiresIn: 15},
+		{Destination: 4, Originator: 3, Sequence: 1, HoldUntil: 30, ExpiresIn: 5},
+		{Destination: 5, Originator: 3, Sequence: 1, HoldUntil: 30, ExpiresIn: 5},
+	}
+	got := n.TopologyEntries()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("TopologyEntries() = %v, want %v", got, want)
+	}
+
+	// The returned entries must be a copy.
+	got[0].Sequence = 100
+	if n.topologyTable[2][6].seq != 7 {
+		t.Errorf("TopologyEntries() did not return a copy")
+	}
+}
+
+func TestNode_RoutingTableAt(t *testing.T) {
+	hello := func(seq int, bidirectional ...NodeID) *HelloMessage {
+		return &HelloMessage{Source: 1, Bidirectional: bidirectional, Sequence: seq}
+	}
+	n := newTestNode(0, &recordingTransmitter{})
+	n.SetRoutingHistoryDepth(2)
+
+	// Tick 0: an empty routing table.
+	n.tick(nil)
+	// Tick 1: neighbor 1 becomes symmetric.
+	n.tick([]interface{}{hello(0, 0), hello(1, 0)})
+	// Tick 2: no change, so no new history entry.
+	n.tick(nil)
+	// Tick 3: node 2 is reachable via 1.
+	n.tick([]interface{}{hello(2, 0, 2)})
+
+	toOne := RoutingEntry{Destination: 1, NextHop: 1, Distance: 1}
+	toTwo := RoutingEntry{Destination: 2, NextHop: 1, Distance: 2}
+	tests := []struct {
+		tick int
+		want []RoutingEntry
+	}{
+		// The empty table at tick 0 was discarded, as the history only holds 2 entries.
+		{tick: 0, want: nil},
+		{tick: 1, want: []RoutingEntry{toOne}},
+		{tick: 2, want: []RoutingEntry{toOne}},
+		{tick: 3, want: []RoutingEntry{toOne, toTwo}},
+		{tick: 100, want: []RoutingEntry{toOne, toTwo}},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.tick), func(t *testing.T) {
+			if got := n.RoutingTableAt(tt.tick); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("RoutingTableAt(%d) = %v, want %v", tt.tick, got, tt.want)
+			}
+		})
+	}
+
+	// Reducing the depth discards the oldest entries.
+	n.SetRoutingHistoryDepth(1)
+	if got := n.RoutingTableAt(2); got != nil {
+		t.Errorf("RoutingTableAt(2) after reducing the depth = %v, want nil", got)
+	}
+	if got, want := n.RoutingTableAt(3), []RoutingEntry{toOne, toTwo}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RoutingTableAt(3) after reducing the depth = %v, want %v", got, want)
+	}
+
+	disabled := newTestNode(0, &recordingTransmitter{})
+	disabled.tick([]interface{}{hello(0, 0)})
+	if got := disabled.RoutingTableAt(0); got != nil {
+		t.Errorf("RoutingTableAt() with history disabled = %v, want nil", got)
+	}
+}
+
+func TestController_Snapshot(t *testing.T) {
+	c := NewController(NetworkTypology{}, time.Millisecond)
+
+	n1 := newTestNode(1, &recordingTransmitter{})
+	n1.handleHello(&HelloMessage{Source: 0, Bidirectional: []NodeID{1}, MultipointRelay: []NodeID{1}, Sequence: 0})
+	n1.handleHello(&HelloMessage{Source: 0, Bidirectional: nil, MultipointRelay: []NodeID{1}, Sequence: 1})
+	n1.handleHello(&HelloMessage{Source: 2, Sequence: 0})
+	n1.calculateRoutingTable()
+	n0 := newTestNode(0, &recordingTransmitter{})
+	c.nodes = append(c.nodes, n1, n0)
+
+	want := NetworkSnapshot{
+		Tick: 0,
+		Nodes: []NodeSummary{
+			{ID: 0, MPRs: []NodeID{}, MPRSelectors: []NodeID{}},
+			{
+				ID:                 1,
+				Neighbors:          2,
+				SymmetricNeighbors: 1,
+				MPRs:               []NodeID{},
+				MPRSelectors:       []NodeID{0},
+				RoutingTableSize:   1,
+			},
+		},
+	}
+	got := c.Snapshot()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Snapshot() = %+v, want %+v", got, want)
+	}
+	if _, err := json.Marshal(got); err != nil {
+		t.Errorf("json.Marshal(Snapshot()) error = %v", err)
+	}
+}
+
+func TestController_SnapshotWhileRunning(t *testing.T) {
+	c := NewController(NetworkTypology{}, time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	var wg sync.WaitGroup
+	for id := NodeID(0); id < 3; id++ {
+		n := newTestNode(id, &recordingTransmitter{})
+		c.nodes = append(c.nodes, n)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			n.Run(ctx)
+		}()
+	}
+
+	// Polling from another goroutine must not race with the running nodes.
+	for i := 0; i < 20; i++ {
+		if got := len(c.Snapshot().Nodes); got != 3 {
+			t.Errorf("Snapshot() has %d nodes, want 3", got)
+		}
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	wg.Wait()
+}
+
+// update makes golden file tests overwrite their golden files with the current output.
+var update = flag.Bool("update", false, "update golden files")
+
+// assertGolden compares the output against the named golden file within testdata.
+func assertGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatalf("updating golden file: %v", err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file: %v", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output does not match %s, rerun with -update if the change is intended:\n%s", path, got)
+	}
+}
+
+func TestController_DumpState(t *testing.T) {
+	// A diamond with a tail: 0 reaches 3 via 1 or 2, and 4 via 3.
+	network := newLockstepNetwork(map[NodeID][]NodeID{
+		0: {1, 2},
+		1: {0, 3},
+		2: {0, 3},
+		3: {1, 2, 4},
+		4: {3},
+	})
+	network.run(60)
+
+	c := NewController(NetworkTypology{}, time.Millisecond)
+	for _, id := range []NodeID{3, 1, 4, 0, 2} {
+		c.nodes = append(c.nodes, network.nodes[id])
+	}
+	var buf bytes.Buffer
+	if err := c.DumpState(&buf); err != nil {
+		t.Fatalf("DumpState() error = %v", err)
+	}
+	assertGolden(t, "diamond_state.golden", buf.Bytes())
+}
+
+func TestController_NeighborAccuracy(t *testing.T) {
+	empty := NeighborDiff{Missing: []NodeID{}, Spurious: []NodeID{}}
+	tests := []struct {
+		name  string
+		ticks int
+		want  map[NodeID]NeighborDiff
+	}{
+		{
+			// The link between 1 and 2 has just gone down, and the link from 3 to 0 has just come up.
+			name:  "lagging",
+			ticks: 25,
+			want: map[NodeID]NeighborDiff{
+				0: {Missing: []NodeID{3}, Spurious: []NodeID{}},
+				1: {Missing: []NodeID{}, Spurious: []NodeID{2}},
+				2: {Missing: []NodeID{}, Spurious: []NodeID{1}},
+				3: empty,
+			},
+		},
+		{
+			name:  "caught up",
+			ticks: 40,
+			want:  map[NodeID]NeighborDiff{0: empty, 1: empty, 2: empty, 3: empty},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topology, err := NewNetworkTypology(strings.NewReader(
+				"0 UP 0 1\n0 UP 1 0\n0 UP 1 2\n0 UP 2 1\n20 DOWN 1 2\n20 DOWN 2 1\n22 UP 3 0\n"))
+			if err != nil {
+				t.Fatalf("NewNetworkTypology() error = %v", err)
+			}
+			c := NewController(*topology, time.Hour)
+			c.SetLogDir(t.TempDir())
+			configs := make([]NodeConfig, 0)
+			for id := NodeID(0); id < 4; id++ {
+				configs = append(configs, NodeConfig{ID: id, Message: NodeMessage{Sent: true}})
+			}
+			c.Initialize(configs)
+			c.SetSynchronous(true)
+			c.Start(tt.ticks)
+
+			if got := c.NeighborAccuracy(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NeighborAccuracy() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
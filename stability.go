@@ -0,0 +1,48 @@
+package main
+
+// countRouteChanges counts, for each destination, whether its route changed between the previous and current routing
+// tables: its next hop or distance changed, or it was removed, or added back after being removed. A destination's
+// first route is recorded without being counted as a change.
+func countRouteChanges(previous, current map[NodeID]routingEntry, changes map[NodeID]int) {
+	for dst, entry := range current {
+		before, in := previous[dst]
+		if _, seen := changes[dst]; !seen {
+			changes[dst] = 0
+			continue
+		}
+		if !in || before.nextHop != entry.nextHop || before.distance != entry.distance {
+			changes[dst]++
+		}
+	}
+	for dst := range previous {
+		if _, in := current[dst]; !in {
+			changes[dst]++
+		}
+	}
+}
+
+// RouteStability returns the number of times the Node's route to each destination has changed, by next hop, distance,
+// or being lost or regained, since it was first added. A destination with many changes suggests instability in the
+// topology near it, or protocol thrashing. Destinations which were always routed the same way have zero changes.
+func (n *Node) RouteStability() map[NodeID]int {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	stability := make(map[NodeID]int, len(n.routeChanges))
+	for dst, changes := range n.routeChanges {
+		stability[dst] = changes
+	}
+	return stability
+}
+
+// RouteStability returns, for each destination, the total number of times the route to it changed across every
+// node. See Node.RouteStability.
+func (c *Controller) RouteStability() map[NodeID]int {
+	stability := make(map[NodeID]int)
+	for _, n := range c.nodes {
+		for dst, changes := range n.RouteStability() {
+			stability[dst] += changes
+		}
+	}
+	return stability
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_countRouteChanges(t *testing.T) {
+	route := func(dst, nextHop NodeID, distance int) routingEntry {
+		return routingEntry{dst: dst, nextHop: nextHop, nextHops: []NodeID{nextHop}, distance: distance}
+	}
+	tests := []struct {
+		name     string
+		changes  map[NodeID]int
+		previous map[NodeID]routingEntry
+		current  map[NodeID]routingEntry
+		want     map[NodeID]int
+	}{
+		{
+			name:    "first route",
+			changes: map[NodeID]int{},
+			current: map[NodeID]routingEntry{1: route(1, 1, 1)},
+			want:    map[NodeID]int{1: 0},
+		},
+		{
+			name:     "unchanged",
+			changes:  map[NodeID]int{1: 2},
+			previous: map[NodeID]routingEntry{1: route(1, 1, 1)},
+			current:  map[NodeID]routingEntry{1: route(1, 1, 1)},
+			want:     map[NodeID]int{1: 2},
+		},
+		{
+			name:     "next hop changed",
+			changes:  map[NodeID]int{3: 0},
+			previous: map[NodeID]routingEntry{3: route(3, 1, 2)},
+			current:  map[NodeID]routingEntry{3: route(3, 2, 2)},
+			want:     map[NodeID]int{3: 1},
+		},
+		{
+			name:     "distance changed",
+			changes:  map[NodeID]int{3: 0},
+			previous: map[NodeID]routingEntry{3: route(3, 1, 2)},
+			current:  map[NodeID]routingEntry{3: route(3, 1, 3)},
+			want:     map[NodeID]int{3: 1},
+		},
+		{
+			name:     "removed",
+			changes:  map[NodeID]int{3: 0},
+			previous: map[NodeID]routingEntry{3: route(3, 1, 2)},
+			current:  map[NodeID]routingEntry{},
+			want:     map[NodeID]int{3: 1},
+		},
+		{
+			name:    "regained",
+			changes: map[NodeID]int{3: 1},
+			current: map[NodeID]routingEntry{3: route(3, 1, 2)},
+			want:    map[NodeID]int{3: 2},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			countRouteChanges(tt.previous, tt.current, tt.changes)
+			if !reflect.DeepEqual(tt.changes, tt.want) {
+				t.Errorf("countRouteChanges() = %v, want %v", tt.changes, tt.want)
+			}
+		})
+	}
+}
+
+func TestController_RouteStability(t *testing.T) {
+	// The link between 1 and 2 goes down and comes back, so routes to 2 flap while routes to 1 are stable.
+	topology, err := NewNetworkTypology(strings.NewReader(
+		"0 UP 0 1\n0 UP 1 0\n0 UP 1 2\n0 UP 2 1\n30 DOWN 1 2\n30 DOWN 2 1\n60 UP 1 2\n60 UP 2 1\n"))
+	if err != nil {
+		t.Fatalf("NewNetworkTypology() error = %v", err)
+	}
+	c := NewController(*topology, time.Hour)
+	c.SetLogDir(t.TempDir())
+	c.Initialize([]NodeConfig{{ID: 0, Message: NodeMessage{Sent: true}}, {ID: 1, Message: NodeMessage{Sent: true}}, {ID: 2, Message: NodeMessage{Sent: true}}})
+	c.SetSynchronous(true)
+	c.Start(90)
+
+	n, _ := c.node(0)
+	got := n.RouteStability()
+	if got[1] != 0 {
+		t.Errorf("node 0 RouteStability()[1] = %d, want 0", got[1])
+	}
+	// Lost once, then regained.
+	if got[2] < 2 {
+		t.Errorf("node 0 RouteStability()[2] = %d, want at least 2", got[2])
+	}
+	if total := c.RouteStability(); total[2] < got[2] {
+		t.Errorf("Controller RouteStability()[2] = %d, want at least node 0's %d", total[2], got[2])
+	}
+}
@@ -0,0 +1,391 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// MessageCounters counts the messages handled by a Node over the course of a simulation.
+type MessageCounters struct {
+	// HelloSent is the number of HelloMessage(s) sent.
+	HelloSent int
+
+	// TCSent is the number of TCMessage(s) originated.
+	TCSent int
+
+	// TCForwarded is the number of TCMessage(s) forwarded on behalf of other nodes.
+	TCForwarded int
+
+	// TCListingSelf is the number of TCMessage(s) received which listed the receiver as an MPR selector of an
+	// originator which is not its symmetric neighbor, indicating a misconfiguration or a loop.
+	TCListingSelf int
+
+	// MIDSent is the number of MIDMessage(s) originated.
+	MIDSent int
+
+	// MIDForwarded is the number of MIDMessage(s) forwarded on behalf of other nodes.
+	MIDForwarded int
+
+	// DataOriginated is the number of DataMessage(s) originated.
+	DataOriginated int
+
+	// DataForwarded is the number of DataMessage(s) forwarded on behalf of other nodes.
+	DataForwarded int
+
+	// DataDelivered is the number of DataMessage(s) received as the destination.
+	DataDelivered int
+
+	// DataDropped is the number of DataMessage(s) dropped due to having no route.
+	DataDropped int
+
+	// HelloBytes is the size, in bytes of the String() format, of all HelloMessage(s) sent.
+	HelloBytes int
+
+	// TCBytes is the size, in bytes of the String() format, of all TCMessage(s) originated or forwarded.
+	TCBytes int
+
+	// MIDBytes is the size, in bytes of the String() format, of all MIDMessage(s) originated or forwarded.
+	MIDBytes int
+
+	// DataDeliveredBytes is the size, in bytes, of the data of all DataMessage(s) received as the destination.
+	DataDeliveredBytes int
+}
+
+// Counters returns a copy of the Node's message counters.
+func (n *Node) Counters() MessageCounters {
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+
+	return n.counters
+}
+
+// Stats are the message counters of every node in a simulation, summed, along with a description of the run for
+// AppendTo.
+type Stats struct {
+	MessageCounters
+
+	// Scenario names the simulated scenario.
+	Scenario string
+
+	// Seed is the seed the scenario was generated or run with, if any.
+	Seed int64
+
+	// Parameters describes the settings the simulation was run with, such as "tc=10 hello=5".
+	Parameters string
+
+	// ConvergenceTick is the tick from which every node had correct routes until the end of the run, or -1 if the
+	// nodes never converged or convergence was not recorded. See RecordConvergence.
+	ConvergenceTick int
+
+	// NonConvergedTicks is the number of ticks during which some node lacked a correct route, or -1 if convergence
+	// was not recorded. Unlike ConvergenceTick, it reflects how long the routes were wrong across every disruption
+	// during the run, not just the last.
+	NonConvergedTicks int
+
+	// NeverScheduled is the number of configured DataMessage(s) which were never attempted, because the run ended
+	// before their node reached their delay. See Controller.NeverScheduled.
+	NeverScheduled int
+
+	// Collisions is the number of times a node lost the messages it received in a tick to a collision. See
+	// Controller.SetCollisions.
+	Collisions int
+}
+
+// Stats sums the message counters of every node.
+func (c *Controller) Stats() Stats {
+	timeline := c.ConvergenceTimeline()
+	s := Stats{
+		ConvergenceTick:   convergenceTick(timeline),
+		NonConvergedTicks: nonConvergedTicks(timeline),
+		NeverScheduled:    len(c.NeverScheduled()),
+		Collisions:        c.Collisions(),
+	}
+	for _, n := range c.nodes {
+		counters := n.Counters()
+		s.HelloSent += counters.HelloSent
+		s.TCSent += counters.TCSent
+		s.TCForwarded += counters.TCForwarded
+		s.TCListingSelf += counters.TCListingSelf
+		s.MIDSent += counters.MIDSent
+		s.MIDForwarded += counters.MIDForwarded
+		s.DataOriginated += counters.DataOriginated
+		s.DataForwarded += counters.DataForwarded
+		s.DataDelivered += counters.DataDelivered
+		s.DataDropped += counters.DataDropped
+		s.HelloBytes += counters.HelloBytes
+		s.TCBytes += counters.TCBytes
+		s.MIDBytes += counters.MIDBytes
+		s.DataDeliveredBytes += counters.DataDeliveredBytes
+	}
+	return s
+}
+
+// NeverScheduled lists the nodes whose configured DataMessage was never attempted, because the run ended before the
+// node reached the message's delay. A message which was attempted without a route, and rescheduled beyond the end of
+// the run, is not included. Must be called after Start.
+func (c *Controller) NeverScheduled() []NodeID {
+	ids := make([]NodeID, 0)
+	for _, n := range c.nodes {
+		config := c.configs[n.id]
+		n.mu.RLock()
+		pending := !n.nodeMsg.Sent && n.nodeMsg.Delay == config.Message.Delay && n.currentTick <= n.nodeMsg.Delay
+		n.mu.RUnlock()
+		if pending && !config.Message.Sent {
+			ids = append(ids, n.id)
+		}
+	}
+	sortNodeIDs(ids)
+	return ids
+}
+
+// ControlMessages is the number of HelloMessage(s), TCMessage(s), and MIDMessage(s) sent, including forwarded ones.
+func (s Stats) ControlMessages() int {
+	return s.HelloSent + s.TCSent + s.TCForwarded + s.MIDSent + s.MIDForwarded
+}
+
+// ControlBytes is the size, in bytes, of all HelloMessage(s), TCMessage(s), and MIDMessage(s) sent, including
+// forwarded ones.
+func (s Stats) ControlBytes() int {
+	return s.HelloBytes + s.TCBytes + s.MIDBytes
+}
+
+// OverheadRatio is the number of control bytes sent per byte of data delivered. It is +Inf if control bytes were sent
+// but no data was delivered, and zero if neither were.
+func (s Stats) OverheadRatio() float64 {
+	if s.DataDeliveredBytes == 0 {
+		if s.ControlBytes() == 0 {
+			return 0
+		}
+		return math.Inf(1)
+	}
+	return float64(s.ControlBytes()) / float64(s.DataDeliveredBytes)
+}
+
+// DeliveryRatio is the fraction of resolved DataMessage deliveries which were delivered rather than dropped. It is
+// zero if none were resolved.
+func (s Stats) DeliveryRatio() float64 {
+	resolved := s.DataDelivered + s.DataDropped
+	if resolved == 0 {
+		return 0
+	}
+	return float64(s.DataDelivered) / float64(resolved)
+}
+
+// convergenceTick determines the tick from which a convergence timeline stays fully converged, or -1 if it ends
+// unconverged.
+func convergenceTick(timeline []float64) int {
+	tick := -1
+	for i := len(timeline) - 1; i >= 0 && timeline[i] == 1; i-- {
+		tick = i
+	}
+	return tick
+}
+
+// nonConvergedTicks counts the ticks of a convergence timeline which are not fully converged, or -1 if there are no
+// ticks.
+func nonConvergedTicks(timeline []float64) int {
+	if len(timeline) == 0 {
+		return -1
+	}
+	ticks := 0
+	for _, accuracy := range timeline {
+		if accuracy < 1 {
+			ticks++
+		}
+	}
+	return ticks
+}
+
+// resultsHeader names the columns of the records written by AppendTo.
+const resultsHeader = "scenario\tseed\tparameters\tconvergence_tick\toverhead_ratio\tdelivery_ratio\n"
+
+// AppendTo appends a tab-separated record of the run to the results file at the path, creating the file, with a
+// header line, if it does not exist. The file is locked while appending, so that simulations run concurrently, such
+// as in a parameter sweep, can share a results file. Platforms without file locking in the standard library, such as
+// js/wasm, plan9, solaris, and aix, do not lock the file.
+func (s Stats) AppendTo(path string) error {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("append results: %w", err)
+	}
+	defer f.Close()
+
+	if err := lockFile(f); err != nil {
+		return fmt.Errorf("append results: lock %s: %w", path, err)
+	}
+	defer unlockFile(f)
+
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("append results: %w", err)
+	}
+	// Tabs and newlines within the text fields would split the record.
+	field := strings.NewReplacer("\t", " ", "\n", " ", "\r", " ")
+	record := fmt.Sprintf("%s\t%d\t%s\t%d\t%s\t%s\n", field.Replace(s.Scenario), s.Seed, field.Replace(s.Parameters),
+		s.ConvergenceTick, strconv.FormatFloat(s.OverheadRatio(), 'f', -1, 64), strconv.FormatFloat(s.DeliveryRatio(), 'f', -1, 64))
+	if info.Size() == 0 {
+		record = resultsHeader + record
+	}
+	// A single write keeps the record whole.
+	if _, err := f.WriteString(record); err != nil {
+		return fmt.Errorf("append results: %w", err)
+	}
+	return nil
+}
+
+// ReportFormat determines how Stats are written by ReportAs.
+type ReportFormat int
+
+const (
+	// ReportTable writes a human-readable table.
+	ReportTable ReportFormat = iota
+
+	// ReportCSV writes a CSV header line of metric names followed by a line of their values.
+	ReportCSV
+
+	// ReportJSON writes a JSON object mapping metric names to their values.
+	ReportJSON
+)
+
+// ParseReportFormat parses a ReportFormat from its name, either "table", "csv", or "json".
+func ParseReportFormat(s string) (ReportFormat, error) {
+	switch s {
+	case "table":
+		return ReportTable, nil
+	case "csv":
+		return ReportCSV, nil
+	case "json":
+		return ReportJSON, nil
+	default:
+		return ReportTable, fmt.Errorf("unknown report format: '%s'", s)
+	}
+}
+
+// statsMetric is a single named statistic. Every report format is written from the same metrics.
+type statsMetric struct {
+	name  string
+	value float64
+}
+
+// metrics lists the statistics in the order they are reported.
+func (s Stats) metrics() []statsMetric {
+	return []statsMetric{
+		{name: "hello_sent", value: float64(s.HelloSent)},
+		{name: "hello_bytes", value: float64(s.HelloBytes)},
+		{name: "tc_sent", value: float64(s.TCSent)},
+		{name: "tc_forwarded", value: float64(s.TCForwarded)},
+		{name: "tc_bytes", value: float64(s.TCBytes)},
+		{name: "tc_listing_self", value: float64(s.TCListingSelf)},
+		{name: "mid_sent", value: float64(s.MIDSent)},
+		{name: "mid_forwarded", value: float64(s.MIDForwarded)},
+		{name: "mid_bytes", value: float64(s.MIDBytes)},
+		{name: "data_originated", value: float64(s.DataOriginated)},
+		{name: "data_forwarded", value: float64(s.DataForwarded)},
+		{name: "data_delivered", value: float64(s.DataDelivered)},
+		{name: "data_delivered_bytes", value: float64(s.DataDeliveredBytes)},
+		{name: "data_dropped", value: float64(s.DataDropped)},
+		{name: "control_messages", value: float64(s.ControlMessages())},
+		{name: "control_bytes", value: float64(s.ControlBytes())},
+		{name: "overhead_ratio", value: s.OverheadRatio()},
+		{name: "delivery_ratio", value: s.DeliveryRatio()},
+		{name: "convergence_tick", value: float64(s.ConvergenceTick)},
+		{name: "non_converged_ticks", value: float64(s.NonConvergedTicks)},
+		{name: "never_scheduled", value: float64(s.NeverScheduled)},
+		{name: "collisions", value: float64(s.Collisions)},
+	}
+}
+
+// ReportAs writes the statistics in the given format.
+func (s Stats) ReportAs(w io.Writer, format ReportFormat) error {
+	switch format {
+	case ReportCSV:
+		return s.ReportCSV(w)
+	case ReportJSON:
+		return s.ReportJSON(w)
+	default:
+		return s.Report(w)
+	}
+}
+
+// Report writes a human-readable summary of the statistics.
+func (s Stats) Report(w io.Writer) error {
+	m := make(map[string]float64)
+	for _, metric := range s.metrics() {
+		m[metric.name] = metric.value
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	rows := []struct {
+		name  string
+		value string
+	}{
+		{name: "HELLO sent", value: fmt.Sprintf("%.0f (%.0f bytes)", m["hello_sent"], m["hello_bytes"])},
+		{name: "TC sent", value: fmt.Sprintf("%.0f originated, %.0f forwarded (%.0f bytes)", m["tc_sent"], m["tc_forwarded"], m["tc_bytes"])},
+		{name: "TC listing receiver", value: fmt.Sprintf("%.0f from non-neighbors", m["tc_listing_self"])},
+		{name: "MID sent", value: fmt.Sprintf("%.0f originated, %.0f forwarded (%.0f bytes)", m["mid_sent"], m["mid_forwarded"], m["mid_bytes"])},
+		{name: "DATA delivered", value: fmt.Sprintf("%.0f (%.0f bytes)", m["data_delivered"], m["data_delivered_bytes"])},
+		{name: "DATA dropped", value: fmt.Sprintf("%.0f", m["data_dropped"])},
+		{name: "DATA never scheduled", value: fmt.Sprintf("%.0f", m["never_scheduled"])},
+		{name: "Collisions", value: fmt.Sprintf("%.0f", m["collisions"])},
+		{name: "Delivery ratio", value: fmt.Sprintf("%.2f", m["delivery_ratio"])},
+		{name: "Convergence", value: fmt.Sprintf("from tick %.0f, %.0f ticks not converged", m["convergence_tick"], m["non_converged_ticks"])},
+		{name: "Control overhead", value: fmt.Sprintf("%.0f messages, %.0f bytes", m["control_messages"], m["control_bytes"])},
+		{name: "Overhead ratio", value: fmt.Sprintf("%.2f control bytes per delivered data byte", m["overhead_ratio"])},
+	}
+	for _, row := range rows {
+		if _, err := fmt.Fprintf(tw, "%s:\t%s\n", row.name, row.value); err != nil {
+			return err
+		}
+	}
+	return tw.Flush()
+}
+
+// ReportCSV writes the statistics as a CSV header line of metric names followed by a line of their values.
+func (s Stats) ReportCSV(w io.Writer) error {
+	metrics := s.metrics()
+	names := make([]string, 0, len(metrics))
+	values := make([]string, 0, len(metrics))
+	for _, metric := range metrics {
+		names = append(names, metric.name)
+		values = append(values, strconv.FormatFloat(metric.value, 'f', -1, 64))
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.WriteAll([][]string{names, values}); err != nil {
+		return err
+	}
+	return cw.Error()
+}
+
+// ReportJSON writes the statistics as a single-line JSON object mapping metric names to their values, in the same
+// order as the other formats. An infinite overhead ratio, which JSON can not represent, is written as null.
+func (s Stats) ReportJSON(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("{")
+	for i, metric := range s.metrics() {
+		if i > 0 {
+			b.WriteString(",")
+		}
+		name, err := json.Marshal(metric.name)
+		if err != nil {
+			return err
+		}
+		value := []byte("null")
+		if !math.IsInf(metric.value, 0) && !math.IsNaN(metric.value) {
+			if value, err = json.Marshal(metric.value); err != nil {
+				return err
+			}
+		}
+		b.Write(name)
+		b.WriteString(":")
+		b.Write(value)
+	}
+	b.WriteString("}\n")
+	_, err := io.WriteString(w, b.String())
+	return err
+}
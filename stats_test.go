@@ -0,0 +1,271 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestStats_OverheadRatio(t *testing.T) {
+	tests := []struct {
+		name  string
+		stats Stats
+		want  float64
+	}{
+		{name: "empty", stats: Stats{}, want: 0},
+		{
+			name:  "no data delivered",
+			stats: Stats{MessageCounters: MessageCounters{HelloBytes: 10}},
+			want:  math.Inf(1),
+		},
+		{
+			name:  "hello and tc",
+			stats: Stats{MessageCounters: MessageCounters{HelloBytes: 30, TCBytes: 20, DataDeliveredBytes: 25}},
+			want:  2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.stats.OverheadRatio(); got != tt.want {
+				t.Errorf("OverheadRatio() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNode_countersBytes(t *testing.T) {
+	out := &recordingTransmitter{}
+	n := newTestNode(0, out)
+	n.msSet[1] = 1
+	n.tick(nil)
+	n.handleData(&DataMessage{Source: 1, Destination: 0, NextHop: 0, FromNeighbor: 1, Data: "hello"})
+
+	var hello, tc int
+	for _, msg := range out.sent {
+		switch m := msg.(type) {
+		case *HelloMessage:
+			hello += len(m.String())
+		case *TCMessage:
+			tc += len(m.String())
+		}
+	}
+	got := n.Counters()
+	if got.HelloBytes != hello || got.TCBytes != tc || got.DataDeliveredBytes != len("hello") {
+		t.Errorf("Counters() bytes = %d, %d, %d, want %d, %d, %d", got.HelloBytes, got.TCBytes, got.DataDeliveredBytes, hello, tc, len("hello"))
+	}
+}
+
+func TestStats_Report(t *testing.T) {
+	s := Stats{MessageCounters: MessageCounters{HelloSent: 3, HelloBytes: 30, TCSent: 1, TCForwarded: 1, TCBytes: 20, DataDelivered: 1, DataDeliveredBytes: 25}}
+	var buf bytes.Buffer
+	if err := s.Report(&buf); err != nil {
+		t.Fatalf("Report() error = %v", err)
+	}
+	for _, want := range []string{"5 messages, 50 bytes", "2.00 control bytes per delivered data byte"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("Report() = %q, want it to contain %q", buf.String(), want)
+		}
+	}
+}
+
+func TestStats_ReportAs(t *testing.T) {
+	s := Stats{
+		MessageCounters:   MessageCounters{HelloSent: 3, HelloBytes: 30, TCSent: 1, TCForwarded: 1, TCBytes: 20, DataDelivered: 1, DataDropped: 1, DataDeliveredBytes: 25},
+		ConvergenceTick:   12,
+		NonConvergedTicks: 4,
+	}
+	tests := []struct {
+		name   string
+		format ReportFormat
+		want   []string
+	}{
+		{name: "table", format: ReportTable, want: []string{"HELLO sent:", "3 (30 bytes)", "5 messages, 50 bytes", "Delivery ratio:", "0.50", "from tick 12, 4 ticks not converged", "2.00 control bytes"}},
+		{name: "csv", format: ReportCSV, want: []string{"hello_sent,hello_bytes,", ",control_messages,control_bytes,overhead_ratio,delivery_ratio,convergence_tick,non_converged_ticks,never_scheduled,collisions\n", "3,30,", ",5,50,2,0.5,12,4,0,0\n"}},
+		{name: "json", format: ReportJSON, want: []string{`{"hello_sent":3,"hello_bytes":30,`, `"control_messages":5,"control_bytes":50,"overhead_ratio":2,"delivery_ratio":0.5,"convergence_tick":12,"non_converged_ticks":4,"never_scheduled":0,"collisions":0}`}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := s.ReportAs(&buf, tt.format); err != nil {
+				t.Fatalf("ReportAs() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(buf.String(), want) {
+					t.Errorf("ReportAs() = %q, want it to contain %q", buf.String(), want)
+				}
+			}
+		})
+	}
+}
+
+func TestStats_ReportJSON_infiniteOverhead(t *testing.T) {
+	s := Stats{MessageCounters: MessageCounters{HelloSent: 1, HelloBytes: 10}}
+	var buf bytes.Buffer
+	if err := s.ReportJSON(&buf); err != nil {
+		t.Fatalf("ReportJSON() error = %v", err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("ReportJSON() = %q is not valid JSON: %v", buf.String(), err)
+	}
+	if v, in := got["overhead_ratio"]; !in || v != nil {
+		t.Errorf("ReportJSON() overhead_ratio = %v, want null", v)
+	}
+}
+
+func TestParseReportFormat(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    ReportFormat
+		wantErr bool
+	}{
+		{s: "table", want: ReportTable},
+		{s: "csv", want: ReportCSV},
+		{s: "json", want: ReportJSON},
+		{s: "xml", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseReportFormat(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseReportFormat() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseReportFormat() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestController_NeverScheduled(t *testing.T) {
+	topology, err := NewNetworkTypology(strings.NewReader("0 UP 0 1\n0 UP 1 0\n"))
+	if err != nil {
+		t.Fatalf("NewNetworkTypology() error = %v", err)
+	}
+	// The delays are read from a node configuration, as they would be by the command line.
+	configs, err := ReadNodeConfiguration(strings.NewReader(
+		"0 0 \"in time\" 5\n1 0 \"too late\" 150\n2 2 \"late start\" 10 15 0\n"))
+	if err != nil {
+		t.Fatalf("ReadNodeConfiguration() error = %v", err)
+	}
+	if got := configs[1].Message.Delay; got != 150 {
+		t.Fatalf("Delay = %d, want 150", got)
+	}
+	configs = append(configs, NodeConfig{ID: 3, Message: NodeMessage{Sent: true}})
+	c := NewController(*topology, time.Hour)
+	c.SetLogDir(t.TempDir())
+	if err := c.Initialize(configs); err != nil {
+		t.Fatalf("Initialize() error = %v", err)
+	}
+	c.SetSynchronous(true)
+	c.Start(20)
+
+	// Node 2 comes online at tick 15, so it only reaches tick 5 of its own.
+	want := []NodeID{1, 2}
+	if got := c.NeverScheduled(); !reflect.DeepEqual(got, want) {
+		t.Errorf("NeverScheduled() = %v, want %v", got, want)
+	}
+	if got := c.Stats().NeverScheduled; got != len(want) {
+		t.Errorf("Stats().NeverScheduled = %d, want %d", got, len(want))
+	}
+}
+
+func Test_convergenceTick(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeline []float64
+		want     int
+	}{
+		{name: "not recorded", timeline: nil, want: -1},
+		{name: "never converged", timeline: []float64{0, 0.5, 0.5}, want: -1},
+		{name: "converged", timeline: []float64{0, 0.5, 1, 1}, want: 2},
+		{name: "converged again after a break", timeline: []float64{1, 1, 0.5, 1}, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := convergenceTick(tt.timeline); got != tt.want {
+				t.Errorf("convergenceTick() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_nonConvergedTicks(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeline []float64
+		want     int
+	}{
+		{name: "not recorded", timeline: nil, want: -1},
+		{name: "always converged", timeline: []float64{1, 1}, want: 0},
+		{name: "several disruptions", timeline: []float64{0, 0.5, 1, 0.75, 1, 1, 0.5}, want: 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nonConvergedTicks(tt.timeline); got != tt.want {
+				t.Errorf("nonConvergedTicks() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStats_AppendTo(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.tsv")
+	first := Stats{
+		MessageCounters: MessageCounters{HelloBytes: 30, TCBytes: 20, DataDelivered: 3, DataDropped: 1, DataDeliveredBytes: 25},
+		Scenario:        "diamond",
+		Seed:            7,
+		Parameters:      "tc=10\thello=5",
+		ConvergenceTick: 22,
+	}
+	if err := first.AppendTo(path); err != nil {
+		t.Fatalf("AppendTo() error = %v", err)
+	}
+	if err := (Stats{Scenario: "empty", ConvergenceTick: -1}).AppendTo(path); err != nil {
+		t.Fatalf("AppendTo() error = %v", err)
+	}
+	got, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	want := "scenario\tseed\tparameters\tconvergence_tick\toverhead_ratio\tdelivery_ratio\n" +
+		"diamond\t7\ttc=10 hello=5\t22\t2\t0.75\n" +
+		"empty\t0\t\t-1\t0\t0\n"
+	if string(got) != want {
+		t.Errorf("AppendTo() wrote %q, want %q", got, want)
+	}
+
+	// Concurrent appends, as from a sweep, write whole records under a single header.
+	sweep := filepath.Join(t.TempDir(), "sweep.tsv")
+	var wg sync.WaitGroup
+	for seed := int64(0); seed < 20; seed++ {
+		wg.Add(1)
+		go func(seed int64) {
+			defer wg.Done()
+			if err := (Stats{Scenario: "sweep", Seed: seed}).AppendTo(sweep); err != nil {
+				t.Errorf("AppendTo() error = %v", err)
+			}
+		}(seed)
+	}
+	wg.Wait()
+	got, err = os.ReadFile(sweep)
+	if err != nil {
+		t.Fatalf("ReadFile() error = %v", err)
+	}
+	lines := strings.Split(strings.TrimSuffix(string(got), "\n"), "\n")
+	if len(lines) != 21 || !strings.HasPrefix(lines[0], "scenario\t") {
+		t.Fatalf("AppendTo() concurrently wrote %d lines, want a header and 20 records:\n%s", len(lines), got)
+	}
+	for _, line := range lines[1:] {
+		if fields := strings.Split(line, "\t"); len(fields) != 6 || fields[0] != "sweep" {
+			t.Errorf("AppendTo() concurrently wrote malformed record %q", line)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"sort"
+)
+
+// SubnetReachability is the reachability from every node in one subnet to every node in another.
+type SubnetReachability struct {
+	From int
+	To   int
+
+	// Routes is the number of (source, destination) pairs for which the source has a route to the destination.
+	Routes int
+
+	// Pairs is the total number of (source, destination) pairs.
+	Pairs int
+}
+
+// Reachable determines whether every node in the source subnet has a route to every node in the destination subnet.
+func (r SubnetReachability) Reachable() bool {
+	return r.Routes == r.Pairs
+}
+
+// SubnetReport describes the routing within and between the subnets nodes were configured with.
+type SubnetReport struct {
+	// Subnets is sorted in increasing order.
+	Subnets []int
+
+	// Gateways maps each subnet to its sorted gateway nodes.
+	Gateways map[int][]NodeID
+
+	// Internal holds the reachability within each subnet, sorted by subnet.
+	Internal []SubnetReachability
+
+	// Cross holds the reachability between each ordered pair of distinct subnets, sorted by source then destination.
+	Cross []SubnetReachability
+}
+
+// SubnetReport analyses every node's routing table by subnet. Each node's routing table is read under its own lock, so
+// it is safe to call while the simulation is running.
+func (c *Controller) SubnetReport() SubnetReport {
+	members := make(map[int][]NodeID)
+	gateways := make(map[int][]NodeID)
+	routes := make(map[NodeID]map[NodeID]struct{})
+	for _, n := range c.nodes {
+		config := c.configs[n.id]
+		members[config.Subnet] = append(members[config.Subnet], n.id)
+		if config.Gateway {
+			gateways[config.Subnet] = append(gateways[config.Subnet], n.id)
+		}
+
+		routes[n.id] = make(map[NodeID]struct{})
+		for _, entry := range n.Snapshot().RoutingTable {
+			routes[n.id][entry.Destination] = struct{}{}
+		}
+	}
+
+	r := SubnetReport{
+		Subnets:  make([]int, 0, len(members)),
+		Gateways: make(map[int][]NodeID),
+		Internal: make([]SubnetReachability, 0),
+		Cross:    make([]SubnetReachability, 0),
+	}
+	for subnet := range members {
+		r.Subnets = append(r.Subnets, subnet)
+		sortNodeIDs(members[subnet])
+		r.Gateways[subnet] = make([]NodeID, 0)
+	}
+	sort.Ints(r.Subnets)
+	for subnet, ids := range gateways {
+		sortNodeIDs(ids)
+		r.Gateways[subnet] = ids
+	}
+
+	for _, from := range r.Subnets {
+		for _, to := range r.Subnets {
+			reachability := SubnetReachability{From: from, To: to}
+			for _, src := range members[from] {
+				for _, dst := range members[to] {
+					if src == dst {
+						continue
+					}
+					reachability.Pairs++
+					if _, in := routes[src][dst]; in {
+						reachability.Routes++
+					}
+				}
+			}
+			if from == to {
+				r.Internal = append(r.Internal, reachability)
+			} else {
+				r.Cross = append(r.Cross, reachability)
+			}
+		}
+	}
+	return r
+}
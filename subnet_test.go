@@ -0,0 +1,50 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestController_SubnetReport(t *testing.T) {
+	c := NewController(NetworkTypology{}, time.Millisecond)
+	routes := map[NodeID][]NodeID{
+		0: {1, 2},
+		1: {0, 2, 3},
+		2: {1, 3},
+		3: {2},
+	}
+	for _, config := range []NodeConfig{
+		{ID: 0, Subnet: 10},
+		{ID: 1, Subnet: 10, Gateway: true},
+		{ID: 2, Subnet: 20, Gateway: true},
+		{ID: 3, Subnet: 20},
+	} {
+		n := newTestNode(config.ID, &recordingTransmitter{})
+		for _, dst := range routes[config.ID] {
+			n.routingTable[dst] = routingEntry{dst: dst, nextHop: dst, distance: 1}
+		}
+		c.nodes = append(c.nodes, n)
+		c.configs[config.ID] = config
+	}
+
+	want := SubnetReport{
+		Subnets:  []int{10, 20},
+		Gateways: map[int][]NodeID{10: {1}, 20: {2}},
+		Internal: []SubnetReachability{
+			{From: 10, To: 10, Routes: 2, Pairs: 2},
+			{From: 20, To: 20, Routes: 2, Pairs: 2},
+		},
+		Cross: []SubnetReachability{
+			{From: 10, To: 20, Routes: 3, Pairs: 4},
+			{From: 20, To: 10, Routes: 1, Pairs: 4},
+		},
+	}
+	got := c.SubnetReport()
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("SubnetReport() = %+v, want %+v", got, want)
+	}
+	if !got.Internal[0].Reachable() || got.Cross[0].Reachable() {
+		t.Errorf("Reachable() = %v, %v, want true, false", got.Internal[0].Reachable(), got.Cross[0].Reachable())
+	}
+}
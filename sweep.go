@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Scenario is a simulation which can be run repeatedly, such as by Sweep.
+type Scenario struct {
+	// Name identifies the scenario in results.
+	Name string
+
+	// Topology is the network topology, in the format read by NewNetworkTypology.
+	Topology string
+
+	// Configs are the node configurations, in the format read by ReadNodeConfiguration.
+	Configs string
+
+	// Ticks is the number of ticks each run lasts.
+	Ticks int
+
+	// Seed orders the messages each node receives within a tick. See Controller.SetDeliveryOrderSeed.
+	Seed int64
+}
+
+// sweepParameters are the node parameters which can be swept, by name.
+var sweepParameters = map[string]func(n *Node, value int){
+	"hello_interval":     func(n *Node, value int) { n.SetHelloInterval(Ticks(value)) },
+	"tc_interval":        func(n *Node, value int) { n.SetTCInterval(Ticks(value)) },
+	"mid_interval":       func(n *Node, value int) { n.SetMIDInterval(Ticks(value)) },
+	"neighbor_hold_time": func(n *Node, value int) { n.SetNeighborHoldTime(Ticks(value)) },
+	"topology_hold_time": func(n *Node, value int) { n.SetTopologyHoldTime(Ticks(value)) },
+	"ms_hold_time":       func(n *Node, value int) { n.SetMSHoldTime(Ticks(value)) },
+}
+
+// SweepParameters lists the names of the parameters Sweep accepts.
+func SweepParameters() []string {
+	names := make([]string, 0, len(sweepParameters))
+	for name := range sweepParameters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Sweep runs the scenario once for each value of the named parameter, set on every node, and writes a CSV header line
+// followed by a line for each run: the value, the convergence tick (see Stats.ConvergenceTick), the control bytes, and
+// the delivery ratio. Every run is synchronous and seeded by the scenario, so a sweep is reproducible. The nodes' logs
+// are written to a temporary directory, which is removed afterwards.
+func Sweep(w io.Writer, scenario Scenario, parameter string, values []int) error {
+	set, in := sweepParameters[parameter]
+	if !in {
+		return fmt.Errorf("sweep: unknown parameter '%s', want one of: %s", parameter, strings.Join(SweepParameters(), ", "))
+	}
+
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{parameter, "convergence_tick", "control_bytes", "delivery_ratio"}); err != nil {
+		return err
+	}
+	for _, value := range values {
+		stats, err := runSweep(scenario, func(n *Node) { set(n, value) })
+		if err != nil {
+			return fmt.Errorf("sweep: %s=%d: %w", parameter, value, err)
+		}
+		err = cw.Write([]string{
+			strconv.Itoa(value),
+			strconv.Itoa(stats.ConvergenceTick),
+			strconv.Itoa(stats.ControlBytes()),
+			strconv.FormatFloat(stats.DeliveryRatio(), 'f', -1, 64),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// runSweep runs the scenario once, synchronously, with every node changed before it starts.
+func runSweep(scenario Scenario, change func(n *Node)) (Stats, error) {
+	topology, err := NewNetworkTypology(strings.NewReader(scenario.Topology))
+	if err != nil {
+		return Stats{}, err
+	}
+	configs, err := ReadNodeConfiguration(strings.NewReader(scenario.Configs))
+	if err != nil {
+		return Stats{}, err
+	}
+	logDir, err := os.MkdirTemp("", "olsrsim-sweep")
+	if err != nil {
+		return Stats{}, err
+	}
+	defer os.RemoveAll(logDir)
+
+	// The tick duration does not matter, as synchronous ticks are not paced.
+	c := NewController(*topology, time.Millisecond)
+	c.SetLogDir(logDir)
+	c.SetDeliveryOrderSeed(scenario.Seed)
+	if err := c.Initialize(configs); err != nil {
+		return Stats{}, err
+	}
+	for _, n := range c.nodes {
+		change(n)
+	}
+	c.SetSynchronous(true)
+	c.RecordConvergence(true)
+	c.Start(scenario.Ticks)
+
+	stats := c.Stats()
+	stats.Scenario = scenario.Name
+	stats.Seed = scenario.Seed
+	return stats, nil
+}
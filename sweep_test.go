@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestSweep(t *testing.T) {
+	topology, configs := GenerateScenario(7, 6, 80)
+	scenario := Scenario{Name: "generated", Topology: topology, Configs: configs, Ticks: 80, Seed: 7}
+
+	tests := []struct {
+		name      string
+		parameter string
+		values    []int
+		wantErr   bool
+	}{
+		{name: "hello interval", parameter: "hello_interval", values: []int{2, 5, 8}},
+		{name: "tc interval", parameter: "tc_interval", values: []int{5, 20}},
+		{name: "no values", parameter: "tc_interval", values: nil},
+		{name: "unknown parameter", parameter: "tc_redundancy", values: []int{1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			err := Sweep(&buf, scenario, tt.parameter, tt.values)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Sweep() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			var again bytes.Buffer
+			if err := Sweep(&again, scenario, tt.parameter, tt.values); err != nil || again.String() != buf.String() {
+				t.Errorf("Sweep() is not reproducible:\n%s\n%s", buf.String(), again.String())
+			}
+
+			records, err := csv.NewReader(strings.NewReader(buf.String())).ReadAll()
+			if err != nil {
+				t.Fatalf("Sweep() wrote invalid CSV: %v", err)
+			}
+			if want := []string{tt.parameter, "convergence_tick", "control_bytes", "delivery_ratio"}; strings.Join(records[0], ",") != strings.Join(want, ",") {
+				t.Errorf("header = %v, want %v", records[0], want)
+			}
+			if len(records)-1 != len(tt.values) {
+				t.Fatalf("Sweep() wrote %d rows, want %d", len(records)-1, len(tt.values))
+			}
+			for i, record := range records[1:] {
+				if record[0] != strconv.Itoa(tt.values[i]) {
+					t.Errorf("row %d value = %s, want %d", i, record[0], tt.values[i])
+				}
+				if n, err := strconv.Atoi(record[2]); err != nil || n <= 0 {
+					t.Errorf("row %d control bytes = %s, want a positive count", i, record[2])
+				}
+			}
+		})
+	}
+}
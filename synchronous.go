@@ -0,0 +1,209 @@
+package main
+
+import (
+	"log"
+	"sync/atomic"
+)
+
+// queueTransmitter holds the messages sent by a Node during a tick until the Controller routes them.
+type queueTransmitter struct {
+	sent []interface{}
+}
+
+func (t *queueTransmitter) Send(msg interface{}) {
+	t.sent = append(t.sent, msg)
+}
+
+// SetSynchronous makes Start run the simulation in a single goroutine rather than one goroutine per node. Each tick,
+// the Controller runs every online node's tick in NodeID order, then routes the messages they sent, in the same
+// order, for delivery in a later tick. Ticks are not paced by the tick duration, and Pause has no effect, so a run is
+// fully reproducible. Must be called after Initialize and before Start.
+func (c *Controller) SetSynchronous(enabled bool) {
+	c.synchronous = enabled
+}
+
+// online determines whether the node is within its configured active window at the tick.
+func (c *Controller) online(id NodeID, tick int) bool {
+	config := c.configs[id]
+	return tick >= config.StartTick && (config.StopTick == 0 || tick < config.StopTick)
+}
+
+// isOnline determines whether the node is online at the tick, whether or not the simulation is synchronous.
+func (c *Controller) isOnline(id NodeID, tick int) bool {
+	if c.synchronous {
+		return c.online(id, tick)
+	}
+	return c.running(id)
+}
+
+// startSynchronous runs the simulation in lock-step ticks within the calling goroutine, returning the tick at which
+// the simulation ended.
+func (c *Controller) startSynchronous(ticks int) int {
+	nodes := make(map[NodeID]*Node, len(c.nodes))
+	outputs := make(map[NodeID]*queueTransmitter, len(c.nodes))
+	for _, n := range c.nodes {
+		nodes[n.id] = n
+		outputs[n.id] = &queueTransmitter{}
+		n.output = outputs[n.id]
+	}
+	ids := sortedNodeIDs(nodes)
+
+	// inboxes holds the messages to be processed by each node, by tick.
+	inboxes := make(map[int]map[NodeID][]inboxMessage)
+	enqueue := func(from, to NodeID, msg interface{}, tick int) bool {
+		if !c.online(to, tick) {
+			return false
+		}
+		if inboxes[tick] == nil {
+			inboxes[tick] = make(map[NodeID][]inboxMessage)
+		}
+		inboxes[tick][to] = append(inboxes[tick][to], inboxMessage{from: from, msg: msg})
+		return true
+	}
+	// broadcast sends a control message to every other node with a link from the sender.
+	broadcast := func(msg interface{}, from NodeID, tick int) {
+		for _, to := range ids {
+			if to == from {
+				continue
+			}
+			q := QueryMsg{FromNode: from, ToNode: to, AtTime: tick}
+			if !c.linkUp(q) {
+				continue
+			}
+			if out, ok := c.intercept(from, to, msg, tick); ok && enqueue(from, to, out, tick+1+c.linkDelay(q)) {
+				c.countDelivery(from, to)
+			}
+		}
+	}
+	// sendData sends a DataMessage across the link to its next hop.
+	sendData := func(m *DataMessage, tick int) {
+		q := QueryMsg{FromNode: m.FromNeighbor, ToNode: m.NextHop, AtTime: tick}
+		if !c.linkUp(q) {
+			log.Printf("controller: link %s -> %s unavailable, dropped:\t%s", m.FromNeighbor, m.NextHop, m)
+			c.dataResolved(m, dataDropped)
+			return
+		}
+		out, ok := c.intercept(q.FromNode, q.ToNode, m, tick)
+		if !ok {
+			c.dataResolved(m, dataDropped)
+			return
+		}
+		if !enqueue(m.FromNeighbor, m.NextHop, out, tick+1+c.linkDelay(q)) {
+			log.Printf("controller: link %s -> %s unavailable, dropped:\t%s", m.FromNeighbor, m.NextHop, m)
+			c.dataResolved(m, dataDropped)
+			return
+		}
+		c.countDelivery(q.FromNode, q.ToNode)
+	}
+	// linkData holds the DataMessage(s) sent across each directed link during a tick when links have a capacity, and
+	// links the links in the order of their first message.
+	linkData := make(map[[2]NodeID][]*DataMessage)
+	links := make([][2]NodeID, 0)
+	// A message sent during a tick is processed during the next tick, after any link delay.
+	route := func(msg interface{}, tick int) {
+		switch m := msg.(type) {
+		case *HelloMessage:
+			broadcast(m, m.Source, tick)
+		case *TCMessage:
+			broadcast(m, m.FromNeighbor, tick)
+		case *MIDMessage:
+			broadcast(m, m.FromNeighbor, tick)
+		case *DataMessage:
+			if c.linkCapacity > 0 {
+				link := [2]NodeID{m.FromNeighbor, m.NextHop}
+				if _, in := linkData[link]; !in {
+					links = append(links, link)
+				}
+				linkData[link] = append(linkData[link], m)
+				return
+			}
+			sendData(m, tick)
+		default:
+			log.Panicf("controller: invalid message type: %T\n", m)
+		}
+	}
+
+	resolvedAt := -1
+	tick := 0
+	for ; tick < ticks; tick++ {
+		c.epochMu.Lock()
+		c.syncTick = tick
+		c.epochMu.Unlock()
+
+		if c.stopWhenResolved && atomic.LoadInt64(&c.outstandingData) == 0 {
+			if resolvedAt < 0 {
+				log.Printf("controller: all data messages resolved, stopping in %d ticks", c.resolvedGrace)
+				resolvedAt = tick
+			}
+			if tick >= resolvedAt+c.resolvedGrace {
+				break
+			}
+		}
+		for _, sd := range c.scheduledData {
+			if sd.atTick == tick {
+				nodes[sd.src].OriginatePriority(sd.dst, sd.data, sd.priority)
+			}
+		}
+		for _, sc := range c.scheduledChanges {
+			if sc.atTick == tick {
+				sc.change(nodes[sc.id])
+			}
+		}
+
+		for _, id := range ids {
+			if config := c.configs[id]; config.StopTick > 0 && tick == config.StopTick && config.StopTick > config.StartTick {
+				nodes[id].stopSynchronous()
+			}
+			if !c.online(id, tick) {
+				continue
+			}
+			msgs := c.collide(id, tick, inboxes[tick][id])
+			n := nodes[id]
+			n.mu.Lock()
+			n.tick(msgs)
+			n.mu.Unlock()
+		}
+		delete(inboxes, tick)
+
+		for _, id := range ids {
+			out := outputs[id]
+			for _, msg := range out.sent {
+				route(msg, tick)
+			}
+			out.sent = nil
+		}
+		for _, link := range links {
+			for _, m := range c.congest(link[0], link[1], tick, linkData[link]) {
+				sendData(m, tick)
+			}
+			delete(linkData, link)
+		}
+		links = links[:0]
+		if c.recordConvergence {
+			c.sampleConvergence(tick, func(id NodeID) bool {
+				return c.online(id, tick)
+			})
+		}
+	}
+
+	for _, id := range ids {
+		if c.online(id, tick-1) {
+			nodes[id].stopSynchronous()
+		}
+	}
+	log.Printf("done at tick %d.", tick)
+	return tick
+}
+
+// stopSynchronous closes the Node's logs once it goes offline in a synchronous simulation, as Run does when it
+// returns.
+func (n *Node) stopSynchronous() {
+	_ = n.inputLog.Close()
+	_ = n.outputLog.Close()
+
+	n.mu.RLock()
+	defer n.mu.RUnlock()
+	if n.recorder != nil {
+		n.recorder.printf("stop %d %d", n.id, n.currentTick)
+	}
+}
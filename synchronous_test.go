@@ -0,0 +1,65 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestController_SetSynchronous(t *testing.T) {
+	run := func() (*Controller, string, int) {
+		topology, err := NewNetworkTypology(strings.NewReader(
+			"0 UP 0 1\n0 UP 1 0\n" +
+				"0 UP 0 2\n0 UP 2 0\n" +
+				"0 UP 1 3\n0 UP 3 1\n" +
+				"0 UP 2 3 2\n0 UP 3 2 2\n" +
+				"20 DOWN 1 3\n20 DOWN 3 1\n"))
+		if err != nil {
+			t.Fatalf("NewNetworkTypology() error = %v", err)
+		}
+		logDir, err := os.MkdirTemp("", "olsrsim")
+		if err != nil {
+			t.Fatalf("MkdirTemp() error = %v", err)
+		}
+		defer os.RemoveAll(logDir)
+
+		// A tick duration this long would make the goroutine mode take minutes.
+		c := NewController(*topology, time.Hour)
+		c.SetLogDir(logDir)
+		c.Initialize([]NodeConfig{
+			{ID: 0, Message: NodeMessage{Message: "hello 3", Delay: 15, Destination: 3}},
+			{ID: 1, Message: NodeMessage{Sent: true}},
+			{ID: 2, Message: NodeMessage{Sent: true}, StartTick: 3},
+			{ID: 3, Message: NodeMessage{Sent: true}, StopTick: 50},
+		})
+		if err := c.ScheduleData(3, 0, "hello 0, from 3", 40); err != nil {
+			t.Fatalf("ScheduleData() error = %v", err)
+		}
+		c.SetSynchronous(true)
+		var recording bytes.Buffer
+		c.RecordTo(&recording)
+		tick := c.Start(60)
+		return c, recording.String(), tick
+	}
+
+	c, want, tick := run()
+	if tick != 60 {
+		t.Errorf("Start() = %d, want 60", tick)
+	}
+	for _, id := range []NodeID{0, 3} {
+		n, _ := c.node(id)
+		if got := n.Counters().DataDelivered; got != 1 {
+			t.Errorf("node %d DataDelivered = %d, want 1", id, got)
+		}
+	}
+	if got := c.Snapshot().Tick; got != 59 {
+		t.Errorf("Snapshot().Tick = %d, want 59", got)
+	}
+	for i := 0; i < 3; i++ {
+		if _, got, _ := run(); got != want {
+			t.Fatalf("run %d recorded:\n%s\nwant:\n%s", i, got, want)
+		}
+	}
+}
@@ -0,0 +1,158 @@
+package main
+
+import (
+	"log"
+	"sort"
+)
+
+// TableCaps are soft limits on the size of a Node's neighbor and topology tables, guarding against a flood of
+// messages from many distinct sources. A zero limit represents no limit.
+type TableCaps struct {
+	// OneHopNeighbors is the maximum number of one-hop neighbors.
+	OneHopNeighbors int
+
+	// TwoHopNeighbors is the maximum number of two-hop neighbor entries, counted across all one-hop neighbors.
+	TwoHopNeighbors int
+
+	// TopologyEntries is the maximum number of topology table entries, counted across all originators.
+	TopologyEntries int
+
+	// EvictOldest makes room for new entries by evicting the entries which would expire soonest, rather than refusing
+	// the new entries.
+	EvictOldest bool
+}
+
+// SetTableCaps sets the limits on the size of the Node's neighbor and topology tables. Tables which already exceed a
+// limit are only trimmed as new entries arrive.
+func (n *Node) SetTableCaps(caps TableCaps) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.tableCaps = caps
+}
+
+// admitOneHopNeighbor determines whether a HelloMessage from the source may be processed without exceeding the
+// one-hop neighbor cap, evicting the neighbor which would expire soonest if enabled.
+func (n *Node) admitOneHopNeighbor(source NodeID) bool {
+	max := n.tableCaps.OneHopNeighbors
+	if _, in := n.oneHopNeighbors[source]; in || max == 0 || len(n.oneHopNeighbors) < max {
+		return true
+	}
+	if !n.tableCaps.EvictOldest {
+		log.Printf("node %s: WARNING: one-hop neighbor table is full (%d entries), refused neighbor %s", n.id, max, source)
+		return false
+	}
+	for len(n.oneHopNeighbors) >= max {
+		oldest := NodeID(0)
+		found := false
+		for _, id := range sortedNodeIDs(n.oneHopNeighbors) {
+			if !found || n.oneHopNeighbors[id].holdUntil < n.oneHopNeighbors[oldest].holdUntil {
+				oldest, found = id, true
+			}
+		}
+		delete(n.oneHopNeighbors, oldest)
+		delete(n.twoHopNeighbors, oldest)
+		delete(n.mprs, oldest)
+		log.Printf("node %s: WARNING: one-hop neighbor table is full (%d entries), evicted neighbor %s", n.id, max, oldest)
+	}
+	return true
+}
+
+// tableEntryRef locates an entry within a two-level table, such as the two-hop neighbor or topology table.
+type tableEntryRef struct {
+	outer, inner NodeID
+	holdUntil    int
+}
+
+// tableKeys returns the set of keys of the map.
+func tableKeys[V any](m map[NodeID]V) map[NodeID]bool {
+	keys := make(map[NodeID]bool, len(m))
+	for id := range m {
+		keys[id] = true
+	}
+	return keys
+}
+
+// capTable removes entries from a two-level table until it holds at most max entries, after the entries of the source
+// have just been updated. The source's entries which are not in before are removed, highest ID first, unless
+// evictOldest is set, in which case the entries of other sources which would expire soonest are removed first.
+// Returns the number of entries removed.
+func capTable[V any](table map[NodeID]map[NodeID]V, max int, source NodeID, before map[NodeID]bool, evictOldest bool, holdUntil func(outer NodeID, v V) int) int {
+	excess := -max
+	for _, entries := range table {
+		excess += len(entries)
+	}
+	if max == 0 || excess <= 0 {
+		return 0
+	}
+
+	victims := make([]tableEntryRef, 0)
+	if evictOldest {
+		for outer, entries := range table {
+			if outer == source {
+				continue
+			}
+			for inner, v := range entries {
+				victims = append(victims, tableEntryRef{outer: outer, inner: inner, holdUntil: holdUntil(outer, v)})
+			}
+		}
+		sort.Slice(victims, func(i, j int) bool {
+			a, b := victims[i], victims[j]
+			if a.holdUntil != b.holdUntil {
+				return a.holdUntil < b.holdUntil
+			}
+			if a.outer != b.outer {
+				return a.outer < b.outer
+			}
+			return a.inner < b.inner
+		})
+	}
+	added := make([]NodeID, 0)
+	for inner := range table[source] {
+		if !before[inner] {
+			added = append(added, inner)
+		}
+	}
+	sort.Slice(added, func(i, j int) bool {
+		return added[i] > added[j]
+	})
+	for _, inner := range added {
+		victims = append(victims, tableEntryRef{outer: source, inner: inner})
+	}
+
+	removed := 0
+	for _, v := range victims {
+		if removed == excess {
+			break
+		}
+		delete(table[v.outer], v.inner)
+		if len(table[v.outer]) == 0 && v.outer != source {
+			delete(table, v.outer)
+		}
+		removed++
+	}
+	return removed
+}
+
+// capTwoHopNeighbors enforces the two-hop neighbor cap after the two-hop neighbors of the source have been updated.
+// Two-hop entries are held for as long as the one-hop neighbor advertising them.
+func (n *Node) capTwoHopNeighbors(source NodeID, before map[NodeID]bool) {
+	max := n.tableCaps.TwoHopNeighbors
+	removed := capTable(n.twoHopNeighbors, max, source, before, n.tableCaps.EvictOldest, func(outer NodeID, _ NodeID) int {
+		return n.oneHopNeighbors[outer].holdUntil
+	})
+	if removed > 0 {
+		log.Printf("node %s: WARNING: two-hop neighbor table is full (%d entries), dropped %d entries", n.id, max, removed)
+	}
+}
+
+// capTopologyTable enforces the topology table cap after the entries of the originator have been updated.
+func (n *Node) capTopologyTable(originator NodeID, before map[NodeID]bool) {
+	max := n.tableCaps.TopologyEntries
+	removed := capTable(n.topologyTable, max, originator, before, n.tableCaps.EvictOldest, func(_ NodeID, entry topologyEntry) int {
+		return entry.holdUntil
+	})
+	if removed > 0 {
+		log.Printf("node %s: WARNING: topology table is full (%d entries), dropped %d entries", n.id, max, removed)
+	}
+}
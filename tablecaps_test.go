@@ -0,0 +1,71 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNode_tableCaps(t *testing.T) {
+	tests := []struct {
+		name         string
+		caps         TableCaps
+		wantOneHops  []NodeID
+		wantTwoHops  []NodeID
+		wantTopology []NodeID
+	}{
+		{
+			name:         "unlimited",
+			caps:         TableCaps{},
+			wantOneHops:  []NodeID{1, 2, 3, 4, 5, 6, 7, 8},
+			wantTwoHops:  []NodeID{101, 102, 103, 104, 105, 106, 107, 108},
+			wantTopology: []NodeID{201, 202, 203, 204, 205, 206, 207, 208},
+		},
+		{
+			name:         "refuse",
+			caps:         TableCaps{OneHopNeighbors: 5, TwoHopNeighbors: 3, TopologyEntries: 4},
+			wantOneHops:  []NodeID{1, 2, 3, 4, 5},
+			wantTwoHops:  []NodeID{101, 102, 103},
+			wantTopology: []NodeID{201, 202, 203, 204},
+		},
+		{
+			name:         "evict oldest",
+			caps:         TableCaps{OneHopNeighbors: 5, TwoHopNeighbors: 3, TopologyEntries: 4, EvictOldest: true},
+			wantOneHops:  []NodeID{4, 5, 6, 7, 8},
+			wantTwoHops:  []NodeID{106, 107, 108},
+			wantTopology: []NodeID{205, 206, 207, 208},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := newTestNode(0, &recordingTransmitter{})
+			n.SetTableCaps(tt.caps)
+
+			// Flood the node with a HELLO and a TC from each source in turn, so later sources are held for longer.
+			for src := NodeID(1); src <= 8; src++ {
+				n.currentTick = int(src)
+				n.handleHello(&HelloMessage{Source: src, Bidirectional: []NodeID{100 + src}})
+				n.handleTC(&TCMessage{Source: src, FromNeighbor: src, MultipointRelaySet: []NodeID{200 + src}})
+			}
+
+			if got := sortedNodeIDs(n.oneHopNeighbors); !reflect.DeepEqual(got, tt.wantOneHops) {
+				t.Errorf("one-hop neighbors = %v, want %v", got, tt.wantOneHops)
+			}
+			twoHops := make([]NodeID, 0)
+			for _, entries := range n.twoHopNeighbors {
+				twoHops = append(twoHops, sortedNodeIDs(entries)...)
+			}
+			sortNodeIDs(twoHops)
+			if !reflect.DeepEqual(twoHops, tt.wantTwoHops) {
+				t.Errorf("two-hop neighbors = %v, want %v", twoHops, tt.wantTwoHops)
+			}
+			topology := make([]NodeID, 0)
+			for _, entries := range n.topologyTable {
+				topology = append(topology, sortedNodeIDs(entries)...)
+			}
+			sortNodeIDs(topology)
+			if !reflect.DeepEqual(topology, tt.wantTopology) {
+				t.Errorf("topology destinations = %v, want %v", topology, tt.wantTopology)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"time"
+)
+
+// Ticks is a number of simulation ticks. All timing within a Node is measured in ticks; Ticks distinguishes tick
+// counts from durations and other integers at API boundaries.
+type Ticks int
+
+// DurationToTicks converts a duration to Ticks given the duration of a tick, rounding up so that a hold time is never
+// shortened.
+func DurationToTicks(d, tickDuration time.Duration) Ticks {
+	if d <= 0 {
+		return 0
+	}
+	return Ticks((d + tickDuration - 1) / tickDuration)
+}
+
+// Duration converts Ticks to a duration given the duration of a tick.
+func (t Ticks) Duration(tickDuration time.Duration) time.Duration {
+	return time.Duration(t) * tickDuration
+}
+
+// SetNeighborHoldTime sets how long neighbor table entries are held until they are expelled.
+func (n *Node) SetNeighborHoldTime(t Ticks) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.neighborHoldTime = int(t)
+}
+
+// SetNeighborHoldDuration sets how long neighbor table entries are held until they are expelled, converted to ticks
+// using the Node's tick duration.
+func (n *Node) SetNeighborHoldDuration(d time.Duration) {
+	n.SetNeighborHoldTime(DurationToTicks(d, n.tickDuration))
+}
+
+// SetMSHoldTime sets how long MPR selector (msSet) entries are held until they are expelled, unless refreshed by a
+// HelloMessage which still selects the Node.
+func (n *Node) SetMSHoldTime(t Ticks) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.msHoldTime = int(t)
+}
+
+// SetTopologyHoldTime sets how long topology table entries are held until they are expelled.
+func (n *Node) SetTopologyHoldTime(t Ticks) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.topologyHoldTime = int(t)
+}
+
+// SetTopologyHoldDuration sets how long topology table entries are held until they are expelled, converted to ticks
+// using the Node's tick duration.
+func (n *Node) SetTopologyHoldDuration(d time.Duration) {
+	n.SetTopologyHoldTime(DurationToTicks(d, n.tickDuration))
+}
+
+// SetTCInterval sets the base interval between TCMessage(s), which is scaled by the Node's willingness. It may be
+// changed mid-run, taking effect from the next tick; see SetHelloInterval.
+func (n *Node) SetTCInterval(t Ticks) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	old := n.willingness.scaleTCInterval(n.tcInterval)
+	n.tcInterval = int(t)
+	n.tcPhase = rephase(n.tcPhase, old, n.willingness.scaleTCInterval(n.tcInterval), n.currentTick)
+}
+
+// SetHelloInterval sets the interval between HelloMessage(s), of at least one tick. It may be changed mid-run, taking
+// effect from the next tick: a HelloMessage already due by then is still sent, no later than the new interval after
+// the previous one, and the following ones are spaced by the new interval.
+func (n *Node) SetHelloInterval(t Ticks) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	interval := int(t)
+	if interval < 1 {
+		interval = 1
+	}
+	n.helloPhase = rephase(n.helloPhase, n.helloInterval, interval, n.currentTick)
+	n.helloInterval = interval
+}
+
+// SetMIDInterval sets the interval between MIDMessage(s), of at least one tick. It may be changed mid-run, taking
+// effect from the next tick; see SetHelloInterval.
+func (n *Node) SetMIDInterval(t Ticks) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	interval := int(t)
+	if interval < 1 {
+		interval = 1
+	}
+	n.midPhase = rephase(n.midPhase, n.midInterval, interval, n.currentTick)
+	n.midInterval = interval
+}
+
+// emissionDue determines whether a periodic emission, sent at the phase tick and every interval after it, is due
+// during the current tick.
+func (n *Node) emissionDue(phase, interval int) bool {
+	return n.currentTick >= phase && (n.currentTick-phase)%interval == 0
+}
+
+// rephase determines the phase of a periodic emission whose interval changes before the tick. The next emission is
+// sent when it was due under the old interval, or the new interval after the previous emission if that is sooner,
+// but never before the tick.
+func rephase(phase, oldInterval, newInterval, tick int) int {
+	if oldInterval == newInterval {
+		return phase
+	}
+	next := phase
+	if tick > phase {
+		next = tick
+		if r := (tick - phase) % oldInterval; r != 0 {
+			next += oldInterval - r
+		}
+	}
+	sooner := next - oldInterval + newInterval
+	if sooner < tick {
+		sooner = tick
+	}
+	if sooner < next {
+		return sooner
+	}
+	return next
+}
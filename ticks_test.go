@@ -0,0 +1,115 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDurationToTicks(t *testing.T) {
+	tests := []struct {
+		name string
+		d    time.Duration
+		want Ticks
+	}{
+		{name: "zero", d: 0, want: 0},
+		{name: "negative", d: -time.Second, want: 0},
+		{name: "exact", d: 3 * time.Second, want: 3},
+		{name: "rounds up", d: 3*time.Second + time.Millisecond, want: 4},
+		{name: "less than a tick", d: time.Millisecond, want: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DurationToTicks(tt.d, time.Second); got != tt.want {
+				t.Errorf("DurationToTicks() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+	if got, want := Ticks(15).Duration(100*time.Millisecond), 1500*time.Millisecond; got != want {
+		t.Errorf("Duration() = %s, want %s", got, want)
+	}
+}
+
+func TestNode_SetHoldTimes(t *testing.T) {
+	n := newNode(make(chan interface{}), &recordingTransmitter{}, 0, NodeMessage{Sent: true}, 100*time.Millisecond, &bufferCloser{}, &bufferCloser{}, &bufferCloser{})
+
+	n.SetNeighborHoldDuration(2 * time.Second)
+	n.SetTopologyHoldDuration(5 * time.Second)
+	if n.neighborHoldTime != 20 || n.topologyHoldTime != 50 {
+		t.Errorf("hold times = %d, %d, want 20, 50", n.neighborHoldTime, n.topologyHoldTime)
+	}
+
+	n.SetNeighborHoldTime(7)
+	n.SetTopologyHoldTime(9)
+	if n.neighborHoldTime != 7 || n.topologyHoldTime != 9 {
+		t.Errorf("hold times = %d, %d, want 7, 9", n.neighborHoldTime, n.topologyHoldTime)
+	}
+}
+
+func Test_rephase(t *testing.T) {
+	tests := []struct {
+		name                         string
+		phase, oldInterval, interval int
+		tick                         int
+		want                         int
+	}{
+		{name: "unchanged", phase: 0, oldInterval: 5, interval: 5, tick: 17, want: 0},
+		{name: "due this tick", phase: 0, oldInterval: 5, interval: 8, tick: 20, want: 20},
+		{name: "longer", phase: 0, oldInterval: 5, interval: 8, tick: 17, want: 20},
+		{name: "shorter", phase: 0, oldInterval: 5, interval: 3, tick: 17, want: 18},
+		{name: "shorter, already overdue", phase: 0, oldInterval: 10, interval: 2, tick: 17, want: 17},
+		{name: "before the phase", phase: 20, oldInterval: 10, interval: 4, tick: 12, want: 14},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rephase(tt.phase, tt.oldInterval, tt.interval, tt.tick); got != tt.want {
+				t.Errorf("rephase() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestController_ScheduleNodeChange(t *testing.T) {
+	tests := []struct {
+		name     string
+		atTick   int
+		interval Ticks
+		want     []int
+	}{
+		{name: "longer at tick 20", atTick: 20, interval: 8, want: []int{0, 5, 10, 15, 20, 28, 36}},
+		{name: "longer at tick 17", atTick: 17, interval: 8, want: []int{0, 5, 10, 15, 20, 28, 36}},
+		{name: "shorter at tick 17", atTick: 17, interval: 3, want: []int{0, 5, 10, 15, 18, 21, 24, 27, 30, 33, 36, 39}},
+		{name: "zero", atTick: 37, interval: 0, want: []int{0, 5, 10, 15, 20, 25, 30, 35, 37, 38, 39}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			topology, err := NewNetworkTypology(strings.NewReader("0 UP 0 1\n0 UP 1 0\n"))
+			if err != nil {
+				t.Fatalf("NewNetworkTypology() error = %v", err)
+			}
+			c := NewController(*topology, time.Hour)
+			c.SetLogDir(t.TempDir())
+			c.Initialize([]NodeConfig{{ID: 0, Message: NodeMessage{Sent: true}}, {ID: 1, Message: NodeMessage{Sent: true}}})
+			c.SetSynchronous(true)
+			if err := c.ScheduleNodeChange(0, tt.atTick, func(n *Node) { n.SetHelloInterval(tt.interval) }); err != nil {
+				t.Fatalf("ScheduleNodeChange() error = %v", err)
+			}
+			if err := c.ScheduleNodeChange(7, tt.atTick, func(n *Node) {}); err == nil {
+				t.Errorf("ScheduleNodeChange() for an unknown node error = nil, want an error")
+			}
+			got := make([]int, 0)
+			c.AddInterceptor(func(from, _ NodeID, msg interface{}, tick int) (interface{}, bool) {
+				if _, ok := msg.(*HelloMessage); ok && from == 0 {
+					got = append(got, tick)
+				}
+				return msg, true
+			})
+			c.Start(40)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("HELLOs sent by node 0 at ticks %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,7 +1,6 @@
 package main
 
 import (
-	"bufio"
 	"errors"
 	"fmt"
 	"io"
@@ -36,10 +35,12 @@ func (e ErrParseLinkState) Error() string {
 }
 
 func NewNetworkTypology(in io.Reader) (*NetworkTypology, error) {
-	n := &NetworkTypology{}
-	n.links = make(map[NodeID]map[NodeID]Link)
+	n := NewEmptyTopology()
 
-	r := bufio.NewReader(in)
+	r, err := newInputReader(in)
+	if err != nil {
+		return nil, err
+	}
 	currTime := 0
 	for {
 		line, err := r.ReadString('\n')
@@ -61,31 +62,65 @@ func NewNetworkTypology(in io.Reader) (*NetworkTypology, error) {
 		}
 		currTime = ls.time
 
-		// Add the new LinkState to the applicable link. If there is not a link, create one.
-		dsts, in := n.links[ls.fromNode]
-		if !in {
-			link := Link{fromNode: ls.fromNode, toNode: ls.toNode}
-			link.states = append(link.states, *ls)
+		n.addLinkState(*ls)
+	}
 
-			srcMap := make(map[NodeID]Link)
-			srcMap[ls.toNode] = link
-			n.links[ls.fromNode] = srcMap
-			continue
-		}
-		dst, in := dsts[ls.toNode]
-		if !in {
-			link := Link{fromNode: ls.fromNode, toNode: ls.toNode}
-			link.states = append(link.states, *ls)
+	return n, nil
+}
 
-			dsts[ls.toNode] = link
-			continue
-		}
+// NewEmptyTopology creates a NetworkTypology without any links, to be populated with SetLinkState or AddLink.
+func NewEmptyTopology() *NetworkTypology {
+	n := &NetworkTypology{}
+	n.links = make(map[NodeID]map[NodeID]Link)
+	return n
+}
+
+// SetLinkState makes the directed link from one node to another up or down from the given moment in time onwards.
+// States must be set in increasing order of time for each link.
+func (n *NetworkTypology) SetLinkState(from, to NodeID, time int, up bool) error {
+	if time < 0 {
+		return fmt.Errorf("set link state: time must not be negative: %d", time)
+	}
+	if states := n.links[from][to].states; len(states) > 0 && time < states[len(states)-1].time {
+		return fmt.Errorf("set link state: %s -> %s: time %d is before the previous state at %d", from, to, time, states[len(states)-1].time)
+	}
 
-		dst.states = append(dst.states, *ls)
-		dsts[ls.toNode] = dst
+	ls := LinkState{time: time, status: DOWN, fromNode: from, toNode: to}
+	if up {
+		ls.status = UP
 	}
+	n.addLinkState(ls)
+	return nil
+}
 
-	return n, nil
+// AddLink makes the directed link from one node to another up from upAt until downAt, exclusive.
+// A negative downAt leaves the link up indefinitely.
+func (n *NetworkTypology) AddLink(from, to NodeID, upAt int, downAt int) error {
+	if downAt >= 0 && downAt <= upAt {
+		return fmt.Errorf("add link: %s -> %s: down time %d must be after up time %d", from, to, downAt, upAt)
+	}
+	if err := n.SetLinkState(from, to, upAt, true); err != nil {
+		return err
+	}
+	if downAt >= 0 {
+		return n.SetLinkState(from, to, downAt, false)
+	}
+	return nil
+}
+
+// addLinkState adds the LinkState to the applicable link. If there is not a link, one is created.
+func (n *NetworkTypology) addLinkState(ls LinkState) {
+	dsts, in := n.links[ls.fromNode]
+	if !in {
+		dsts = make(map[NodeID]Link)
+		n.links[ls.fromNode] = dsts
+	}
+	link, in := dsts[ls.toNode]
+	if !in {
+		link = Link{fromNode: ls.fromNode, toNode: ls.toNode}
+	}
+	link.states = append(link.states, ls)
+	dsts[ls.toNode] = link
 }
 
 // Query enables to Controller to determine the current link-state at a time quantum.
@@ -102,3 +137,88 @@ func (n *NetworkTypology) Query(msg QueryMsg) bool {
 
 	return link.isUp(msg.AtTime)
 }
+
+// LinksOf determines the neighbors the node can reach, and the neighbors which can reach the node, at the given time.
+// Both are sorted by NodeID.
+func (n *NetworkTypology) LinksOf(id NodeID, atTime int) (out []NodeID, in []NodeID) {
+	out = make([]NodeID, 0)
+	in = make([]NodeID, 0)
+	for _, from := range sortedNodeIDs(n.links) {
+		dsts := n.links[from]
+		for _, to := range sortedNodeIDs(dsts) {
+			link := dsts[to]
+			if !link.isUp(atTime) {
+				continue
+			}
+			if from == id {
+				out = append(out, to)
+			}
+			if to == id {
+				in = append(in, from)
+			}
+		}
+	}
+	return out, in
+}
+
+// NodeIDs determines every node with a link to or from it at any moment in time, sorted by NodeID.
+func (n *NetworkTypology) NodeIDs() []NodeID {
+	ids := make(map[NodeID]struct{})
+	for from, dsts := range n.links {
+		ids[from] = struct{}{}
+		for to := range dsts {
+			ids[to] = struct{}{}
+		}
+	}
+	return sortedNodeIDs(ids)
+}
+
+// Delay determines the number of ticks a message sent across the link at the given moment in time takes to arrive.
+// Zero if the link is down or has no delay.
+func (n *NetworkTypology) Delay(msg QueryMsg) int {
+	link, in := n.links[msg.FromNode][msg.ToNode]
+	if !in {
+		return 0
+	}
+	return link.delayAt(msg.AtTime)
+}
+
+// LinkInterval is a period of time during which a link is up.
+type LinkInterval struct {
+	// Start is the moment in time, inclusive, the link comes up.
+	Start int
+
+	// End is the moment in time, exclusive, the link goes down. -1 represents a link which never goes down.
+	End int
+}
+
+// EverLinked determines whether the link is up at any moment in time.
+func (n *NetworkTypology) EverLinked(from, to NodeID) bool {
+	return len(n.LinkLifetime(from, to)) > 0
+}
+
+// LinkLifetime determines every period of time the link is up, in increasing order of time.
+func (n *NetworkTypology) LinkLifetime(from, to NodeID) []LinkInterval {
+	intervals := make([]LinkInterval, 0)
+	link, in := n.links[from][to]
+	if !in {
+		return intervals
+	}
+
+	up := false
+	for i, state := range link.states {
+		// When several states share a moment in time, the last one applies.
+		if i+1 < len(link.states) && link.states[i+1].time == state.time {
+			continue
+		}
+		switch {
+		case !up && state.status == UP:
+			intervals = append(intervals, LinkInterval{Start: state.time, End: -1})
+			up = true
+		case up && state.status == DOWN:
+			intervals[len(intervals)-1].End = state.time
+			up = false
+		}
+	}
+	return intervals
+}
@@ -4,6 +4,7 @@ import (
 	"io"
 	"os"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -202,3 +203,189 @@ func TestNewNetworkTypology(t *testing.T) {
 		})
 	}
 }
+
+func TestNetworkTypology_LinksOf(t *testing.T) {
+	type args struct {
+		id     NodeID
+		atTime int
+	}
+	tests := []struct {
+		name    string
+		args    args
+		wantOut []NodeID
+		wantIn  []NodeID
+	}{
+		{
+			name:    "before any links",
+			args:    args{id: 0, atTime: 0},
+			wantOut: []NodeID{},
+			wantIn:  []NodeID{},
+		},
+		{
+			name:    "bidirectional",
+			args:    args{id: 0, atTime: 10},
+			wantOut: []NodeID{1},
+			wantIn:  []NodeID{1},
+		},
+		{
+			name:    "outbound only",
+			args:    args{id: 0, atTime: 22},
+			wantOut: []NodeID{2},
+			wantIn:  []NodeID{},
+		},
+		{
+			name:    "inbound only",
+			args:    args{id: 2, atTime: 22},
+			wantOut: []NodeID{},
+			wantIn:  []NodeID{0},
+		},
+		{
+			name:    "after link down",
+			args:    args{id: 1, atTime: 25},
+			wantOut: []NodeID{},
+			wantIn:  []NodeID{},
+		},
+		{
+			name:    "ID not in topology",
+			args:    args{id: 7, atTime: 25},
+			wantOut: []NodeID{},
+			wantIn:  []NodeID{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotOut, gotIn := goodTopology().LinksOf(tt.args.id, tt.args.atTime)
+			if !reflect.DeepEqual(gotOut, tt.wantOut) {
+				t.Errorf("LinksOf() out = %v, want %v", gotOut, tt.wantOut)
+			}
+			if !reflect.DeepEqual(gotIn, tt.wantIn) {
+				t.Errorf("LinksOf() in = %v, want %v", gotIn, tt.wantIn)
+			}
+		})
+	}
+}
+
+func TestNetworkTypology_Delay(t *testing.T) {
+	topology, err := NewNetworkTypology(strings.NewReader(
+		"0 UP 0 1 2\n" +
+			"0 UP 1 0\n" +
+			"10 UP 0 1 5\n" +
+			"20 DOWN 0 1 5\n"))
+	if err != nil {
+		t.Fatalf("NewNetworkTypology() error = %v", err)
+	}
+	tests := []struct {
+		name string
+		msg  QueryMsg
+		want int
+	}{
+		{name: "delayed", msg: QueryMsg{FromNode: 0, ToNode: 1, AtTime: 5}, want: 2},
+		{name: "delay changed", msg: QueryMsg{FromNode: 0, ToNode: 1, AtTime: 15}, want: 5},
+		{name: "link down", msg: QueryMsg{FromNode: 0, ToNode: 1, AtTime: 20}, want: 0},
+		{name: "no delay", msg: QueryMsg{FromNode: 1, ToNode: 0, AtTime: 5}, want: 0},
+		{name: "not in topology", msg: QueryMsg{FromNode: 2, ToNode: 0, AtTime: 5}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := topology.Delay(tt.msg); got != tt.want {
+				t.Errorf("Delay() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+func TestNetworkTypology_LinkLifetime(t *testing.T) {
+	topology, err := NewNetworkTypology(strings.NewReader(
+		"0 UP 0 1\n" +
+			"5 UP 0 1\n" +
+			"10 DOWN 0 1\n" +
+			"12 DOWN 0 1\n" +
+			"15 DOWN 1 0\n" +
+			"20 UP 0 1\n" +
+			"30 DOWN 0 1\n" +
+			"30 UP 0 1\n" +
+			"40 UP 0 2\n" +
+			"40 DOWN 0 2\n"))
+	if err != nil {
+		t.Fatalf("NewNetworkTypology() error = %v", err)
+	}
+	tests := []struct {
+		name     string
+		from, to NodeID
+		want     []LinkInterval
+	}{
+		{
+			name: "flapping",
+			from: 0, to: 1,
+			want: []LinkInterval{{Start: 0, End: 10}, {Start: 20, End: -1}},
+		},
+		{name: "never up", from: 1, to: 0, want: []LinkInterval{}},
+		{name: "overridden at the same time", from: 0, to: 2, want: []LinkInterval{}},
+		{name: "not in topology", from: 2, to: 0, want: []LinkInterval{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := topology.LinkLifetime(tt.from, tt.to); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LinkLifetime() = %v, want %v", got, tt.want)
+			}
+			if got, want := topology.EverLinked(tt.from, tt.to), len(tt.want) > 0; got != want {
+				t.Errorf("EverLinked() = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestNetworkTypology_AddLink(t *testing.T) {
+	built := NewEmptyTopology()
+	for _, err := range []error{
+		built.AddLink(0, 1, 10, 20),
+		built.AddLink(1, 0, 10, 20),
+		built.AddLink(0, 2, 21, -1),
+		built.SetLinkState(2, 0, 25, true),
+	} {
+		if err != nil {
+			t.Fatalf("building topology: %v", err)
+		}
+	}
+	if !reflect.DeepEqual(built, goodTopology()) {
+		t.Errorf("built topology = %v, want %v", built, goodTopology())
+	}
+
+	tests := []struct {
+		name  string
+		build func(n *NetworkTypology) error
+	}{
+		{
+			name:  "down before up",
+			build: func(n *NetworkTypology) error { return n.AddLink(0, 1, 10, 10) },
+		},
+		{
+			name:  "negative time",
+			build: func(n *NetworkTypology) error { return n.SetLinkState(0, 1, -1, true) },
+		},
+		{
+			name: "out of order",
+			build: func(n *NetworkTypology) error {
+				if err := n.AddLink(0, 1, 10, 20); err != nil {
+					return nil
+				}
+				return n.AddLink(0, 1, 15, -1)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.build(NewEmptyTopology()); err == nil {
+				t.Errorf("building topology error = nil, want an error")
+			}
+		})
+	}
+
+	// Time ordering is only enforced within each link.
+	n := NewEmptyTopology()
+	if err := n.AddLink(0, 1, 10, -1); err != nil {
+		t.Fatalf("AddLink() error = %v", err)
+	}
+	if err := n.AddLink(1, 0, 5, -1); err != nil {
+		t.Errorf("AddLink() on another link error = %v, want nil", err)
+	}
+}
@@ -0,0 +1,194 @@
+package main
+
+import (
+	"encoding/csv"
+	"io"
+	"sort"
+	"strconv"
+)
+
+// LinkLoad is the number of messages carried across a directed link.
+type LinkLoad struct {
+	From     NodeID
+	To       NodeID
+	Messages int
+}
+
+// countDelivery records a message delivered across the directed link.
+func (c *Controller) countDelivery(from, to NodeID) {
+	c.linkMu.Lock()
+	defer c.linkMu.Unlock()
+
+	c.linkMessages[[2]NodeID{from, to}]++
+}
+
+// LinkUtilization returns the number of messages delivered across each directed link so far, sorted by source then
+// destination. Links which carried no messages are omitted.
+func (c *Controller) LinkUtilization() []LinkLoad {
+	c.linkMu.Lock()
+	defer c.linkMu.Unlock()
+
+	loads := make([]LinkLoad, 0, len(c.linkMessages))
+	for link, messages := range c.linkMessages {
+		loads = append(loads, LinkLoad{From: link[0], To: link[1], Messages: messages})
+	}
+	sort.Slice(loads, func(i, j int) bool {
+		if loads[i].From != loads[j].From {
+			return loads[i].From < loads[j].From
+		}
+		return loads[i].To < loads[j].To
+	})
+	return loads
+}
+
+// WriteLinkUtilizationCSV writes the link utilization as a CSV heatmap, with a row for each source node and a column
+// for each destination node. Nodes appearing in any link are included, sorted by NodeID.
+func (c *Controller) WriteLinkUtilizationCSV(w io.Writer) error {
+	loads := c.LinkUtilization()
+
+	nodes := make(map[NodeID]struct{})
+	messages := make(map[[2]NodeID]int)
+	for _, load := range loads {
+		nodes[load.From] = struct{}{}
+		nodes[load.To] = struct{}{}
+		messages[[2]NodeID{load.From, load.To}] = load.Messages
+	}
+	ids := sortedNodeIDs(nodes)
+
+	cw := csv.NewWriter(w)
+	header := []string{"from\\to"}
+	for _, id := range ids {
+		header = append(header, id.String())
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, from := range ids {
+		row := []string{from.String()}
+		for _, to := range ids {
+			row = append(row, strconv.Itoa(messages[[2]NodeID{from, to}]))
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// routeNextHops copies the next hop of every route in each node's routing table, keyed by node then destination.
+func (c *Controller) routeNextHops() map[NodeID]map[NodeID]NodeID {
+	nextHops := make(map[NodeID]map[NodeID]NodeID, len(c.nodes))
+	for _, n := range c.nodes {
+		n.mu.RLock()
+		hops := make(map[NodeID]NodeID, len(n.routingTable))
+		for dst, entry := range n.routingTable {
+			hops[dst] = entry.nextHop
+		}
+		n.mu.RUnlock()
+		nextHops[n.id] = hops
+	}
+	return nextHops
+}
+
+// RelayLoad returns, for every node, the number of ordered pairs of other nodes whose route passes through it as a
+// relay, according to the nodes' current routing tables. Each pair contributes the single route in use, so after
+// convergence this approximates betweenness centrality over shortest paths; nodes with a high load are potential
+// single points of failure. Pairs without a complete route, including routes which loop, are not counted.
+func (c *Controller) RelayLoad() map[NodeID]int {
+	nextHops := c.routeNextHops()
+	load := make(map[NodeID]int, len(nextHops))
+	for id := range nextHops {
+		load[id] = 0
+	}
+	for src := range nextHops {
+		for dst := range nextHops {
+			if dst == src {
+				continue
+			}
+			relays := make([]NodeID, 0)
+			at := src
+			for hops := 0; at != dst && hops <= len(nextHops); hops++ {
+				next, in := nextHops[at][dst]
+				if !in {
+					break
+				}
+				if next != dst {
+					relays = append(relays, next)
+				}
+				at = next
+			}
+			if at != dst {
+				continue
+			}
+			for _, relay := range relays {
+				load[relay]++
+			}
+		}
+	}
+	return load
+}
+
+// DetectRoutingLoops follows the next hops of the nodes' current routing tables towards every destination, returning
+// each cycle found. A cycle lists its nodes in the order their routes lead, starting from the lowest NodeID, and is
+// reported once even if it affects several destinations. Cycles are sorted by their nodes. This pinpoints
+// contradictory routes without waiting for a DataMessage to be caught in one.
+func (c *Controller) DetectRoutingLoops() [][]NodeID {
+	nextHops := c.routeNextHops()
+	destinations := make(map[NodeID]struct{})
+	for _, hops := range nextHops {
+		for dst := range hops {
+			destinations[dst] = struct{}{}
+		}
+	}
+
+	loops := make([][]NodeID, 0)
+	found := make(map[string]struct{})
+	for _, dst := range sortedNodeIDs(destinations) {
+		for _, src := range sortedNodeIDs(nextHops) {
+			// Follow the routes until they reach the destination, end, or revisit a node.
+			visited := make(map[NodeID]int)
+			path := make([]NodeID, 0)
+			at := src
+			for at != dst {
+				if i, in := visited[at]; in {
+					loop := canonicalCycle(path[i:])
+					key := separatedString(loop, " ")
+					if _, in := found[key]; !in {
+						found[key] = struct{}{}
+						loops = append(loops, loop)
+					}
+					break
+				}
+				next, in := nextHops[at][dst]
+				if !in {
+					break
+				}
+				visited[at] = len(path)
+				path = append(path, at)
+				at = next
+			}
+		}
+	}
+	sort.Slice(loops, func(i, j int) bool {
+		a, b := loops[i], loops[j]
+		for k := 0; k < len(a) && k < len(b); k++ {
+			if a[k] != b[k] {
+				return a[k] < b[k]
+			}
+		}
+		return len(a) < len(b)
+	})
+	return loops
+}
+
+// canonicalCycle rotates a cycle to start from its lowest NodeID.
+func canonicalCycle(cycle []NodeID) []NodeID {
+	start := 0
+	for i, id := range cycle {
+		if id < cycle[start] {
+			start = i
+		}
+	}
+	return append(append([]NodeID(nil), cycle[start:]...), cycle[:start]...)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestController_LinkUtilization(t *testing.T) {
+	c := NewController(*goodTopology(), time.Hour)
+	for id := NodeID(0); id < 3; id++ {
+		c.nodes = append(c.nodes, newTestNode(id, &recordingTransmitter{}))
+		c.nodeChannels[id] = make(chan interface{}, 10)
+		c.nodeStarted[id] = make(chan struct{})
+		c.nodeStopped[id] = make(chan struct{})
+		close(c.nodeStarted[id])
+	}
+
+	// At tick 10, only the links between 0 and 1 are up.
+	epoch := time.Now().Add(-10 * time.Hour)
+	c.handleHelloMessage(&HelloMessage{Source: 0}, epoch)
+	c.handleHelloMessage(&HelloMessage{Source: 0}, epoch)
+	c.handleTCMessage(&TCMessage{Source: 2, FromNeighbor: 1}, epoch)
+	c.handleDataMessage(&DataMessage{Source: 1, Destination: 0, FromNeighbor: 1, NextHop: 0}, epoch)
+	// The link from 1 to 2 is down, so nothing is counted.
+	c.handleDataMessage(&DataMessage{Source: 1, Destination: 2, FromNeighbor: 1, NextHop: 2}, epoch)
+	waitForLinks(t, c)
+
+	want := []LinkLoad{
+		{From: 0, To: 1, Messages: 2},
+		{From: 1, To: 0, Messages: 2},
+	}
+	if got := c.LinkUtilization(); !reflect.DeepEqual(got, want) {
+		t.Errorf("LinkUtilization() = %v, want %v", got, want)
+	}
+}
+
+func TestController_WriteLinkUtilizationCSV(t *testing.T) {
+	c := NewController(NetworkTypology{}, time.Millisecond)
+	c.countDelivery(0, 1)
+	c.countDelivery(0, 1)
+	c.countDelivery(1, 0)
+	c.countDelivery(2, 0)
+
+	want := "from\\to,0,1,2\n" +
+		"0,0,2,0\n" +
+		"1,1,0,0\n" +
+		"2,1,0,0\n"
+	var buf bytes.Buffer
+	if err := c.WriteLinkUtilizationCSV(&buf); err != nil {
+		t.Fatalf("WriteLinkUtilizationCSV() error = %v", err)
+	}
+	if got := buf.String(); got != want {
+		t.Errorf("WriteLinkUtilizationCSV() = %q, want %q", got, want)
+	}
+}
+
+func TestController_RelayLoad(t *testing.T) {
+	// Converge a line of nodes 0 - 1 - 2 - 3, with node 4 linked to 3 in one direction only.
+	l := newLockstepNetwork(map[NodeID][]NodeID{
+		0: {1},
+		1: {0, 2},
+		2: {1, 3},
+		3: {2},
+		4: {3},
+	})
+	l.run(40)
+
+	c := NewController(NetworkTypology{}, time.Hour)
+	for _, id := range sortedNodeIDs(l.nodes) {
+		c.nodes = append(c.nodes, l.nodes[id])
+	}
+	want := map[NodeID]int{0: 0, 1: 4, 2: 4, 3: 0, 4: 0}
+	if got := c.RelayLoad(); !reflect.DeepEqual(got, want) {
+		t.Errorf("RelayLoad() = %v, want %v", got, want)
+	}
+
+	// Routes which loop are not counted.
+	l.nodes[0].routingTable[3] = routingEntry{dst: 3, nextHop: 1, distance: 3}
+	l.nodes[1].routingTable[3] = routingEntry{dst: 3, nextHop: 0, distance: 3}
+	want = map[NodeID]int{0: 0, 1: 3, 2: 2, 3: 0, 4: 0}
+	if got := c.RelayLoad(); !reflect.DeepEqual(got, want) {
+		t.Errorf("RelayLoad() with a loop = %v, want %v", got, want)
+	}
+}
+
+func TestController_DetectRoutingLoops(t *testing.T) {
+	tests := []struct {
+		name   string
+		routes map[NodeID][]routingEntry
+		want   [][]NodeID
+	}{
+		{
+			name: "consistent",
+			routes: map[NodeID][]routingEntry{
+				0: {{dst: 2, nextHop: 1, distance: 2}},
+				1: {{dst: 2, nextHop: 2, distance: 1}},
+			},
+			want: [][]NodeID{},
+		},
+		{
+			name: "mutually pointing",
+			routes: map[NodeID][]routingEntry{
+				0: {{dst: 2, nextHop: 1, distance: 2}},
+				1: {{dst: 2, nextHop: 0, distance: 2}},
+			},
+			want: [][]NodeID{{0, 1}},
+		},
+		{
+			// The loop is reported once, though it affects two destinations and is reached from node 3.
+			name: "longer loop",
+			routes: map[NodeID][]routingEntry{
+				0: {{dst: 4, nextHop: 2, distance: 2}, {dst: 5, nextHop: 2, distance: 2}},
+				1: {{dst: 4, nextHop: 0, distance: 3}, {dst: 5, nextHop: 0, distance: 3}},
+				2: {{dst: 4, nextHop: 1, distance: 2}, {dst: 5, nextHop: 1, distance: 2}},
+				3: {{dst: 4, nextHop: 2, distance: 3}, {dst: 5, nextHop: 4, distance: 2}},
+			},
+			want: [][]NodeID{{0, 2, 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := NewController(NetworkTypology{}, time.Hour)
+			for _, id := range sortedNodeIDs(tt.routes) {
+				n := newTestNode(id, &recordingTransmitter{})
+				for _, entry := range tt.routes[id] {
+					n.routingTable[entry.dst] = entry
+				}
+				c.nodes = append(c.nodes, n)
+			}
+			if got := c.DetectRoutingLoops(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DetectRoutingLoops() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+)
+
+// SetWatchdog makes the Controller check, every tick, that each running node is still progressing through its ticks,
+// reporting any node which has not completed a tick within the grace period, in ticks, as stuck. A stuck node is
+// typically blocked, such as on a send, and would otherwise hang the simulation silently. Zero disables the watchdog.
+// The watchdog does not apply to synchronous simulations. Must be called before Start.
+func (c *Controller) SetWatchdog(grace int) {
+	c.watchdogGrace = grace
+}
+
+// StuckNodes returns the nodes the watchdog reported as stuck, sorted by NodeID.
+func (c *Controller) StuckNodes() []NodeID {
+	c.stuckMu.Lock()
+	defer c.stuckMu.Unlock()
+
+	return sortedNodeIDs(c.stuck)
+}
+
+// running determines whether the node has come online and not yet gone offline.
+func (c *Controller) running(id NodeID) bool {
+	select {
+	case <-c.nodeStarted[id]:
+	default:
+		return false
+	}
+	select {
+	case <-c.nodeStopped[id]:
+		return false
+	default:
+		return true
+	}
+}
+
+// watchNodes reports every running node which has not progressed within the watchdog grace period, until the
+// context is done. Each node is reported once.
+func (c *Controller) watchNodes(ctx context.Context, epoch time.Time) {
+	// progress and since are the last observed progress of each running node, and the tick it was observed at.
+	progress := make(map[NodeID]int64)
+	since := make(map[NodeID]int)
+	for tick := c.ticksSince(epoch) + 1; c.waitUntilTick(ctx, epoch, tick); tick++ {
+		for _, n := range c.nodes {
+			if !c.running(n.id) {
+				delete(progress, n.id)
+				continue
+			}
+			p := atomic.LoadInt64(&n.progress)
+			if last, in := progress[n.id]; !in || p != last {
+				progress[n.id] = p
+				since[n.id] = tick
+				continue
+			}
+			if tick-since[n.id] < c.watchdogGrace {
+				continue
+			}
+			c.stuckMu.Lock()
+			if _, reported := c.stuck[n.id]; !reported {
+				c.stuck[n.id] = tick
+				log.Printf("controller: WARNING: node %s has not completed a tick since tick %d, it may be blocked", n.id, since[n.id])
+			}
+			c.stuckMu.Unlock()
+		}
+	}
+}
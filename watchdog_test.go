@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// blockingTransmitter blocks every send until released.
+type blockingTransmitter struct {
+	release chan struct{}
+}
+
+func (t blockingTransmitter) Send(interface{}) {
+	<-t.release
+}
+
+func TestController_SetWatchdog(t *testing.T) {
+	logDir, err := os.MkdirTemp("", "olsrsim")
+	if err != nil {
+		t.Fatalf("MkdirTemp() error = %v", err)
+	}
+	defer os.RemoveAll(logDir)
+
+	c := NewController(*NewEmptyTopology(), 5*time.Millisecond)
+	c.SetLogDir(logDir)
+	c.Initialize([]NodeConfig{
+		{ID: 0, Message: NodeMessage{Sent: true}},
+		{ID: 1, Message: NodeMessage{Sent: true}},
+	})
+	// Node 1 blocks on its first HELLO, while holding its lock.
+	release := make(chan struct{})
+	blocked, _ := c.node(1)
+	blocked.output = blockingTransmitter{release: release}
+	c.SetWatchdog(10)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		c.Start(100)
+	}()
+
+	deadline := time.After(5 * time.Second)
+	for len(c.StuckNodes()) == 0 {
+		select {
+		case <-deadline:
+			t.Fatalf("StuckNodes() is empty, want the blocked node reported")
+		case <-time.After(5 * time.Millisecond):
+		}
+	}
+	close(release)
+	<-done
+
+	if got, want := c.StuckNodes(), []NodeID{1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("StuckNodes() = %v, want %v", got, want)
+	}
+}
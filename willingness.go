@@ -0,0 +1,63 @@
+package main
+
+import "fmt"
+
+// Willingness is a Node's willingness to carry traffic on behalf of other nodes, per RFC 3626 section 18.8.
+//
+// A Node's willingness scales its TC interval: less willing nodes, typically leaves, emit TCMessage(s) less often to
+// save airtime, while nodes willing to always relay emit them more often. Longer TC intervals slow convergence, as
+// other nodes learn of changes to the Node's MS set up to a full interval later, and the topology hold time should
+// cover several intervals so that entries do not expire between TCMessage(s).
+type Willingness int
+
+const (
+	WillNever   Willingness = 0
+	WillLow     Willingness = 1
+	WillDefault Willingness = 3
+	WillHigh    Willingness = 6
+	WillAlways  Willingness = 7
+)
+
+func (w Willingness) String() string {
+	switch w {
+	case WillNever:
+		return "WILL_NEVER"
+	case WillLow:
+		return "WILL_LOW"
+	case WillDefault:
+		return "WILL_DEFAULT"
+	case WillHigh:
+		return "WILL_HIGH"
+	case WillAlways:
+		return "WILL_ALWAYS"
+	default:
+		return fmt.Sprintf("WILLINGNESS(%d)", int(w))
+	}
+}
+
+// scaleTCInterval scales a TC interval by the willingness. The result is always at least one tick.
+func (w Willingness) scaleTCInterval(interval int) int {
+	switch {
+	case w <= WillNever:
+		interval *= 4
+	case w < WillDefault:
+		interval *= 2
+	case w >= WillAlways:
+		interval /= 2
+	}
+	if interval < 1 {
+		return 1
+	}
+	return interval
+}
+
+// SetWillingness sets the Node's willingness, which scales its TC interval. It may be changed mid-run, taking effect
+// from the next tick; see SetHelloInterval.
+func (n *Node) SetWillingness(w Willingness) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	old := n.willingness.scaleTCInterval(n.tcInterval)
+	n.willingness = w
+	n.tcPhase = rephase(n.tcPhase, old, n.willingness.scaleTCInterval(n.tcInterval), n.currentTick)
+}
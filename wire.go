@@ -0,0 +1,395 @@
+package main
+
+import (
+	"encoding"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+)
+
+// Message type markers, the first byte of each binary encoded message.
+const (
+	wireHello byte = iota + 1
+	wireTC
+	wireData
+	wireMID
+)
+
+// errShortBuffer is returned when a binary encoded message ends before all of its fields.
+var errShortBuffer = errors.New("unmarshal binary: buffer too short")
+
+// wireWriter appends length-prefixed fields to a buffer.
+type wireWriter struct {
+	buf []byte
+}
+
+func (w *wireWriter) uvarint(v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	w.buf = append(w.buf, tmp[:n]...)
+}
+
+func (w *wireWriter) varint(v int64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutVarint(tmp[:], v)
+	w.buf = append(w.buf, tmp[:n]...)
+}
+
+func (w *wireWriter) ids(ids []NodeID) {
+	w.uvarint(uint64(len(ids)))
+	for _, id := range ids {
+		w.uvarint(uint64(id))
+	}
+}
+
+func (w *wireWriter) bytes(b []byte) {
+	w.uvarint(uint64(len(b)))
+	w.buf = append(w.buf, b...)
+}
+
+func (w *wireWriter) float64(f float64) {
+	var tmp [8]byte
+	binary.BigEndian.PutUint64(tmp[:], math.Float64bits(f))
+	w.buf = append(w.buf, tmp[:]...)
+}
+
+// wireReader consumes fields written by a wireWriter. The first error is retained, and all later reads return zero
+// values, so callers only need to check err once.
+type wireReader struct {
+	buf []byte
+	err error
+}
+
+func (r *wireReader) uvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(r.buf)
+	if n <= 0 {
+		r.err = errShortBuffer
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *wireReader) varint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	v, n := binary.Varint(r.buf)
+	if n <= 0 {
+		r.err = errShortBuffer
+		return 0
+	}
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *wireReader) id() NodeID {
+	v := r.uvarint()
+	if v > math.MaxUint32 {
+		r.fail(fmt.Errorf("unmarshal binary: node ID exceeds 32 bits: %d", v))
+		return 0
+	}
+	return NodeID(v)
+}
+
+func (r *wireReader) ids() []NodeID {
+	n := r.uvarint()
+	// Each ID takes at least one byte, which bounds the allocation for corrupt lengths.
+	if n > uint64(len(r.buf)) {
+		r.fail(errShortBuffer)
+		return nil
+	}
+	if n == 0 {
+		return nil
+	}
+	ids := make([]NodeID, 0, n)
+	for i := uint64(0); i < n; i++ {
+		ids = append(ids, r.id())
+	}
+	return ids
+}
+
+func (r *wireReader) bytes() []byte {
+	n := r.uvarint()
+	if n > uint64(len(r.buf)) {
+		r.fail(errShortBuffer)
+		return nil
+	}
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func (r *wireReader) float64() float64 {
+	if len(r.buf) < 8 {
+		r.fail(errShortBuffer)
+		return 0
+	}
+	f := math.Float64frombits(binary.BigEndian.Uint64(r.buf))
+	r.buf = r.buf[8:]
+	return f
+}
+
+func (r *wireReader) fail(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *wireReader) byte() byte {
+	if r.err != nil {
+		return 0
+	}
+	if len(r.buf) == 0 {
+		r.fail(errShortBuffer)
+		return 0
+	}
+	b := r.buf[0]
+	r.buf = r.buf[1:]
+	return b
+}
+
+// expect consumes the message type marker.
+func (r *wireReader) expect(marker byte) {
+	if b := r.byte(); r.err == nil && b != marker {
+		r.fail(fmt.Errorf("unmarshal binary: unexpected message type: %d", b))
+	}
+}
+
+// done checks that the whole buffer was consumed.
+func (r *wireReader) done() error {
+	if r.err == nil && len(r.buf) != 0 {
+		r.err = fmt.Errorf("unmarshal binary: %d trailing bytes", len(r.buf))
+	}
+	return r.err
+}
+
+// MarshalBinary encodes the HelloMessage in a compact length-prefixed layout:
+// type, source, sequence, the three neighbor lists, link quality entries sorted by NodeID, then any unknown
+// categories.
+func (m HelloMessage) MarshalBinary() ([]byte, error) {
+	w := wireWriter{buf: []byte{wireHello}}
+	w.uvarint(uint64(m.Source))
+	w.varint(int64(m.Sequence))
+	w.ids(m.Unidirectional)
+	w.ids(m.Bidirectional)
+	w.ids(m.MultipointRelay)
+
+	// A nil map is distinguished from an empty one, as it represents the legacy format.
+	if m.LinkQuality == nil {
+		w.buf = append(w.buf, 0)
+	} else {
+		w.buf = append(w.buf, 1)
+		ids := make([]NodeID, 0, len(m.LinkQuality))
+		for id := range m.LinkQuality {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool {
+			return ids[i] < ids[j]
+		})
+		w.uvarint(uint64(len(ids)))
+		for _, id := range ids {
+			w.uvarint(uint64(id))
+			w.float64(m.LinkQuality[id])
+		}
+	}
+
+	w.uvarint(uint64(len(m.UnknownCategories)))
+	for _, category := range m.UnknownCategories {
+		w.bytes([]byte(category.Name))
+		w.uvarint(uint64(len(category.Neighbors)))
+		for _, neighbor := range category.Neighbors {
+			w.bytes([]byte(neighbor))
+		}
+	}
+	return w.buf, nil
+}
+
+// UnmarshalBinary decodes a HelloMessage encoded by MarshalBinary.
+func (m *HelloMessage) UnmarshalBinary(data []byte) error {
+	r := wireReader{buf: data}
+	r.expect(wireHello)
+	decoded := HelloMessage{
+		Source:   r.id(),
+		Sequence: int(r.varint()),
+	}
+	decoded.Unidirectional = r.ids()
+	decoded.Bidirectional = r.ids()
+	decoded.MultipointRelay = r.ids()
+
+	if r.byte() == 1 {
+		n := r.uvarint()
+		// Each entry takes at least nine bytes, which bounds the allocation for corrupt lengths.
+		if n > uint64(len(r.buf))/9 {
+			r.fail(errShortBuffer)
+		}
+		decoded.LinkQuality = make(map[NodeID]float64)
+		for i := uint64(0); i < n && r.err == nil; i++ {
+			id := r.id()
+			decoded.LinkQuality[id] = r.float64()
+		}
+	}
+
+	// Each category and neighbor takes at least one byte, which bounds the allocations for corrupt lengths.
+	if n := r.uvarint(); n > uint64(len(r.buf)) {
+		r.fail(errShortBuffer)
+	} else {
+		for i := uint64(0); i < n && r.err == nil; i++ {
+			category := HelloCategory{Name: string(r.bytes())}
+			neighbors := r.uvarint()
+			if neighbors > uint64(len(r.buf)) {
+				r.fail(errShortBuffer)
+				break
+			}
+			for j := uint64(0); j < neighbors && r.err == nil; j++ {
+				category.Neighbors = append(category.Neighbors, string(r.bytes()))
+			}
+			decoded.UnknownCategories = append(decoded.UnknownCategories, category)
+		}
+	}
+	if err := r.done(); err != nil {
+		return err
+	}
+	*m = decoded
+	return nil
+}
+
+// MarshalBinary encodes the TCMessage in a compact length-prefixed layout:
+// type, source, from-neighbor, sequence, message sequence, the MS set, then whether the TC is incremental, followed by
+// the added and removed lists if so.
+func (m TCMessage) MarshalBinary() ([]byte, error) {
+	w := wireWriter{buf: []byte{wireTC}}
+	w.uvarint(uint64(m.Source))
+	w.uvarint(uint64(m.FromNeighbor))
+	w.varint(int64(m.Sequence))
+	w.varint(int64(m.MessageSequence))
+	w.ids(m.MultipointRelaySet)
+	if !m.Incremental {
+		w.buf = append(w.buf, 0)
+		return w.buf, nil
+	}
+	w.buf = append(w.buf, 1)
+	w.ids(m.Added)
+	w.ids(m.Removed)
+	return w.buf, nil
+}
+
+// UnmarshalBinary decodes a TCMessage encoded by MarshalBinary.
+func (m *TCMessage) UnmarshalBinary(data []byte) error {
+	r := wireReader{buf: data}
+	r.expect(wireTC)
+	decoded := TCMessage{
+		Source:          r.id(),
+		FromNeighbor:    r.id(),
+		Sequence:        int(r.varint()),
+		MessageSequence: int(r.varint()),
+	}
+	decoded.MultipointRelaySet = r.ids()
+	switch r.byte() {
+	case 0:
+	case 1:
+		decoded.Incremental = true
+		decoded.Added = r.ids()
+		decoded.Removed = r.ids()
+	default:
+		r.fail(errors.New("unmarshal binary: invalid incremental marker"))
+	}
+	if err := r.done(); err != nil {
+		return err
+	}
+	*m = decoded
+	return nil
+}
+
+// MarshalBinary encodes the DataMessage in a compact length-prefixed layout:
+// type, source, destination, next hop, from-neighbor, data, the path, the multicast receivers, then the priority.
+func (m DataMessage) MarshalBinary() ([]byte, error) {
+	w := wireWriter{buf: []byte{wireData}}
+	w.uvarint(uint64(m.Source))
+	w.uvarint(uint64(m.Destination))
+	w.uvarint(uint64(m.NextHop))
+	w.uvarint(uint64(m.FromNeighbor))
+	w.bytes([]byte(m.Data))
+	w.ids(m.Path)
+	w.ids(m.Receivers)
+	w.varint(int64(m.Priority))
+	return w.buf, nil
+}
+
+// UnmarshalBinary decodes a DataMessage encoded by MarshalBinary.
+func (m *DataMessage) UnmarshalBinary(data []byte) error {
+	r := wireReader{buf: data}
+	r.expect(wireData)
+	decoded := DataMessage{
+		Source:       r.id(),
+		Destination:  r.id(),
+		NextHop:      r.id(),
+		FromNeighbor: r.id(),
+	}
+	decoded.Data = string(r.bytes())
+	decoded.Path = r.ids()
+	decoded.Receivers = r.ids()
+	decoded.Priority = int(r.varint())
+	if err := r.done(); err != nil {
+		return err
+	}
+	*m = decoded
+	return nil
+}
+
+// MarshalBinary encodes the MIDMessage in a compact length-prefixed layout:
+// type, source, from-neighbor, sequence, then the interface addresses.
+func (m MIDMessage) MarshalBinary() ([]byte, error) {
+	w := wireWriter{buf: []byte{wireMID}}
+	w.uvarint(uint64(m.Source))
+	w.uvarint(uint64(m.FromNeighbor))
+	w.varint(int64(m.Sequence))
+	w.ids(m.Interfaces)
+	return w.buf, nil
+}
+
+// UnmarshalBinary decodes a MIDMessage encoded by MarshalBinary.
+func (m *MIDMessage) UnmarshalBinary(data []byte) error {
+	r := wireReader{buf: data}
+	r.expect(wireMID)
+	decoded := MIDMessage{
+		Source:       r.id(),
+		FromNeighbor: r.id(),
+		Sequence:     int(r.varint()),
+	}
+	decoded.Interfaces = r.ids()
+	if err := r.done(); err != nil {
+		return err
+	}
+	*m = decoded
+	return nil
+}
+
+// unmarshalMessage decodes a HelloMessage, TCMessage, MIDMessage, or DataMessage encoded by its MarshalBinary method.
+func unmarshalMessage(data []byte) (interface{}, error) {
+	if len(data) == 0 {
+		return nil, errShortBuffer
+	}
+	var msg encoding.BinaryUnmarshaler
+	switch data[0] {
+	case wireHello:
+		msg = &HelloMessage{}
+	case wireTC:
+		msg = &TCMessage{}
+	case wireData:
+		msg = &DataMessage{}
+	case wireMID:
+		msg = &MIDMessage{}
+	default:
+		return nil, fmt.Errorf("unmarshal binary: unexpected message type: %d", data[0])
+	}
+	if err := msg.UnmarshalBinary(data); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
@@ -0,0 +1,231 @@
+package main
+
+import (
+	"encoding"
+	"reflect"
+	"testing"
+)
+
+// binaryMessage is implemented by pointers to each message type.
+type binaryMessage interface {
+	encoding.BinaryMarshaler
+	encoding.BinaryUnmarshaler
+}
+
+func TestMessage_MarshalBinary(t *testing.T) {
+	tests := []struct {
+		name string
+		msg  binaryMessage
+	}{
+		{
+			name: "hello",
+			msg: &HelloMessage{
+				Source:          1,
+				Unidirectional:  []NodeID{2},
+				Bidirectional:   []NodeID{3, 4},
+				MultipointRelay: []NodeID{4},
+				Sequence:        12,
+			},
+		},
+		{
+			name: "hello with link quality",
+			msg: &HelloMessage{
+				Source:        0x0A000001,
+				Bidirectional: []NodeID{1, 2},
+				LinkQuality:   map[NodeID]float64{1: 0.5, 2: 1},
+			},
+		},
+		{
+			name: "hello with empty link quality",
+			msg:  &HelloMessage{Source: 1, LinkQuality: map[NodeID]float64{}},
+		},
+		{
+			name: "hello with unknown categories",
+			msg: &HelloMessage{
+				Source:            1,
+				Bidirectional:     []NodeID{2},
+				UnknownCategories: []HelloCategory{{Name: "LOST", Neighbors: []string{"9", "10:x"}}, {Name: "EMPTY"}},
+			},
+		},
+		{
+			name: "tc",
+			msg:  &TCMessage{Source: 2, FromNeighbor: 1, Sequence: 7, MultipointRelaySet: []NodeID{3, 4}, MessageSequence: 12},
+		},
+		{
+			name: "incremental tc",
+			msg:  &TCMessage{Source: 2, FromNeighbor: 1, Sequence: 8, Incremental: true, Added: []NodeID{5}, Removed: []NodeID{3, 4}},
+		},
+		{
+			name: "empty tc",
+			msg:  &TCMessage{Source: 2, FromNeighbor: 1},
+		},
+		{
+			name: "mid",
+			msg:  &MIDMessage{Source: 2, FromNeighbor: 1, Sequence: 3, Interfaces: []NodeID{0x0A000002, 0x0A000102}},
+		},
+		{
+			name: "data",
+			msg: &DataMessage{
+				Source:       0,
+				Destination:  5,
+				NextHop:      3,
+				FromNeighbor: 1,
+				Data:         "hello 5, from 0",
+				Path:         []NodeID{0, 1},
+				Priority:     2,
+			},
+		},
+		{
+			name: "multicast data",
+			msg: &DataMessage{
+				Source:       0,
+				Destination:  0xE0000001,
+				NextHop:      3,
+				FromNeighbor: 0,
+				Data:         "hello group",
+				Receivers:    []NodeID{3, 4},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b, err := tt.msg.MarshalBinary()
+			if err != nil {
+				t.Fatalf("MarshalBinary() error = %v", err)
+			}
+			got := reflect.New(reflect.TypeOf(tt.msg).Elem()).Interface().(binaryMessage)
+			if err := got.UnmarshalBinary(b); err != nil {
+				t.Fatalf("UnmarshalBinary() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.msg) {
+				t.Errorf("UnmarshalBinary() got = %#v, want %#v", got, tt.msg)
+			}
+
+			// Every truncation of a valid encoding must be rejected.
+			for i := 0; i < len(b); i++ {
+				if err := got.UnmarshalBinary(b[:i]); err == nil {
+					t.Errorf("UnmarshalBinary() of %d of %d bytes succeeded", i, len(b))
+				}
+			}
+			if err := got.UnmarshalBinary(append(b, 0)); err == nil {
+				t.Errorf("UnmarshalBinary() with a trailing byte succeeded")
+			}
+		})
+	}
+}
+
+func TestMessage_UnmarshalBinary_wrongType(t *testing.T) {
+	b, err := TCMessage{Source: 1}.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	if err := new(HelloMessage).UnmarshalBinary(b); err == nil {
+		t.Errorf("HelloMessage.UnmarshalBinary() of a TC message succeeded")
+	}
+	if err := new(DataMessage).UnmarshalBinary(b); err == nil {
+		t.Errorf("DataMessage.UnmarshalBinary() of a TC message succeeded")
+	}
+}
+
+func FuzzTCMessage_UnmarshalBinary(f *testing.F) {
+	seed, _ := TCMessage{Source: 2, FromNeighbor: 1, Sequence: 7, MultipointRelaySet: []NodeID{3, 4}}.MarshalBinary()
+	f.Add(seed)
+	f.Fuzz(func(t *testing.T, b []byte) {
+		var m TCMessage
+		if err := m.UnmarshalBinary(b); err != nil {
+			return
+		}
+		// Any successful decode must survive a re-encode.
+		again, err := m.MarshalBinary()
+		if err != nil {
+			t.Fatalf("MarshalBinary() error = %v", err)
+		}
+		var reparsed TCMessage
+		if err := reparsed.UnmarshalBinary(again); err != nil || !reflect.DeepEqual(reparsed, m) {
+			t.Errorf("UnmarshalBinary() of re-encoded %#v got = %#v, %v", m, reparsed, err)
+		}
+	})
+}
+
+// benchmarkTC is a TC message with an MS set typical of a dense neighborhood.
+var benchmarkTC = TCMessage{
+	Source:             0x0A000001,
+	FromNeighbor:       0x0A000002,
+	Sequence:           1234,
+	MultipointRelaySet: []NodeID{3, 4, 5, 6, 7, 8, 9, 10, 11, 12},
+}
+
+func BenchmarkTCMessage_String(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_ = benchmarkTC.String()
+	}
+}
+
+func BenchmarkTCMessage_MarshalBinary(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_, _ = benchmarkTC.MarshalBinary()
+	}
+}
+
+func BenchmarkTCMessage_ParseMessage(b *testing.B) {
+	s := benchmarkTC.String()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseMessage(s); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkTCMessage_UnmarshalBinary(b *testing.B) {
+	data, _ := benchmarkTC.MarshalBinary()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var m TCMessage
+		if err := m.UnmarshalBinary(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// benchmarkHello is a HELLO message with link quality for each neighbor.
+var benchmarkHello = HelloMessage{
+	Source:          1,
+	Unidirectional:  []NodeID{2, 3},
+	Bidirectional:   []NodeID{4, 5, 6, 7},
+	MultipointRelay: []NodeID{8, 9},
+	LinkQuality:     map[NodeID]float64{2: 0.25, 3: 0.5, 4: 0.75, 5: 1, 6: 1, 7: 1, 8: 1, 9: 1},
+}
+
+func BenchmarkHelloMessage_String(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_ = benchmarkHello.String()
+	}
+}
+
+func BenchmarkHelloMessage_MarshalBinary(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		_, _ = benchmarkHello.MarshalBinary()
+	}
+}
+
+func BenchmarkHelloMessage_ParseMessage(b *testing.B) {
+	s := benchmarkHello.String()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := ParseMessage(s); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkHelloMessage_UnmarshalBinary(b *testing.B) {
+	data, _ := benchmarkHello.MarshalBinary()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		var m HelloMessage
+		if err := m.UnmarshalBinary(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}